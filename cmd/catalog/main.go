@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bot/internal/catalog"
+	"bot/internal/config"
+	"bot/internal/dbadapter"
+	"bot/internal/entities"
+	"bot/internal/logger"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Imports or exports the catalog of cities, service categories, services and masters:
+//
+//	catalog import [-dry-run] [-format csv|json] file
+//	catalog export [-format csv|json] file
+func main() {
+
+	if len(os.Args) < 2 || (os.Args[1] != "import" && os.Args[1] != "export") {
+		usage()
+		os.Exit(2)
+	}
+
+	command := os.Args[1]
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	format := flags.String("format", "", "csv or json, taken from the file extension if omitted")
+	dryRun := flags.Bool("dry-run", false, "only print the changes the import would make")
+	configPath := flags.String("config", "config.toml", "path to the config file")
+	flags.Usage = func() {
+		usage()
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(os.Args[2:])
+
+	// a file without a known extension is JSON, a format given explicitly must be known
+	path := flags.Arg(0)
+	fileFormat, err := catalog.Format(*format, "")
+	if len(*format) == 0 {
+		fileFormat, err = catalog.Format(strings.TrimPrefix(filepath.Ext(path), "."), "")
+		if err != nil {
+			fileFormat, err = catalog.JSON, nil
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		panic(fmt.Sprintf("catalog::config::Load: %s", err))
+	}
+
+	logger := logger.NewLogger()
+
+	DBAdapter, err := dbadapter.NewDbAdapter(logger, cfg)
+	if err != nil {
+		logger.Error("catalog::dbadapter::NewDBAdapter: ", err)
+		os.Exit(1)
+	}
+
+	if err := DBAdapter.AutoMigrate(); err != nil {
+		logger.Error("catalog::dbadapter::AutoMigrate: ", err)
+		os.Exit(1)
+	}
+
+	if command == "import" {
+		err = importCatalog(DBAdapter, path, fileFormat, *dryRun)
+	} else {
+		err = exportCatalog(DBAdapter, path, fileFormat)
+	}
+	if err != nil {
+		logger.Errorf("catalog::%s: %s", command, err)
+		os.Exit(1)
+	}
+}
+
+func importCatalog(DBAdapter *dbadapter.DBAdapter, path, format string, dryRun bool) error {
+
+	if len(path) == 0 {
+		return fmt.Errorf("the file to import is required")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	records, err := catalog.Read(file, format)
+	if err != nil {
+		return err
+	}
+
+	result, err := DBAdapter.ImportCatalog(records, dryRun)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		printChanges(result)
+		return nil
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+func exportCatalog(DBAdapter *dbadapter.DBAdapter, path, format string) error {
+
+	// the log goes to stdout, so the export needs a file of its own
+	if len(path) == 0 {
+		return fmt.Errorf("the file to export to is required")
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := catalog.NewWriter(file, format)
+	if err := DBAdapter.ExportCatalog(writer.Write); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: catalog import [-dry-run] [-format csv|json] file")
+	fmt.Fprintln(os.Stderr, "       catalog export [-format csv|json] file")
+}
+
+// printChanges prints the changes the import would make, the updated fields with their old and new values
+func printChanges(result *entities.ImportResult) {
+
+	marks := map[string]string{"created": "+", "updated": "~"}
+	for _, change := range result.Changes {
+		fmt.Printf("%s %s %s %q\n", marks[change.Action], change.Kind, change.ID, change.Name)
+		for _, field := range change.Fields {
+			fmt.Printf("    %s: %q -> %q\n", field.Field, field.Old, field.New)
+		}
+	}
+	fmt.Printf("created: %d, updated: %d, unchanged: %d\n", result.Created, result.Updated, result.Unchanged)
+}
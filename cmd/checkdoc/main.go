@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bot/docs"
+	"bot/internal/config"
+	"bot/internal/logger"
+	"bot/internal/server"
+	"fmt"
+	"os"
+)
+
+// Checks that the OpenAPI document in docs describes exactly the routes of the versioned API,
+// exits with 1 and lists the differences otherwise:
+//
+//	checkdoc
+func main() {
+
+	router, err := server.NewRouter(logger.NewLogger(), &config.Config{}, nil, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	problems, err := server.CheckRoutes(router, docs.JSON)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	for _, problem := range problems {
+		fmt.Fprintln(os.Stderr, problem)
+	}
+	if len(problems) != 0 {
+		os.Exit(1)
+	}
+	fmt.Println("The OpenAPI document matches the routes")
+}
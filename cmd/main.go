@@ -3,14 +3,23 @@ package main
 import (
 	"bot/internal/config"
 	"bot/internal/dbadapter"
+	"bot/internal/fsadapter"
+	"bot/internal/grpcserver"
 	"bot/internal/logger"
 	"bot/internal/minioadapter"
+	"bot/internal/notify"
 	srv "bot/internal/server"
+	"bot/internal/storage"
+	"bot/internal/telegram"
 	"context"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 func main() {
@@ -33,13 +42,24 @@ func main() {
 		return
 	}
 
-	MinIOAdapter, err := minioadapter.NewMinIOAdapter(logger, cfg)
+	ImageStorage, err := newImageStorage(logger, cfg)
+	if err != nil {
+		logger.Error("main::newImageStorage: ", err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go DBAdapter.RunEventRollups(ctx)
+
+	notifier, err := notify.NewNotifier(logger, cfg, DBAdapter)
 	if err != nil {
-		logger.Error("main::minioadapter::NewMinIOAdapter: ", err)
+		logger.Error("main::notify::NewNotifier: ", err)
 		return
 	}
+	go notifier.Run(ctx)
 
-	server, err := srv.NewServer(logger, cfg, DBAdapter, MinIOAdapter)
+	server, err := srv.NewServer(logger, cfg, DBAdapter, ImageStorage)
 	if err != nil {
 		logger.Error("main::server::NewServer: ", err)
 		return
@@ -51,12 +71,63 @@ func main() {
 		}
 	}()
 
+	if len(cfg.TelegramToken) != 0 && len(cfg.TelegramWebhookURL) != 0 {
+		api := telegram.NewAPI(cfg.TelegramAPIURL, cfg.TelegramToken)
+		if err := api.SetWebhook(cfg.TelegramWebhookURL, cfg.TelegramSecret); err != nil {
+			logger.Error("main::telegram::SetWebhook: ", err)
+		}
+	}
+
+	var grpcServer *grpc.Server
+	if cfg.GRPCPort != 0 {
+		creds, err := credentials.NewServerTLSFromFile("dev-full.crt", "dev-key.key")
+		if err != nil {
+			logger.Error("main::credentials::NewServerTLSFromFile: ", err)
+			return
+		}
+
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPCPort))
+		if err != nil {
+			logger.Error("main::net::Listen: ", err)
+			return
+		}
+
+		grpcServer = grpcserver.NewServer(logger, cfg, DBAdapter, ImageStorage, grpc.Creds(creds))
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				logger.Fatal("main::grpcServer::Serve: ", err)
+			}
+		}()
+	}
+
 	signalHandler := setupSignalHandler()
 	<-signalHandler
 
 	if err := server.Shutdown(context.Background()); err != nil {
 		logger.Error("main::server::Shutdown: ", err)
 	}
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
+}
+
+func newImageStorage(logger logger.Logger, cfg *config.Config) (storage.ImageStorage, error) {
+
+	switch cfg.StorageBackend {
+	case "fs", "local":
+		return fsadapter.NewFSAdapter(logger, cfg)
+	case "minio":
+		MinIOAdapter, err := minioadapter.NewMinIOAdapter(logger, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if err := MinIOAdapter.EnsureBucket(); err != nil {
+			return nil, err
+		}
+		return MinIOAdapter, nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", cfg.StorageBackend)
+	}
 }
 
 func setupSignalHandler() chan os.Signal {
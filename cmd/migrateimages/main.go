@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bot/internal/config"
+	"bot/internal/dbadapter"
+	"bot/internal/logger"
+	"bot/internal/minioadapter"
+	"fmt"
+)
+
+// Moves the images from the old layout with one bucket per master into
+// the single images bucket and registers them in the database
+func main() {
+
+	cfg, err := config.Load("config.toml")
+	if err != nil {
+		panic(fmt.Sprintf("migrateimages::config::Load: %s", err))
+	}
+
+	logger := logger.NewLogger()
+
+	DBAdapter, err := dbadapter.NewDbAdapter(logger, cfg)
+	if err != nil {
+		logger.Error("migrateimages::dbadapter::NewDBAdapter: ", err)
+		return
+	}
+
+	if err := DBAdapter.AutoMigrate(); err != nil {
+		logger.Error("migrateimages::dbadapter::AutoMigrate: ", err)
+		return
+	}
+
+	MinIOAdapter, err := minioadapter.NewMinIOAdapter(logger, cfg)
+	if err != nil {
+		logger.Error("migrateimages::minioadapter::NewMinIOAdapter: ", err)
+		return
+	}
+
+	if err := MinIOAdapter.EnsureBucket(); err != nil {
+		logger.Error("migrateimages::minioadapter::EnsureBucket: ", err)
+		return
+	}
+
+	buckets, err := MinIOAdapter.ListLegacyBuckets()
+	if err != nil {
+		logger.Error("migrateimages::minioadapter::ListLegacyBuckets: ", err)
+		return
+	}
+
+	migrated := 0
+	for _, bucket := range buckets {
+		if _, err := DBAdapter.GetMaster(bucket); err != nil {
+			logger.Infof("Bucket %s skipped, no master with this ID", bucket)
+			continue
+		}
+
+		images, err := MinIOAdapter.CopyLegacyBucket(bucket)
+		if err != nil {
+			logger.Errorf("migrateimages::minioadapter::CopyLegacyBucket: %s: %s", bucket, err)
+			return
+		}
+
+		for _, image := range images {
+			if err := DBAdapter.RegisterMasterImage(bucket, image); err != nil {
+				logger.Errorf("migrateimages::dbadapter::RegisterMasterImage: %s: %s", bucket, err)
+				return
+			}
+		}
+
+		if err := MinIOAdapter.RemoveLegacyBucket(bucket); err != nil {
+			logger.Errorf("migrateimages::minioadapter::RemoveLegacyBucket: %s: %s", bucket, err)
+			return
+		}
+		migrated++
+	}
+
+	logger.Infof("Image migration finished, buckets migrated: %d", migrated)
+}
@@ -0,0 +1,10 @@
+// Package docs embeds the OpenAPI document that swag generates from the handler annotations (mage genDoc)
+package docs
+
+import _ "embed"
+
+//go:embed swagger.json
+var JSON []byte
+
+//go:embed swagger.yaml
+var YAML []byte
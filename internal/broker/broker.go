@@ -0,0 +1,90 @@
+package broker
+
+import (
+	"bot/internal/entities"
+	"sync"
+	"time"
+)
+
+// the events a subscriber may lag behind before it is dropped
+const subscriberBuffer = 16
+
+// Broker fans the moderation events out to the subscribers. The latest events are kept
+// in a ring, a subscriber that reconnects gets the ones it missed while they are there
+type Broker struct {
+	mu          sync.Mutex
+	lastID      int64
+	ring        []*entities.ModerationEvent
+	next        int
+	count       int
+	subscribers map[chan *entities.ModerationEvent]bool
+}
+
+func New(size int) *Broker {
+	// the IDs continue from the start time, so the IDs a client got before a restart stay older
+	return &Broker{
+		lastID:      time.Now().UnixMicro(),
+		ring:        make([]*entities.ModerationEvent, size),
+		subscribers: make(map[chan *entities.ModerationEvent]bool),
+	}
+}
+
+// Publish sets the ID and the time of the event and sends it to the subscribers
+func (b *Broker) Publish(event *entities.ModerationEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event.ID = b.lastID
+	event.Time = time.Now()
+
+	if len(b.ring) != 0 {
+		b.ring[b.next] = event
+		b.next = (b.next + 1) % len(b.ring)
+		if b.count < len(b.ring) {
+			b.count++
+		}
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// the subscriber fell behind, it has to resume from the ring
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe returns the buffered events after lastID and the channel of the next ones,
+// the zero lastID skips the buffered events. The channel is closed when the subscriber falls behind,
+// cancel must be called when it leaves
+func (b *Broker) Subscribe(lastID int64) ([]*entities.ModerationEvent, <-chan *entities.ModerationEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	missed := make([]*entities.ModerationEvent, 0)
+	if lastID != 0 {
+		for i := b.count; i > 0; i-- {
+			event := b.ring[(b.next-i+len(b.ring))%len(b.ring)]
+			if event.ID > lastID {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	ch := make(chan *entities.ModerationEvent, subscriberBuffer)
+	b.subscribers[ch] = true
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if b.subscribers[ch] {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+	return missed, ch, cancel
+}
@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+type item struct {
+	value     any
+	expiresAt time.Time
+}
+
+type Cache struct {
+	mu      sync.RWMutex
+	items   map[string]item
+	ttl     time.Duration
+	maxSize int
+	gen     uint64 // bumped on every invalidation
+}
+
+// New creates a cache of at most maxSize keys, zero or less means no limit
+func New(ttl time.Duration, maxSize int) *Cache {
+	return &Cache{items: make(map[string]item), ttl: ttl, maxSize: maxSize}
+}
+
+func (c *Cache) Get(key string) (any, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	it, ok := c.items[key]
+	if !ok || time.Now().After(it.expiresAt) {
+		return nil, false
+	}
+	return it.value, true
+}
+
+func (c *Cache) Set(key string, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(key, value)
+}
+
+func (c *Cache) set(key string, value any) {
+	if c.ttl <= 0 {
+		return
+	}
+	if _, ok := c.items[key]; !ok && c.maxSize > 0 && len(c.items) >= c.maxSize {
+		c.evict()
+	}
+	c.items[key] = item{value: value, expiresAt: time.Now().Add(c.ttl)}
+}
+
+// evict makes room for a key, it drops the expired keys and, if there are none, the oldest one.
+// The keys share the TTL, so the oldest key expires first
+func (c *Cache) evict() {
+
+	now := time.Now()
+	oldestKey, oldest := "", time.Time{}
+	for key, it := range c.items {
+		if now.After(it.expiresAt) {
+			delete(c.items, key)
+			continue
+		}
+		if len(oldestKey) == 0 || it.expiresAt.Before(oldest) {
+			oldestKey, oldest = key, it.expiresAt
+		}
+	}
+
+	if len(c.items) >= c.maxSize {
+		delete(c.items, oldestKey)
+	}
+}
+
+func (c *Cache) generation() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.gen
+}
+
+// Invalidate drops every key that starts with one of the prefixes
+func (c *Cache) Invalidate(prefixes ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	for key := range c.items {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				delete(c.items, key)
+				break
+			}
+		}
+	}
+}
+
+// GetOrLoad returns the cached value for the key or calls load and caches its result
+func GetOrLoad[T any](c *Cache, key string, load func() (T, error)) (T, error) {
+	if value, ok := c.Get(key); ok {
+		if typed, ok := value.(T); ok {
+			return typed, nil
+		}
+	}
+
+	gen := c.generation()
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+
+	// skip caching if the data was invalidated while it was being loaded
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen == gen {
+		c.set(key, value)
+	}
+	return value, nil
+}
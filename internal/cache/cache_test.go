@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaxSizeEvictsOldest(t *testing.T) {
+
+	c := New(time.Minute, 2)
+	c.Set("cities:a", 1)
+	time.Sleep(time.Millisecond)
+	c.Set("cities:b", 2)
+	time.Sleep(time.Millisecond)
+
+	// a key already cached is replaced without an eviction
+	c.Set("cities:b", 3)
+	if len(c.items) != 2 {
+		t.Fatalf("got %d keys, want 2", len(c.items))
+	}
+
+	c.Set("cities:c", 4)
+	if len(c.items) != 2 {
+		t.Fatalf("got %d keys, want 2", len(c.items))
+	}
+	if _, ok := c.Get("cities:a"); ok {
+		t.Error("the oldest key is kept")
+	}
+	if value, ok := c.Get("cities:b"); !ok || value != 3 {
+		t.Errorf("got %v, want 3", value)
+	}
+	if value, ok := c.Get("cities:c"); !ok || value != 4 {
+		t.Errorf("got %v, want 4", value)
+	}
+}
+
+func TestMaxSizeDropsExpired(t *testing.T) {
+
+	c := New(20*time.Millisecond, 3)
+	c.Set("cities:a", 1)
+	c.Set("cities:b", 2)
+	time.Sleep(30 * time.Millisecond)
+	c.Set("cities:c", 3)
+	c.Set("cities:d", 4)
+
+	// the expired keys make the room, the live one stays
+	if len(c.items) != 2 {
+		t.Errorf("got %d keys, want 2", len(c.items))
+	}
+	if _, ok := c.Get("cities:c"); !ok {
+		t.Error("the live key was evicted")
+	}
+}
+
+func TestGetOrLoadSkipsInvalidated(t *testing.T) {
+
+	c := New(time.Minute, 0)
+	value, err := GetOrLoad(c, "cities:", func() (int, error) {
+		c.Invalidate("cities:")
+		return 1, nil
+	})
+	if err != nil || value != 1 {
+		t.Fatalf("got %d, %v", value, err)
+	}
+	if _, ok := c.Get("cities:"); ok {
+		t.Error("the value loaded before the invalidation is cached")
+	}
+}
@@ -0,0 +1,266 @@
+package catalog
+
+import (
+	"bot/internal/entities"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	JSON = "json"
+	CSV  = "csv"
+)
+
+// one CSV file holds the whole catalog, the type column tells the kind of the row
+var csvHeader = []string{"type", "id", "name", "category", "city", "description", "contact", "services", "status", "telegram_id"}
+
+// the service references of a master are joined into one cell
+const csvListSeparator = ";"
+
+// Format picks the format from the query parameter or the content type, JSON by default
+func Format(format, contentType string) (string, error) {
+	switch {
+	case format == JSON || format == CSV:
+		return format, nil
+	case len(format) != 0:
+		return "", fmt.Errorf("unknown format: %s", format)
+	case strings.Contains(contentType, "csv"):
+		return CSV, nil
+	default:
+		return JSON, nil
+	}
+}
+
+func ContentType(format string) string {
+	if format == CSV {
+		return "text/csv"
+	}
+	return "application/json"
+}
+
+func Read(r io.Reader, format string) (*entities.Catalog, error) {
+	if format == CSV {
+		return readCSV(r)
+	}
+
+	catalog := &entities.Catalog{}
+	if err := json.NewDecoder(r).Decode(catalog); err != nil {
+		return nil, err
+	}
+	return catalog, nil
+}
+
+func readCSV(r io.Reader) (*entities.Catalog, error) {
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for index, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = index
+	}
+	if _, ok := columns["type"]; !ok {
+		return nil, fmt.Errorf("the type column is missing")
+	}
+
+	catalog := &entities.Catalog{}
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return catalog, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		get := func(column string) string {
+			index, ok := columns[column]
+			if !ok || index >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[index])
+		}
+
+		switch get("type") {
+		case entities.CityKind:
+			catalog.Cities = append(catalog.Cities, &entities.City{ID: get("id"), Name: get("name")})
+		case entities.CategoryKind:
+			catalog.Categories = append(catalog.Categories, &entities.ServiceCategory{ID: get("id"), Name: get("name")})
+		case entities.ServiceKind:
+			catalog.Services = append(catalog.Services, &entities.Service{ID: get("id"), Name: get("name"), CatID: get("category")})
+		case entities.MasterKind:
+			master, err := masterFromRow(get)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			catalog.Masters = append(catalog.Masters, master)
+		case "":
+			continue
+		default:
+			return nil, fmt.Errorf("line %d: unknown type %q", line, get("type"))
+		}
+	}
+}
+
+func masterFromRow(get func(column string) string) (*entities.MasterLong, error) {
+
+	master := &entities.MasterLong{
+		ID: get("id"),
+		Master: entities.Master{
+			Name:        get("name"),
+			Description: get("description"),
+			Contact:     get("contact"),
+			CityID:      get("city"),
+			ServCatID:   get("category"),
+			ServIDs:     make([]string, 0),
+		},
+	}
+
+	for _, service := range strings.Split(get("services"), csvListSeparator) {
+		if service = strings.TrimSpace(service); len(service) != 0 {
+			master.ServIDs = append(master.ServIDs, service)
+		}
+	}
+
+	if status := get("status"); len(status) != 0 {
+		value, err := strconv.ParseUint(status, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		master.Status = uint(value)
+	}
+
+	if telegramID := get("telegram_id"); len(telegramID) != 0 {
+		value, err := strconv.ParseInt(telegramID, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		master.TelegramID = value
+	}
+
+	return master, nil
+}
+
+// Writer streams the catalog records, the records of one kind must come together
+type Writer interface {
+	Write(kind string, item interface{}) error
+	Close() error
+}
+
+func NewWriter(w io.Writer, format string) Writer {
+	if format == CSV {
+		return newCSVWriter(w)
+	}
+	return &jsonWriter{w: w}
+}
+
+// jsonWriter writes the same document Read accepts without keeping it in memory
+type jsonWriter struct {
+	w     io.Writer
+	kind  string
+	count int
+}
+
+var jsonSections = map[string]string{
+	entities.CityKind:     "cities",
+	entities.CategoryKind: "categories",
+	entities.ServiceKind:  "services",
+	entities.MasterKind:   "masters",
+}
+
+func (j *jsonWriter) Write(kind string, item interface{}) error {
+
+	body, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	var prefix string
+	switch {
+	case j.count == 0:
+		prefix = fmt.Sprintf("{\n%q: [\n", jsonSections[kind])
+	case kind != j.kind:
+		prefix = fmt.Sprintf("\n],\n%q: [\n", jsonSections[kind])
+	default:
+		prefix = ",\n"
+	}
+	j.kind = kind
+	j.count++
+
+	if _, err := io.WriteString(j.w, prefix); err != nil {
+		return err
+	}
+	_, err = j.w.Write(body)
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	suffix := "\n]\n}\n"
+	if j.count == 0 {
+		suffix = "{}\n"
+	}
+	_, err := io.WriteString(j.w, suffix)
+	return err
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) Write(kind string, item interface{}) error {
+
+	if !c.header {
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+		c.header = true
+	}
+
+	row := make([]string, len(csvHeader))
+	row[0] = kind
+	switch value := item.(type) {
+	case *entities.City:
+		row[1], row[2] = value.ID, value.Name
+	case *entities.ServiceCategory:
+		row[1], row[2] = value.ID, value.Name
+	case *entities.Service:
+		row[1], row[2], row[3] = value.ID, value.Name, value.CatID
+	case *entities.MasterLong:
+		row[1], row[2], row[3], row[4] = value.ID, value.Name, value.ServCatID, value.CityID
+		row[5], row[6] = value.Description, value.Contact
+		row[7] = strings.Join(value.ServIDs, csvListSeparator)
+		row[8] = strconv.FormatUint(uint64(value.Status), 10)
+		if value.TelegramID != 0 {
+			row[9] = strconv.FormatInt(value.TelegramID, 10)
+		}
+	default:
+		return fmt.Errorf("unexpected %s record: %T", kind, item)
+	}
+
+	return c.w.Write(row)
+}
+
+func (c *csvWriter) Close() error {
+	if !c.header {
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
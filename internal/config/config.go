@@ -1,11 +1,15 @@
 package config
 
 import (
+	"fmt"
+	"net/http"
+
 	"github.com/pelletier/go-toml"
 )
 
 type Config struct {
 	Port        int64
+	GRPCPort    int64
 	ImagePrefix string
 	PsqlHost    string
 	PsqlPort    int64
@@ -16,6 +20,63 @@ type Config struct {
 	MinIOPort   int64
 	MinIOUser   string
 	MinIOPass   string
+	// "minio" or "fs" ("local") for the local directory backend
+	StorageBackend string
+	StorageRoot    string
+	StorageSecret  string
+	MinIOBucket    string
+	MinIORegion    string
+	// host:port used in presigned URLs when clients can't reach MinIOHost
+	MinIOPublicEndpoint string
+	PrivateImages       bool
+	DownloadURLExpiry   int64
+	UploadURLExpiry     int64
+	MaxImageSize        int64
+	ImageTypes          []string
+	AdminKeys           []string
+	BotKeys             []string
+	RateLimits          []RateLimit
+	CacheTTL            int64
+	CacheMaxEntries     int64
+	CacheMaxAge         int64
+	EventBatchSize      int64
+	RollupInterval      int64
+	EventRetentionDays  int64
+	PopularDays         int64
+	StreamBufferSize    int64
+	GraphQLMaxDepth     int64
+	// the built-in bot is off without a token
+	TelegramToken      string
+	TelegramAPIURL     string
+	TelegramWebhookURL string
+	TelegramSecret     string
+	// the notification channels are off while their destination is empty
+	NotifyChatID     int64
+	SMTPHost         string
+	SMTPPort         int64
+	SMTPUser         string
+	SMTPPass         string
+	SMTPFrom         string
+	SMTPTo           []string
+	NotifyWebhookURL string
+	NotifyRetries    int64
+	NotifyRetryDelay int64
+	DigestInterval   int64
+	CreatedTemplate  string
+	StatusTemplate   string
+}
+
+type RateLimit struct {
+	Method string
+	Path   string
+	Rate   float64
+	Burst  int64
+}
+
+var defaultRateLimits = []RateLimit{
+	{Method: http.MethodPost, Path: "/masters", Rate: 1.0 / 60, Burst: 3},
+	{Method: http.MethodPost, Path: "/masters/{master_id}/images", Rate: 0.2, Burst: 10},
+	{Method: http.MethodPost, Path: "/masters/{master_id}/images/uploads", Rate: 0.2, Burst: 10},
 }
 
 func Load(path string) (*Config, error) {
@@ -25,17 +86,140 @@ func Load(path string) (*Config, error) {
 		return nil, err
 	}
 
-	return &Config{
-		Port:        cfg.Get("bot-server.port").(int64),
-		ImagePrefix: cfg.Get("bot-server.image_prefix").(string),
-		PsqlHost:    cfg.Get("postgres.host").(string),
-		PsqlPort:    cfg.Get("postgres.port").(int64),
-		PsqlUser:    cfg.Get("postgres.user").(string),
-		PsqlPass:    cfg.Get("postgres.password").(string),
-		PsqlDb:      cfg.Get("postgres.dbname").(string),
-		MinIOHost:   cfg.Get("minio.host").(string),
-		MinIOPort:   cfg.Get("minio.port").(int64),
-		MinIOUser:   cfg.Get("minio.user").(string),
-		MinIOPass:   cfg.Get("minio.password").(string),
-	}, nil
+	rateLimits, err := loadRateLimits(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{
+		Port:                cfg.Get("bot-server.port").(int64),
+		GRPCPort:            cfg.GetDefault("bot-server.grpc_port", int64(0)).(int64),
+		ImagePrefix:         cfg.Get("bot-server.image_prefix").(string),
+		PsqlHost:            cfg.Get("postgres.host").(string),
+		PsqlPort:            cfg.Get("postgres.port").(int64),
+		PsqlUser:            cfg.Get("postgres.user").(string),
+		PsqlPass:            cfg.Get("postgres.password").(string),
+		PsqlDb:              cfg.Get("postgres.dbname").(string),
+		MinIOHost:           cfg.GetDefault("minio.host", "").(string),
+		MinIOPort:           cfg.GetDefault("minio.port", int64(0)).(int64),
+		MinIOUser:           cfg.GetDefault("minio.user", "").(string),
+		MinIOPass:           cfg.GetDefault("minio.password", "").(string),
+		MinIOBucket:         cfg.GetDefault("minio.bucket", "bot-server").(string),
+		MinIORegion:         cfg.GetDefault("minio.region", "us-east-1").(string),
+		StorageBackend:      cfg.GetDefault("storage.backend", "minio").(string),
+		StorageRoot:         cfg.GetDefault("storage.root", "").(string),
+		StorageSecret:       cfg.GetDefault("storage.secret", "").(string),
+		MinIOPublicEndpoint: cfg.GetDefault("minio.public_endpoint", "").(string),
+		PrivateImages:       cfg.GetDefault("images.private", false).(bool),
+		DownloadURLExpiry:   cfg.GetDefault("images.download_url_expiry", int64(900)).(int64),
+		UploadURLExpiry:     cfg.GetDefault("images.upload_url_expiry", int64(300)).(int64),
+		MaxImageSize:        cfg.GetDefault("images.max_size", int64(10<<20)).(int64),
+		ImageTypes:          loadStrings(cfg, "images.content_types", []string{"image/jpeg", "image/png", "image/webp"}),
+		AdminKeys:           loadStrings(cfg, "auth.admin_keys", nil),
+		BotKeys:             loadStrings(cfg, "auth.bot_keys", nil),
+		RateLimits:          rateLimits,
+		CacheTTL:            cfg.GetDefault("cache.ttl", int64(60)).(int64),
+		CacheMaxEntries:     cfg.GetDefault("cache.max_entries", int64(1000)).(int64),
+		CacheMaxAge:         cfg.GetDefault("cache.max_age", int64(60)).(int64),
+		EventBatchSize:      cfg.GetDefault("events.batch_size", int64(100)).(int64),
+		RollupInterval:      cfg.GetDefault("events.rollup_interval", int64(3600)).(int64),
+		EventRetentionDays:  cfg.GetDefault("events.retention_days", int64(90)).(int64),
+		PopularDays:         cfg.GetDefault("events.popular_days", int64(30)).(int64),
+		StreamBufferSize:    cfg.GetDefault("events.stream_buffer", int64(100)).(int64),
+		GraphQLMaxDepth:     cfg.GetDefault("graphql.max_depth", int64(6)).(int64),
+		TelegramToken:       cfg.GetDefault("telegram.token", "").(string),
+		TelegramAPIURL:      cfg.GetDefault("telegram.api_url", "https://api.telegram.org").(string),
+		TelegramWebhookURL:  cfg.GetDefault("telegram.webhook_url", "").(string),
+		TelegramSecret:      cfg.GetDefault("telegram.webhook_secret", "").(string),
+		NotifyChatID:        cfg.GetDefault("notify.telegram_chat_id", int64(0)).(int64),
+		SMTPHost:            cfg.GetDefault("notify.smtp_host", "").(string),
+		SMTPPort:            cfg.GetDefault("notify.smtp_port", int64(587)).(int64),
+		SMTPUser:            cfg.GetDefault("notify.smtp_user", "").(string),
+		SMTPPass:            cfg.GetDefault("notify.smtp_password", "").(string),
+		SMTPFrom:            cfg.GetDefault("notify.smtp_from", "").(string),
+		SMTPTo:              loadStrings(cfg, "notify.smtp_to", nil),
+		NotifyWebhookURL:    cfg.GetDefault("notify.webhook_url", "").(string),
+		NotifyRetries:       cfg.GetDefault("notify.retries", int64(3)).(int64),
+		NotifyRetryDelay:    cfg.GetDefault("notify.retry_delay", int64(5)).(int64),
+		DigestInterval:      cfg.GetDefault("notify.digest_interval", int64(0)).(int64),
+		CreatedTemplate:     cfg.GetDefault("notify.created_template", "New master registered: {{.MasterName}} ({{status .Status}})").(string),
+		StatusTemplate:      cfg.GetDefault("notify.status_template", "Master {{.MasterName}} is {{status .Status}} now").(string),
+	}
+
+	// the links of the local backend are signed with the secret, a generated one wouldn't survive a restart
+	if config.StorageBackend == "fs" || config.StorageBackend == "local" {
+		if len(config.StorageRoot) == 0 || len(config.StorageSecret) == 0 {
+			return nil, fmt.Errorf("storage.root and storage.secret are required by the %s storage backend", config.StorageBackend)
+		}
+	}
+
+	// the webhook is public, the updates are told from the forged ones by the secret only
+	if len(config.TelegramToken) != 0 && len(config.TelegramSecret) == 0 {
+		return nil, fmt.Errorf("telegram.webhook_secret is required with telegram.token")
+	}
+
+	// the raw events are kept for the days, 0 keeps them forever.
+	// Yesterday is rolled up again, its events must still be there
+	if config.EventRetentionDays < 0 || config.EventRetentionDays == 1 {
+		return nil, fmt.Errorf("events.retention_days must be 0 or at least 2, got %d", config.EventRetentionDays)
+	}
+
+	return config, nil
+}
+
+func loadRateLimits(cfg *toml.Tree) ([]RateLimit, error) {
+
+	if !cfg.Has("rate-limit.routes") {
+		return defaultRateLimits, nil
+	}
+
+	routes, ok := cfg.Get("rate-limit.routes").([]*toml.Tree)
+	if !ok {
+		return nil, fmt.Errorf("rate-limit.routes must be an array of tables")
+	}
+
+	limits := make([]RateLimit, 0)
+	for index, route := range routes {
+		method, ok := route.GetDefault("method", http.MethodGet).(string)
+		if !ok {
+			return nil, fmt.Errorf("rate-limit.routes: invalid method in route %d", index)
+		}
+		path, ok := route.Get("path").(string)
+		if !ok {
+			return nil, fmt.Errorf("rate-limit.routes: invalid path in route %d", index)
+		}
+		burst, ok := route.Get("burst").(int64)
+		if !ok {
+			return nil, fmt.Errorf("rate-limit.routes: invalid burst for %s %s", method, path)
+		}
+
+		limit := RateLimit{Method: method, Path: path, Burst: burst}
+		switch rate := route.Get("rate").(type) {
+		case float64:
+			limit.Rate = rate
+		case int64:
+			limit.Rate = float64(rate)
+		default:
+			return nil, fmt.Errorf("rate-limit.routes: invalid rate for %s %s", limit.Method, limit.Path)
+		}
+		limits = append(limits, limit)
+	}
+
+	return limits, nil
+}
+
+func loadStrings(cfg *toml.Tree, key string, defaultValue []string) []string {
+
+	values, ok := cfg.Get(key).([]interface{})
+	if !ok {
+		return defaultValue
+	}
+
+	result := make([]string, 0)
+	for _, value := range values {
+		if str, ok := value.(string); ok {
+			result = append(result, str)
+		}
+	}
+	return result
 }
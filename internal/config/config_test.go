@@ -0,0 +1,123 @@
+package config
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pelletier/go-toml"
+)
+
+func TestLoadRateLimits(t *testing.T) {
+
+	tree, err := toml.Load(`
+[[rate-limit.routes]]
+method = "POST"
+path = "/masters"
+rate = 0.5
+burst = 3
+
+[[rate-limit.routes]]
+path = "/cities"
+rate = 2
+burst = 10
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limits, err := loadRateLimits(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []RateLimit{
+		{Method: http.MethodPost, Path: "/masters", Rate: 0.5, Burst: 3},
+		{Method: http.MethodGet, Path: "/cities", Rate: 2, Burst: 10},
+	}
+	if !reflect.DeepEqual(limits, want) {
+		t.Errorf("got %+v, want %+v", limits, want)
+	}
+}
+
+func TestLoadRateLimitsInvalid(t *testing.T) {
+
+	tests := map[string]string{
+		"no path":             "rate = 1\nburst = 1",
+		"path not a string":   "path = 1\nrate = 1\nburst = 1",
+		"no burst":            "path = \"/masters\"\nrate = 1",
+		"burst not an int":    "path = \"/masters\"\nrate = 1\nburst = 1.5",
+		"method not a string": "method = 1\npath = \"/masters\"\nrate = 1\nburst = 1",
+		"rate not a number":   "path = \"/masters\"\nrate = \"fast\"\nburst = 1",
+	}
+
+	for name, route := range tests {
+		t.Run(name, func(t *testing.T) {
+			tree, err := toml.Load("[[rate-limit.routes]]\n" + route)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loadRateLimits(tree); err == nil {
+				t.Error("the route is accepted")
+			}
+		})
+	}
+}
+
+// writeConfig writes the required settings with the given ones and returns the path of the file
+func writeConfig(t *testing.T, settings string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.toml")
+	data := `
+[bot-server]
+port = 8080
+image_prefix = "images"
+
+[postgres]
+host = "localhost"
+port = 5432
+user = "bot"
+password = "bot"
+dbname = "bot"
+
+` + settings
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadEventRetention(t *testing.T) {
+
+	tests := map[string]bool{
+		"":                     true,
+		"retention_days = 0":   true,
+		"retention_days = 2":   true,
+		"retention_days = 1":   false,
+		"retention_days = -30": false,
+	}
+
+	for events, valid := range tests {
+		t.Run(events, func(t *testing.T) {
+			config, err := Load(writeConfig(t, "[events]\n"+events))
+			if valid && err != nil {
+				t.Errorf("the retention is rejected: %v", err)
+			}
+			if !valid && err == nil {
+				t.Errorf("the retention %d is accepted", config.EventRetentionDays)
+			}
+		})
+	}
+}
+
+func TestLoadTelegramSecret(t *testing.T) {
+
+	if _, err := Load(writeConfig(t, "[telegram]\ntoken = \"123:token\"")); err == nil {
+		t.Error("the token is accepted without the webhook secret")
+	}
+	if _, err := Load(writeConfig(t, "[telegram]\ntoken = \"123:token\"\nwebhook_secret = \"s3cret\"")); err != nil {
+		t.Errorf("the token with the webhook secret is rejected: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package dbadapter
+
+import (
+	"bot/internal/entities"
+	"bot/internal/mapper"
+	"bot/internal/models"
+)
+
+// The batch lookups load the records of several IDs with a single query,
+// the IDs without a record are missing from the result
+
+func (d *DBAdapter) GetCitiesByIDs(ids []string) (map[string]*entities.City, error) {
+
+	result := make(map[string]*entities.City)
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	cities := make([]*models.City, 0)
+	if err := d.DBConn.Where("id IN ?", ids).Find(&cities).Error; err != nil {
+		return nil, err
+	}
+
+	for _, city := range cities {
+		result[city.ID] = mapper.FromCityModel(city)
+	}
+	return result, nil
+}
+
+func (d *DBAdapter) GetServCategoriesByIDs(ids []string) (map[string]*entities.ServiceCategory, error) {
+
+	result := make(map[string]*entities.ServiceCategory)
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	categories := make([]*models.ServiceCategory, 0)
+	if err := d.DBConn.Where("id IN ?", ids).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	for _, category := range categories {
+		result[category.ID] = mapper.FromServCatModel(category)
+	}
+	return result, nil
+}
+
+func (d *DBAdapter) GetServicesByIDs(ids []string) (map[string]*entities.Service, error) {
+
+	result := make(map[string]*entities.Service)
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	services := make([]*models.Service, 0)
+	if err := withCategory(d.DBConn).Where("services.id IN ?", ids).Find(&services).Error; err != nil {
+		return nil, err
+	}
+
+	for _, service := range services {
+		result[service.ID] = mapper.FromServiceModel(service)
+	}
+	return result, nil
+}
+
+// GetServicesByCategories returns the services of each category sorted by name
+func (d *DBAdapter) GetServicesByCategories(categoryIDs []string) (map[string][]*entities.Service, error) {
+
+	result := make(map[string][]*entities.Service)
+	if len(categoryIDs) == 0 {
+		return result, nil
+	}
+
+	services := make([]*models.Service, 0)
+	query := withCategory(d.DBConn).Where("services.cat_id IN ?", categoryIDs).Order("services.name, services.id")
+	if err := query.Find(&services).Error; err != nil {
+		return nil, err
+	}
+
+	for _, service := range services {
+		result[service.CatID] = append(result[service.CatID], mapper.FromServiceModel(service))
+	}
+	return result, nil
+}
+
+func (d *DBAdapter) GetMastersByIDs(ids []string) (map[string]*entities.MasterLong, error) {
+
+	result := make(map[string]*entities.MasterLong)
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	masterRecs := make([]*models.Master, 0)
+	if err := withRelations(d.DBConn).Where("masters.id IN ?", ids).Find(&masterRecs).Error; err != nil {
+		return nil, err
+	}
+
+	for _, rec := range masterRecs {
+		result[rec.ID] = mapper.FromMasterModel(rec)
+	}
+	return result, nil
+}
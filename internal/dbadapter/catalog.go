@@ -0,0 +1,460 @@
+package dbadapter
+
+import (
+	"bot/internal/entities"
+	"bot/internal/mapper"
+	"bot/internal/models"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidCatalog = errors.New("invalid catalog")
+
+const (
+	created = "created"
+	updated = "updated"
+)
+
+// catalogImport keeps the records known so far, so that the later
+// sections can refer to the earlier ones by ID or by name
+type catalogImport struct {
+	tx         *gorm.DB
+	result     *entities.ImportResult
+	cities     map[string]*models.City
+	categories map[string]*models.ServiceCategory
+	services   map[string]*models.Service
+}
+
+func nameKey(names ...string) string {
+	return strings.ToLower(strings.TrimSpace(strings.Join(names, "/")))
+}
+
+func (c *catalogImport) record(kind, action, id, name string, fields ...*entities.FieldChange) {
+	switch action {
+	case created:
+		c.result.Created++
+	case updated:
+		c.result.Updated++
+	default:
+		c.result.Unchanged++
+		return
+	}
+	c.result.Changes = append(c.result.Changes, &entities.CatalogChange{Kind: kind, Action: action, ID: id, Name: name, Fields: fields})
+}
+
+// diff lists the fields that differ, values holds the name, the old and the new value of each field
+func diff(values ...string) []*entities.FieldChange {
+	fields := make([]*entities.FieldChange, 0)
+	for index := 0; index+2 < len(values); index += 3 {
+		if values[index+1] != values[index+2] {
+			fields = append(fields, &entities.FieldChange{Field: values[index], Old: values[index+1], New: values[index+2]})
+		}
+	}
+	return fields
+}
+
+func (c *catalogImport) city(ref string) *models.City {
+	if city, ok := c.cities[ref]; ok {
+		return city
+	}
+	return c.cities[nameKey(ref)]
+}
+
+func (c *catalogImport) category(ref string) *models.ServiceCategory {
+	if category, ok := c.categories[ref]; ok {
+		return category
+	}
+	return c.categories[nameKey(ref)]
+}
+
+// service names are looked up within the category, they may repeat across categories
+func (c *catalogImport) service(catID, ref string) *models.Service {
+	if service, ok := c.services[ref]; ok {
+		return service
+	}
+	return c.services[nameKey(catID, ref)]
+}
+
+// ImportCatalog upserts the catalog in one transaction. Records are matched
+// by ID and then by name. With dryRun the transaction is rolled back and only
+// the changes that would be made are returned.
+func (d *DBAdapter) ImportCatalog(catalog *entities.Catalog, dryRun bool) (*entities.ImportResult, error) {
+
+	tx := d.DBConn.Begin()
+	defer tx.Rollback()
+
+	c := &catalogImport{
+		tx:         tx,
+		result:     &entities.ImportResult{DryRun: dryRun, Changes: make([]*entities.CatalogChange, 0)},
+		cities:     make(map[string]*models.City),
+		categories: make(map[string]*models.ServiceCategory),
+		services:   make(map[string]*models.Service),
+	}
+
+	if err := c.loadExisting(); err != nil {
+		return nil, err
+	}
+
+	for index, city := range catalog.Cities {
+		if err := c.importCity(city); err != nil {
+			return nil, fmt.Errorf("city %d: %w", index+1, err)
+		}
+	}
+	for index, category := range catalog.Categories {
+		if err := c.importCategory(category); err != nil {
+			return nil, fmt.Errorf("category %d: %w", index+1, err)
+		}
+	}
+	for index, service := range catalog.Services {
+		if err := c.importService(service); err != nil {
+			return nil, fmt.Errorf("service %d: %w", index+1, err)
+		}
+	}
+	for index, master := range catalog.Masters {
+		if err := c.importMaster(master); err != nil {
+			return nil, fmt.Errorf("master %d: %w", index+1, err)
+		}
+	}
+
+	if dryRun {
+		return c.result, nil
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	d.cache.Invalidate(citiesKey, categoriesKey, servicesKey)
+	d.logger.Infof("Catalog imported, created: %d, updated: %d, unchanged: %d", c.result.Created, c.result.Updated, c.result.Unchanged)
+	return c.result, nil
+}
+
+func (c *catalogImport) loadExisting() error {
+
+	cities := make([]*models.City, 0)
+	if err := c.tx.Find(&cities).Error; err != nil {
+		return err
+	}
+	for _, city := range cities {
+		c.cities[city.ID] = city
+		c.cities[nameKey(city.Name)] = city
+	}
+
+	categories := make([]*models.ServiceCategory, 0)
+	if err := c.tx.Find(&categories).Error; err != nil {
+		return err
+	}
+	for _, category := range categories {
+		c.categories[category.ID] = category
+		c.categories[nameKey(category.Name)] = category
+	}
+
+	services := make([]*models.Service, 0)
+	if err := c.tx.Find(&services).Error; err != nil {
+		return err
+	}
+	for _, service := range services {
+		c.services[service.ID] = service
+		c.services[nameKey(service.CatID, service.Name)] = service
+	}
+
+	return nil
+}
+
+func (c *catalogImport) importCity(city *entities.City) error {
+
+	if len(strings.TrimSpace(city.Name)) == 0 {
+		return fmt.Errorf("%w: name is required", ErrInvalidCatalog)
+	}
+
+	existing, ok := c.cities[city.ID]
+	if !ok {
+		existing, ok = c.cities[nameKey(city.Name)]
+	}
+
+	if !ok {
+		record := &models.City{ID: city.ID, Name: city.Name}
+		if len(record.ID) == 0 {
+			record.ID = uuid.NewString()
+		}
+		if err := c.tx.Create(record).Error; err != nil {
+			return err
+		}
+		c.cities[record.ID] = record
+		c.cities[nameKey(record.Name)] = record
+		c.record(entities.CityKind, created, record.ID, record.Name)
+		return nil
+	}
+
+	if existing.Name == city.Name {
+		c.record(entities.CityKind, "", existing.ID, existing.Name)
+		return nil
+	}
+
+	if err := updateVersioned(c.tx, &models.City{}, "city", existing.ID, 0, map[string]interface{}{"name": city.Name}); err != nil {
+		return err
+	}
+
+	fields := diff("name", existing.Name, city.Name)
+	delete(c.cities, nameKey(existing.Name))
+	existing.Name = city.Name
+	c.cities[nameKey(city.Name)] = existing
+	c.record(entities.CityKind, updated, existing.ID, existing.Name, fields...)
+	return nil
+}
+
+func (c *catalogImport) importCategory(category *entities.ServiceCategory) error {
+
+	if len(strings.TrimSpace(category.Name)) == 0 {
+		return fmt.Errorf("%w: name is required", ErrInvalidCatalog)
+	}
+
+	existing, ok := c.categories[category.ID]
+	if !ok {
+		existing, ok = c.categories[nameKey(category.Name)]
+	}
+
+	if !ok {
+		record := &models.ServiceCategory{ID: category.ID, Name: category.Name}
+		if len(record.ID) == 0 {
+			record.ID = uuid.NewString()
+		}
+		if err := c.tx.Create(record).Error; err != nil {
+			return err
+		}
+		c.categories[record.ID] = record
+		c.categories[nameKey(record.Name)] = record
+		c.record(entities.CategoryKind, created, record.ID, record.Name)
+		return nil
+	}
+
+	if existing.Name == category.Name {
+		c.record(entities.CategoryKind, "", existing.ID, existing.Name)
+		return nil
+	}
+
+	if err := updateVersioned(c.tx, &models.ServiceCategory{}, "category", existing.ID, 0, map[string]interface{}{"name": category.Name}); err != nil {
+		return err
+	}
+
+	fields := diff("name", existing.Name, category.Name)
+	delete(c.categories, nameKey(existing.Name))
+	existing.Name = category.Name
+	c.categories[nameKey(category.Name)] = existing
+	c.record(entities.CategoryKind, updated, existing.ID, existing.Name, fields...)
+	return nil
+}
+
+func (c *catalogImport) importService(service *entities.Service) error {
+
+	if len(strings.TrimSpace(service.Name)) == 0 {
+		return fmt.Errorf("%w: name is required", ErrInvalidCatalog)
+	}
+
+	category := c.category(service.CatID)
+	if category == nil {
+		category = c.category(service.CatName)
+	}
+	if category == nil {
+		return fmt.Errorf("%w: unknown category %q", ErrInvalidCatalog, service.CatID+service.CatName)
+	}
+
+	existing, ok := c.services[service.ID]
+	if !ok {
+		existing, ok = c.services[nameKey(category.ID, service.Name)]
+	}
+
+	if !ok {
+		record := &models.Service{ID: service.ID, Name: service.Name, CatID: category.ID}
+		if len(record.ID) == 0 {
+			record.ID = uuid.NewString()
+		}
+		if err := c.tx.Create(record).Error; err != nil {
+			return err
+		}
+		c.services[record.ID] = record
+		c.services[nameKey(record.CatID, record.Name)] = record
+		c.record(entities.ServiceKind, created, record.ID, record.Name)
+		return nil
+	}
+
+	if existing.Name == service.Name && existing.CatID == category.ID {
+		c.record(entities.ServiceKind, "", existing.ID, existing.Name)
+		return nil
+	}
+
+	update := &models.Service{ID: existing.ID, Name: service.Name, CatID: category.ID}
+	columns := map[string]interface{}{"name": update.Name, "cat_id": update.CatID}
+	if err := updateVersioned(c.tx, &models.Service{}, "service", update.ID, 0, columns); err != nil {
+		return err
+	}
+
+	fields := diff("name", existing.Name, update.Name, "catID", existing.CatID, update.CatID)
+	delete(c.services, nameKey(existing.CatID, existing.Name))
+	c.services[update.ID] = update
+	c.services[nameKey(update.CatID, update.Name)] = update
+	c.record(entities.ServiceKind, updated, update.ID, update.Name, fields...)
+	return nil
+}
+
+// importMaster matches the masters by ID only, a master without ID is always created
+func (c *catalogImport) importMaster(master *entities.MasterLong) error {
+
+	if len(strings.TrimSpace(master.Name)) == 0 || len(strings.TrimSpace(master.Contact)) == 0 {
+		return fmt.Errorf("%w: name and contact are required", ErrInvalidCatalog)
+	}
+
+	city := c.city(master.CityID)
+	if city == nil {
+		return fmt.Errorf("%w: unknown city %q", ErrInvalidCatalog, master.CityID)
+	}
+
+	category := c.category(master.ServCatID)
+	if category == nil {
+		return fmt.Errorf("%w: unknown category %q", ErrInvalidCatalog, master.ServCatID)
+	}
+
+	servIDs := make([]string, 0)
+	for _, ref := range master.ServIDs {
+		service := c.service(category.ID, ref)
+		if service == nil || service.CatID != category.ID {
+			return fmt.Errorf("%w: unknown service %q in category %q", ErrInvalidCatalog, ref, category.Name)
+		}
+		servIDs = append(servIDs, service.ID)
+	}
+
+	// imported masters are published unless told otherwise
+	status := master.Status
+	if status == 0 {
+		status = entities.APPROVED
+	}
+
+	resolved := &entities.MasterLong{
+		ID: master.ID,
+		Master: entities.Master{
+			Name:        master.Name,
+			Description: master.Description,
+			Contact:     master.Contact,
+			CityID:      city.ID,
+			ServCatID:   category.ID,
+			ServIDs:     servIDs,
+			Status:      status,
+			TelegramID:  master.TelegramID,
+		},
+	}
+
+	action := updated
+	var fields []*entities.FieldChange
+	existing := &models.Master{}
+	err := withRelations(c.tx).Where("masters.id = ?", master.ID).First(&existing).Error
+	switch {
+	case len(master.ID) != 0 && err == nil:
+		current := mapper.FromMasterModel(existing)
+		fields = masterDiff(&current.Master, &resolved.Master)
+		if len(fields) == 0 {
+			c.record(entities.MasterKind, "", existing.ID, existing.Name)
+			return nil
+		}
+	case len(master.ID) == 0 || errors.Is(err, gorm.ErrRecordNotFound):
+		if len(resolved.ID) == 0 {
+			resolved.ID = uuid.NewString()
+		}
+		record := &models.Master{
+			ID:         resolved.ID,
+			CreatedAt:  time.Now(),
+			Name:       resolved.Name,
+			Contact:    resolved.Contact,
+			CityID:     city.ID,
+			ServCatID:  category.ID,
+			Status:     entities.PENDING,
+			TelegramID: resolved.TelegramID,
+		}
+		if err := c.tx.Create(record).Error; err != nil {
+			return err
+		}
+		action = created
+	default:
+		return err
+	}
+
+	if err := updateMaster(c.tx, resolved); err != nil {
+		return err
+	}
+
+	c.record(entities.MasterKind, action, resolved.ID, resolved.Name, fields...)
+	return nil
+}
+
+func masterDiff(a, b *entities.Master) []*entities.FieldChange {
+	return diff(
+		"name", a.Name, b.Name,
+		"description", a.Description, b.Description,
+		"contact", a.Contact, b.Contact,
+		"cityID", a.CityID, b.CityID,
+		"servCatID", a.ServCatID, b.ServCatID,
+		"servIDs", strings.Join(a.ServIDs, ","), strings.Join(b.ServIDs, ","),
+		"status", strconv.FormatUint(uint64(a.Status), 10), strconv.FormatUint(uint64(b.Status), 10),
+	)
+}
+
+// ExportCatalog passes every city, category, service and master to write,
+// in this order, reading the tables in batches by primary key
+func (d *DBAdapter) ExportCatalog(write func(kind string, item interface{}) error) error {
+
+	const batchSize = 500
+
+	cities := make([]*models.City, 0)
+	err := d.DBConn.FindInBatches(&cities, batchSize, func(tx *gorm.DB, batch int) error {
+		for _, city := range cities {
+			if err := write(entities.CityKind, mapper.FromCityModel(city)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	categories := make([]*models.ServiceCategory, 0)
+	err = d.DBConn.FindInBatches(&categories, batchSize, func(tx *gorm.DB, batch int) error {
+		for _, category := range categories {
+			if err := write(entities.CategoryKind, mapper.FromServCatModel(category)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	services := make([]*models.Service, 0)
+	err = withCategory(d.DBConn).FindInBatches(&services, batchSize, func(tx *gorm.DB, batch int) error {
+		for _, service := range services {
+			if err := write(entities.ServiceKind, mapper.FromServiceModel(service)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	masters := make([]*models.Master, 0)
+	return withRelations(d.DBConn).FindInBatches(&masters, batchSize, func(tx *gorm.DB, batch int) error {
+		for _, master := range masters {
+			if err := write(entities.MasterKind, mapper.FromMasterModel(master)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
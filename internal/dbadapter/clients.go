@@ -0,0 +1,85 @@
+package dbadapter
+
+import (
+	"bot/internal/entities"
+	"bot/internal/mapper"
+	"bot/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (d *DBAdapter) GetClient(telegramID int64) (*entities.Client, error) {
+
+	client := &models.Client{}
+	if err := d.DBConn.Where("telegram_id = ?", telegramID).First(&client).Error; err != nil {
+		return nil, err
+	}
+
+	return mapper.FromClientModel(client), nil
+}
+
+// Client columns the profile update may change
+const (
+	ClientLanguage    = "language"
+	ClientDefaultCity = "default_city_id"
+)
+
+// SaveClient creates the client or updates the listed columns of its profile, the other columns
+// keep their values. Both refresh the last-seen time
+func (d *DBAdapter) SaveClient(client *entities.Client, columns []string) (*entities.Client, error) {
+
+	tx := d.DBConn.Begin()
+	defer tx.Rollback()
+
+	if len(client.DefaultCityID) != 0 {
+		if err := tx.Where("id = ?", client.DefaultCityID).First(&models.City{}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	record := &models.Client{
+		TelegramID: client.TelegramID,
+		Language:   client.Language,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	// no default city is NULL, the foreign key doesn't take an empty ID
+	if len(client.DefaultCityID) != 0 {
+		record.DefaultCityID = &client.DefaultCityID
+	}
+
+	upsert := clause.OnConflict{
+		Columns:   []clause.Column{{Name: "telegram_id"}},
+		DoUpdates: clause.AssignmentColumns(append(columns, "last_seen_at")),
+	}
+	if err := tx.Clauses(upsert).Create(record).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Where("telegram_id = ?", client.TelegramID).First(&record).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	d.logger.Infof("Client saved: %d", client.TelegramID)
+	return mapper.FromClientModel(record), nil
+}
+
+// touchClient makes sure the client exists and updates its last-seen time
+func touchClient(tx *gorm.DB, telegramID int64) error {
+
+	now := time.Now()
+	record := &models.Client{TelegramID: telegramID, CreatedAt: now, LastSeenAt: now}
+
+	upsert := clause.OnConflict{
+		Columns:   []clause.Column{{Name: "telegram_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_seen_at"}),
+	}
+	return tx.Clauses(upsert).Create(record).Error
+}
@@ -1,11 +1,15 @@
 package dbadapter
 
 import (
+	"bot/internal/broker"
+	"bot/internal/cache"
 	"bot/internal/config"
 	"bot/internal/entities"
 	"bot/internal/mapper"
 	"bot/internal/models"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"bot/internal/logger"
@@ -16,10 +20,27 @@ import (
 	"gorm.io/gorm"
 )
 
+const PopularOrder = "popular"
+
+// ErrVersionMismatch is returned when the record was changed after the client read it
+var ErrVersionMismatch = errors.New("version mismatch, the record was changed")
+
+// the search text is matched literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+const (
+	citiesKey     = "cities:"
+	categoriesKey = "categories:"
+	servicesKey   = "services:"
+	imagesKey     = "images:"
+)
+
 type DBAdapter struct {
 	logger logger.Logger
 	cfg    *config.Config
 	DBConn *gorm.DB
+	cache  *cache.Cache
+	broker *broker.Broker
 }
 
 func NewDbAdapter(logger logger.Logger, cfg *config.Config) (*DBAdapter, error) {
@@ -32,35 +53,75 @@ func NewDbAdapter(logger logger.Logger, cfg *config.Config) (*DBAdapter, error)
 		cfg.PsqlDb,
 	)
 
-	DBConn, err := gorm.Open(postgres.Open(psqlconf), &gorm.Config{})
+	DBConn, err := gorm.Open(postgres.Open(psqlconf), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return nil, err
 	}
 
-	return &DBAdapter{logger: logger, cfg: cfg, DBConn: DBConn}, nil
+	return &DBAdapter{
+		logger: logger,
+		cfg:    cfg,
+		DBConn: DBConn,
+		cache:  cache.New(time.Duration(cfg.CacheTTL)*time.Second, int(cfg.CacheMaxEntries)),
+		broker: broker.New(int(cfg.StreamBufferSize)),
+	}, nil
 }
 
 func (d *DBAdapter) AutoMigrate() error {
+	if err := d.migrateNames(); err != nil {
+		return err
+	}
 	if err := d.DBConn.AutoMigrate(&models.City{}); err != nil {
 		return err
 	}
 	if err := d.DBConn.AutoMigrate(&models.ServiceCategory{}); err != nil {
 		return err
 	}
-	if err := d.DBConn.AutoMigrate(&models.Service{}); err != nil {
+	if err := d.migrateRelations(); err != nil {
 		return err
 	}
-	if err := d.DBConn.AutoMigrate(&models.MasterServRelation{}); err != nil {
+	if err := d.DBConn.AutoMigrate(&models.Service{}); err != nil {
 		return err
 	}
 	if err := d.DBConn.AutoMigrate(&models.Master{}); err != nil {
 		return err
 	}
+	if err := d.DBConn.AutoMigrate(&models.MasterService{}); err != nil {
+		return err
+	}
+	if err := d.migrateOrphans(); err != nil {
+		return err
+	}
+	if err := d.DBConn.AutoMigrate(&models.Image{}); err != nil {
+		return err
+	}
+	if err := d.DBConn.AutoMigrate(&models.Client{}); err != nil {
+		return err
+	}
+	if err := d.DBConn.AutoMigrate(&models.MasterRevision{}); err != nil {
+		return err
+	}
+	if err := d.DBConn.AutoMigrate(&models.Favorite{}); err != nil {
+		return err
+	}
+	if err := d.DBConn.AutoMigrate(&models.Event{}); err != nil {
+		return err
+	}
+	if err := d.DBConn.AutoMigrate(&models.EventRollup{}); err != nil {
+		return err
+	}
 	d.logger.Info("Auto-migration: success")
 	return nil
 }
 
 func (d *DBAdapter) GetCities(servID string, page, limit int) ([]*entities.City, error) {
+	key := fmt.Sprintf("%s%s:%d:%d", citiesKey, servID, page, limit)
+	return cache.GetOrLoad(d.cache, key, func() ([]*entities.City, error) {
+		return d.getCities(servID, page, limit)
+	})
+}
+
+func (d *DBAdapter) getCities(servID string, page, limit int) ([]*entities.City, error) {
 
 	if len(servID) != 0 {
 		return d.GetCitiesByService(servID, page, limit)
@@ -79,27 +140,34 @@ func (d *DBAdapter) GetCities(servID string, page, limit int) ([]*entities.City,
 	return result, nil
 }
 
+// GetCitiesByService returns the cities with approved masters providing the service
 func (d *DBAdapter) GetCitiesByService(servID string, page, limit int) ([]*entities.City, error) {
 
-	masterServRelations := make([]*models.MasterServRelation, 0)
-	query := d.DBConn.Offset(page * limit).Limit(limit)
-	query = query.Where("serv_id = ?", servID).Select("DISTINCT ON (city_id) city_id, city_name")
-	if err := query.Find(&masterServRelations).Error; err != nil {
+	cities := make([]*models.City, 0)
+	query := d.DBConn.Offset(page * limit).Limit(limit).Order("cities.id")
+	query = query.Where(`EXISTS (
+		SELECT 1 FROM masters JOIN master_services ON master_services.master_id = masters.id
+		WHERE masters.city_id = cities.id AND masters.status = ? AND master_services.service_id = ?)`, entities.APPROVED, servID)
+	if err := query.Find(&cities).Error; err != nil {
 		return nil, err
 	}
 
 	result := make([]*entities.City, 0)
-	for _, relation := range masterServRelations {
-		result = append(result, &entities.City{
-			ID:   relation.CityID,
-			Name: relation.CityName,
-		})
+	for _, city := range cities {
+		result = append(result, mapper.FromCityModel(city))
 	}
 
 	return result, nil
 }
 
 func (d *DBAdapter) GetServCategories(cityID string, page, limit int) ([]*entities.ServiceCategory, error) {
+	key := fmt.Sprintf("%s%s:%d:%d", categoriesKey, cityID, page, limit)
+	return cache.GetOrLoad(d.cache, key, func() ([]*entities.ServiceCategory, error) {
+		return d.getServCategories(cityID, page, limit)
+	})
+}
+
+func (d *DBAdapter) getServCategories(cityID string, page, limit int) ([]*entities.ServiceCategory, error) {
 
 	if len(cityID) != 0 {
 		return d.GetServCategoriesByCity(cityID, page, limit)
@@ -118,56 +186,56 @@ func (d *DBAdapter) GetServCategories(cityID string, page, limit int) ([]*entiti
 	return result, nil
 }
 
+// GetServCategoriesByCity returns the categories with approved masters in the city
 func (d *DBAdapter) GetServCategoriesByCity(cityID string, page, limit int) ([]*entities.ServiceCategory, error) {
 
-	masterServRelations := make([]*models.MasterServRelation, 0)
-	query := d.DBConn.Offset(page * limit).Limit(limit)
-	query = query.Where("city_id = ?", cityID).Select("DISTINCT ON (serv_cat_id) serv_cat_id, serv_cat_name")
-	if err := query.Find(&masterServRelations).Error; err != nil {
+	categories := make([]*models.ServiceCategory, 0)
+	query := d.DBConn.Offset(page * limit).Limit(limit).Order("service_categories.id")
+	query = query.Where(`EXISTS (
+		SELECT 1 FROM masters JOIN master_services ON master_services.master_id = masters.id
+		WHERE masters.serv_cat_id = service_categories.id AND masters.status = ? AND masters.city_id = ?)`, entities.APPROVED, cityID)
+	if err := query.Find(&categories).Error; err != nil {
 		return nil, err
 	}
 
 	result := make([]*entities.ServiceCategory, 0)
-	for _, relation := range masterServRelations {
-		result = append(result, &entities.ServiceCategory{
-			ID:   relation.ServCatID,
-			Name: relation.ServCatName,
-		})
+	for _, category := range categories {
+		result = append(result, mapper.FromServCatModel(category))
 	}
 
 	return result, nil
 }
 
 func (d *DBAdapter) GetServices(categoryID, cityID string, page, limit int) ([]*entities.Service, error) {
-
-	if len(cityID) != 0 {
-		return d.GetServicesByCity(categoryID, cityID, page, limit)
-	}
-
-	return d.GetServicesByCategory(categoryID, page, limit)
+	key := fmt.Sprintf("%s%s:%s:%d:%d", servicesKey, categoryID, cityID, page, limit)
+	return cache.GetOrLoad(d.cache, key, func() ([]*entities.Service, error) {
+		if len(cityID) != 0 {
+			return d.GetServicesByCity(categoryID, cityID, page, limit)
+		}
+		return d.GetServicesByCategory(categoryID, page, limit)
+	})
 }
 
+// GetServicesByCity returns the services provided by approved masters in the city
 func (d *DBAdapter) GetServicesByCity(categoryID, cityID string, page, limit int) ([]*entities.Service, error) {
 
-	masterServRelations := make([]*models.MasterServRelation, 0)
-	query := d.DBConn.Offset(page * limit).Limit(limit)
+	query := withCategory(d.DBConn).Offset(page * limit).Limit(limit).Order("services.id")
 	if len(categoryID) != 0 {
-		query = query.Where("serv_cat_id = ?", categoryID)
+		query = query.Where("services.cat_id = ?", categoryID)
 	}
 
-	query = query.Where("city_id = ?", cityID).Select("DISTINCT ON (serv_id) serv_id, serv_name, serv_cat_id, serv_cat_name")
-	if err := query.Find(&masterServRelations).Error; err != nil {
+	query = query.Where(`EXISTS (
+		SELECT 1 FROM master_services JOIN masters ON masters.id = master_services.master_id
+		WHERE master_services.service_id = services.id AND masters.status = ? AND masters.city_id = ?)`, entities.APPROVED, cityID)
+
+	services := make([]*models.Service, 0)
+	if err := query.Find(&services).Error; err != nil {
 		return nil, err
 	}
 
 	result := make([]*entities.Service, 0)
-	for _, relation := range masterServRelations {
-		result = append(result, &entities.Service{
-			ID:      relation.ServID,
-			Name:    relation.ServName,
-			CatID:   relation.ServCatID,
-			CatName: relation.ServCatName,
-		})
+	for _, service := range services {
+		result = append(result, mapper.FromServiceModel(service))
 	}
 
 	return result, nil
@@ -175,9 +243,9 @@ func (d *DBAdapter) GetServicesByCity(categoryID, cityID string, page, limit int
 
 func (d *DBAdapter) GetServicesByCategory(categoryID string, page, limit int) ([]*entities.Service, error) {
 
-	query := d.DBConn.Offset(page * limit).Limit(limit)
+	query := withCategory(d.DBConn).Offset(page * limit).Limit(limit)
 	if len(categoryID) != 0 {
-		query = query.Where("cat_id = ?", categoryID)
+		query = query.Where("services.cat_id = ?", categoryID)
 	}
 
 	services := make([]*models.Service, 0)
@@ -193,38 +261,103 @@ func (d *DBAdapter) GetServicesByCategory(categoryID string, page, limit int) ([
 	return result, nil
 }
 
-func (d *DBAdapter) GetMastersBot(cityID, servCatID, servID string, page, limit int) ([]*entities.MasterShort, error) {
+// GetMastersBot returns the approved masters with at least one service. With PopularOrder
+// the masters viewed the most in the city over the last days come first.
+func (d *DBAdapter) GetMastersBot(cityID, servCatID, servID, order string, page, limit int) ([]*entities.MasterShort, error) {
 
-	query := d.DBConn.Offset(page * limit).Limit(limit)
+	query := withRelations(d.DBConn).Where("masters.status = ? AND cities.id IS NOT NULL AND service_categories.id IS NOT NULL", entities.APPROVED)
 	if len(cityID) != 0 {
-		query = query.Where("city_id = ?", cityID)
+		query = query.Where("masters.city_id = ?", cityID)
 	}
 	if len(servCatID) != 0 {
-		query = query.Where("serv_cat_id = ?", servCatID)
+		query = query.Where("masters.serv_cat_id = ?", servCatID)
 	}
 	if len(servID) != 0 {
-		query = query.Where("serv_id = ?", servID)
+		query = query.Where("EXISTS (SELECT 1 FROM master_services WHERE master_services.master_id = masters.id AND master_services.service_id = ?)", servID)
+	} else {
+		query = query.Where("EXISTS (SELECT 1 FROM master_services WHERE master_services.master_id = masters.id)")
+	}
+
+	if order == PopularOrder {
+		views := d.DBConn.Model(&models.EventRollup{}).
+			Select("master_id, SUM(count) AS views").
+			Where("type = ? AND day >= ?", entities.ViewedMaster, time.Now().AddDate(0, 0, -int(d.cfg.PopularDays)).Format("2006-01-02")).
+			Group("master_id")
+		if len(cityID) != 0 {
+			views = views.Where("city_id = ?", cityID)
+		}
+		query = query.Joins("LEFT JOIN (?) AS views ON views.master_id = masters.id", views).
+			Order("COALESCE(views.views, 0) DESC")
 	}
 
-	masters := make([]*models.MasterServRelation, 0)
-	if err := query.Select("DISTINCT ON (master_id) *").Find(&masters).Error; err != nil {
+	masters := make([]*models.Master, 0)
+	if err := query.Order("masters.id").Offset(page * limit).Limit(limit).Find(&masters).Error; err != nil {
 		return nil, err
 	}
 
 	result := make([]*entities.MasterShort, 0)
 	for _, master := range masters {
-		result = append(result, mapper.FromMasterServRelationModel(master))
+		result = append(result, mapper.FromMasterModelShort(master))
 	}
 	return result, nil
 }
 
-func (d *DBAdapter) GetMastersAdmin(page, limit int) ([]*entities.MasterShort, error) {
+func (d *DBAdapter) GetMastersAdmin(filter *entities.MastersFilter, page, limit int) ([]*entities.MasterShort, error) {
+
+	query := withRelations(d.DBConn)
+	if filter.Status != 0 {
+		query = query.Where("masters.status = ?", filter.Status)
+	}
+	if len(filter.CityID) != 0 {
+		query = query.Where("masters.city_id = ?", filter.CityID)
+	}
+	if len(filter.ServCatID) != 0 {
+		query = query.Where("masters.serv_cat_id = ?", filter.ServCatID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("masters.created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("masters.created_at < ?", filter.To)
+	}
+	if len(filter.Search) != 0 {
+		pattern := "%" + likeEscaper.Replace(filter.Search) + "%"
+		query = query.Where("(masters.name ILIKE ? OR masters.contact ILIKE ?)", pattern, pattern)
+	}
+
+	switch filter.Sort {
+	case entities.SortByName:
+		query = query.Order("masters.name, masters.id")
+	case entities.SortByNameDesc:
+		query = query.Order("masters.name DESC, masters.id")
+	case entities.SortByDateDesc:
+		query = query.Order("masters.created_at DESC, masters.id")
+	default:
+		query = query.Order("masters.created_at, masters.id")
+	}
 
 	masterRecs := make([]*models.Master, 0)
-	if err := d.DBConn.Find(&masterRecs).Offset(page * limit).Limit(limit).Error; err != nil {
+	if err := query.Offset(page * limit).Limit(limit).Find(&masterRecs).Error; err != nil {
 		return nil, err
 	}
 
+	servIDs := make([]string, 0)
+	for _, rec := range masterRecs {
+		servIDs = append(servIDs, rec.ServIDs...)
+	}
+
+	services := make([]*models.Service, 0)
+	if len(servIDs) != 0 {
+		if err := d.DBConn.Where("id IN ?", servIDs).Find(&services).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	servNames := make(map[string]string)
+	for _, service := range services {
+		servNames[service.ID] = service.Name
+	}
+
 	masters := make([]*entities.MasterShort, 0)
 	for _, rec := range masterRecs {
 		master := &entities.MasterShort{
@@ -235,6 +368,18 @@ func (d *DBAdapter) GetMastersAdmin(page, limit int) ([]*entities.MasterShort, e
 			CityName:    rec.CityName,
 			ServCatName: rec.ServCatName,
 			RegDate:     rec.CreatedAt.Format("2006-01-02"),
+			TelegramID:  rec.TelegramID,
+			Status:      rec.Status,
+			CityID:      rec.CityID,
+			ServCatID:   rec.ServCatID,
+			ServIDs:     rec.ServIDs,
+			ServNames:   make([]string, 0),
+		}
+
+		for _, servID := range rec.ServIDs {
+			if name, ok := servNames[servID]; ok {
+				master.ServNames = append(master.ServNames, name)
+			}
 		}
 
 		masters = append(masters, master)
@@ -243,31 +388,47 @@ func (d *DBAdapter) GetMastersAdmin(page, limit int) ([]*entities.MasterShort, e
 	return masters, nil
 }
 
+func (d *DBAdapter) GetCity(id string) (*entities.City, error) {
+	city := &models.City{}
+	if err := d.DBConn.Where("id = ?", id).First(city).Error; err != nil {
+		return nil, notFound(err, "city", id)
+	}
+	return mapper.FromCityModel(city), nil
+}
+
+func (d *DBAdapter) GetServCategory(id string) (*entities.ServiceCategory, error) {
+	category := &models.ServiceCategory{}
+	if err := d.DBConn.Where("id = ?", id).First(category).Error; err != nil {
+		return nil, notFound(err, "category", id)
+	}
+	return mapper.FromServCatModel(category), nil
+}
+
+func (d *DBAdapter) GetService(id string) (*entities.Service, error) {
+	service := &models.Service{}
+	if err := withCategory(d.DBConn).Where("services.id = ?", id).First(service).Error; err != nil {
+		return nil, notFound(err, "service", id)
+	}
+	return mapper.FromServiceModel(service), nil
+}
+
 func (d *DBAdapter) GetMaster(masterID string) (*entities.MasterLong, error) {
 
 	masterRec := &models.Master{}
-	if err := d.DBConn.Where("id = ?", masterID).First(&masterRec).Error; err != nil {
+	if err := withRelations(d.DBConn).Where("masters.id = ?", masterID).First(&masterRec).Error; err != nil {
 		return nil, err
 	}
 
-	master := &entities.MasterLong{
-		ID: masterID,
-		Master: entities.Master{
-			Name:        masterRec.Name,
-			Description: masterRec.Description,
-			Contact:     masterRec.Contact,
-			CityID:      masterRec.CityID,
-			ServCatID:   masterRec.ServCatID,
-			ServIDs:     masterRec.ServIDs,
-			Status:      masterRec.Status,
-		},
-	}
-
-	return master, nil
+	return mapper.FromMasterModel(masterRec), nil
 }
 
 func (d *DBAdapter) SaveCity(name string) (string, error) {
 	id := uuid.NewString()
+
+	if err := checkName(d.DBConn.Model(&models.City{}), "", name); err != nil {
+		return "", err
+	}
+
 	city := &models.City{
 		ID:   id,
 		Name: name,
@@ -275,12 +436,18 @@ func (d *DBAdapter) SaveCity(name string) (string, error) {
 	if err := d.DBConn.Create(city).Error; err != nil {
 		return "", err
 	}
+	d.cache.Invalidate(citiesKey)
 	d.logger.Infof("New city added successfully, id: %s, name: %s", id, name)
 	return id, nil
 }
 
 func (d *DBAdapter) SaveServiceCategory(name string) (string, error) {
 	id := uuid.NewString()
+
+	if err := checkName(d.DBConn.Model(&models.ServiceCategory{}), "", name); err != nil {
+		return "", err
+	}
+
 	service := &models.ServiceCategory{
 		ID:   id,
 		Name: name,
@@ -288,6 +455,7 @@ func (d *DBAdapter) SaveServiceCategory(name string) (string, error) {
 	if err := d.DBConn.Create(service).Error; err != nil {
 		return "", err
 	}
+	d.cache.Invalidate(categoriesKey)
 	d.logger.Infof("New service category added successfully, id: %s, name: %s", id, name)
 	return id, nil
 }
@@ -295,36 +463,43 @@ func (d *DBAdapter) SaveServiceCategory(name string) (string, error) {
 func (d *DBAdapter) SaveService(name, categoryID string) (string, error) {
 	id := uuid.NewString()
 
-	category := models.ServiceCategory{}
-	if err := d.DBConn.Where("id = ?", categoryID).First(&category).Error; err != nil {
+	if err := checkExists(d.DBConn, &models.ServiceCategory{}, "category", categoryID); err != nil {
+		return "", err
+	}
+
+	if err := checkName(d.DBConn.Model(&models.Service{}).Where("cat_id = ?", categoryID), "", name); err != nil {
 		return "", err
 	}
 
 	service := &models.Service{
-		ID:      id,
-		Name:    name,
-		CatID:   category.ID,
-		CatName: category.Name,
+		ID:    id,
+		Name:  name,
+		CatID: categoryID,
 	}
 	if err := d.DBConn.Create(service).Error; err != nil {
 		return "", err
 	}
+	d.cache.Invalidate(servicesKey)
 	d.logger.Infof("New service added successfully, id: %s, name: %s", id, name)
 	return id, nil
 }
 
 func (d *DBAdapter) SaveMaster(master *entities.Master) (string, error) {
+	return d.saveMaster(master, entities.PENDING)
+}
+
+// SaveApprovedMaster registers the master approved right away, the subscribers see one
+// registration with the final status instead of a registration and an approval
+func (d *DBAdapter) SaveApprovedMaster(master *entities.Master) (string, error) {
+	return d.saveMaster(master, entities.APPROVED)
+}
+
+func (d *DBAdapter) saveMaster(master *entities.Master, status uint) (string, error) {
 
 	tx := d.DBConn.Begin()
 	defer tx.Rollback()
 
-	city := &models.City{}
-	if err := tx.Where("id = ?", master.CityID).First(&city).Error; err != nil {
-		return "", err
-	}
-
-	servCat := &models.ServiceCategory{}
-	if err := tx.Where("id = ?", master.ServCatID).First(&servCat).Error; err != nil {
+	if err := checkMaster(tx, master); err != nil {
 		return "", err
 	}
 
@@ -335,42 +510,47 @@ func (d *DBAdapter) SaveMaster(master *entities.Master) (string, error) {
 		Name:        master.Name,
 		Contact:     master.Contact,
 		Description: master.Description,
-		CityID:      city.ID,
-		CityName:    city.Name,
-		ServCatID:   servCat.ID,
-		ServCatName: servCat.Name,
-		ServIDs:     master.ServIDs,
-		Status:      entities.PENDING,
+		CityID:      master.CityID,
+		ServCatID:   master.ServCatID,
+		Status:      status,
+		TelegramID:  master.TelegramID,
+	}
+	if status == entities.APPROVED {
+		masterRec.ApprovedAt = &masterRec.CreatedAt
+	}
+
+	if master.TelegramID != 0 {
+		if err := touchClient(tx, master.TelegramID); err != nil {
+			return "", err
+		}
 	}
 
 	if err := tx.Create(&masterRec).Error; err != nil {
 		return "", err
 	}
 
+	if err := saveMasterServices(tx, id, master.ServIDs); err != nil {
+		return "", err
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return "", err
 	}
 
+	if status == entities.APPROVED {
+		d.cache.Invalidate(citiesKey, categoriesKey, servicesKey)
+	}
 	d.logger.Infof("Form saved successfully, id: %s, name: %s", id, master.Name)
+	d.publish(&entities.ModerationEvent{Type: entities.MasterCreated, MasterID: id, MasterName: master.Name, Status: status})
 	return id, nil
 }
 
 func (d *DBAdapter) UpdateCity(city *entities.City) error {
 
-	update := models.City{
-		ID:   city.ID,
-		Name: city.Name,
-	}
-
 	tx := d.DBConn.Begin()
 	defer tx.Rollback()
 
-	if err := tx.Save(&update).Error; err != nil {
-		return err
-	}
-
-	query := tx.Model(&models.MasterServRelation{}).Where("city_id = ?", city.ID)
-	if err := query.UpdateColumn("city_name", city.Name).Error; err != nil {
+	if err := updateCity(tx, city); err != nil {
 		return err
 	}
 
@@ -378,31 +558,35 @@ func (d *DBAdapter) UpdateCity(city *entities.City) error {
 		return err
 	}
 
+	d.cache.Invalidate(citiesKey)
 	d.logger.Infof("City changed successfully: %s", city.Name)
 	return nil
 }
 
 func (d *DBAdapter) UpdateServCategory(category *entities.ServiceCategory) error {
 
-	update := models.ServiceCategory{
-		ID:   category.ID,
-		Name: category.Name,
-	}
-
 	tx := d.DBConn.Begin()
 	defer tx.Rollback()
 
-	if err := tx.Save(&update).Error; err != nil {
+	if err := updateServCategory(tx, category); err != nil {
 		return err
 	}
 
-	query := tx.Model(&models.Service{}).Where("cat_id = ?", category.ID)
-	if err := query.UpdateColumn("cat_name", category.Name).Error; err != nil {
+	if err := tx.Commit().Error; err != nil {
 		return err
 	}
 
-	query = tx.Model(&models.MasterServRelation{}).Where("serv_cat_id = ?", category.ID)
-	if err := query.UpdateColumn("serv_cat_name", category.Name).Error; err != nil {
+	d.cache.Invalidate(categoriesKey, servicesKey)
+	d.logger.Infof("Service category changed successfully: %s", category.Name)
+	return nil
+}
+
+func (d *DBAdapter) UpdateService(service *entities.Service) error {
+
+	tx := d.DBConn.Begin()
+	defer tx.Rollback()
+
+	if err := updateService(tx, service); err != nil {
 		return err
 	}
 
@@ -410,39 +594,56 @@ func (d *DBAdapter) UpdateServCategory(category *entities.ServiceCategory) error
 		return err
 	}
 
-	d.logger.Infof("Service category changed successfully: %s", category.Name)
+	d.cache.Invalidate(citiesKey, categoriesKey, servicesKey)
+	d.logger.Infof("Service changed successfully: %s", service.Name)
 	return nil
 }
 
-func (d *DBAdapter) UpdateService(service *entities.Service) error {
+func updateCity(tx *gorm.DB, city *entities.City) error {
 
-	category := models.ServiceCategory{}
-	if err := d.DBConn.Where("id = ?", service.CatID).First(&category).Error; err != nil {
+	if err := checkName(tx.Model(&models.City{}), city.ID, city.Name); err != nil {
 		return err
 	}
 
-	update := models.Service{
-		ID:      service.ID,
-		CatID:   category.ID,
-		CatName: category.Name,
-		Name:    service.Name,
+	columns := map[string]interface{}{"name": city.Name}
+	return updateVersioned(tx, &models.City{}, "city", city.ID, city.Version, columns)
+}
+
+func updateServCategory(tx *gorm.DB, category *entities.ServiceCategory) error {
+
+	if err := checkName(tx.Model(&models.ServiceCategory{}), category.ID, category.Name); err != nil {
+		return err
+	}
+
+	columns := map[string]interface{}{"name": category.Name}
+	return updateVersioned(tx, &models.ServiceCategory{}, "category", category.ID, category.Version, columns)
+}
+
+func updateService(tx *gorm.DB, service *entities.Service) error {
+
+	if err := checkExists(tx, &models.ServiceCategory{}, "category", service.CatID); err != nil {
+		return err
 	}
 
-	relation := models.MasterServRelation{
-		ServCatID:   update.CatID,
-		ServCatName: update.CatName,
-		ServName:    update.Name,
+	if err := checkName(tx.Model(&models.Service{}).Where("cat_id = ?", service.CatID), service.ID, service.Name); err != nil {
+		return err
 	}
 
+	columns := map[string]interface{}{"name": service.Name, "cat_id": service.CatID}
+	return updateVersioned(tx, &models.Service{}, "service", service.ID, service.Version, columns)
+}
+
+func (d *DBAdapter) UpdateMaster(master *entities.MasterLong) error {
+
 	tx := d.DBConn.Begin()
 	defer tx.Rollback()
 
-	if err := tx.Save(&update).Error; err != nil {
+	previousStatus, err := masterStatus(tx, master.ID)
+	if err != nil {
 		return err
 	}
 
-	query := tx.Model(&models.MasterServRelation{}).Where("serv_id = ?", service.ID)
-	if err := query.UpdateColumns(&relation).Error; err != nil {
+	if err := updateMaster(tx, master); err != nil {
 		return err
 	}
 
@@ -450,101 +651,197 @@ func (d *DBAdapter) UpdateService(service *entities.Service) error {
 		return err
 	}
 
-	d.logger.Infof("Service changed successfully: %s", service.Name)
+	d.cache.Invalidate(citiesKey, categoriesKey, servicesKey)
+	d.logger.Infof("Master info updated successfully: %s", master.Name)
+	d.publishMasterUpdate(master, previousStatus)
 	return nil
 }
 
-func (d *DBAdapter) UpdateMaster(master *entities.MasterLong) error {
+// updateMaster rewrites the master record and its service selection
+func updateMaster(tx *gorm.DB, master *entities.MasterLong) error {
 
-	tx := d.DBConn.Begin()
-	defer tx.Commit()
+	if err := checkMaster(tx, &master.Master); err != nil {
+		return err
+	}
 
-	city := &models.City{}
-	if err := tx.Where("id = ?", master.CityID).First(&city).Error; err != nil {
+	columns := map[string]interface{}{
+		"name":        master.Name,
+		"description": master.Description,
+		"contact":     master.Contact,
+		"city_id":     master.CityID,
+		"serv_cat_id": master.ServCatID,
+		"status":      master.Status,
+	}
+	if err := updateVersioned(tx, &models.Master{}, "master", master.ID, master.Version, columns); err != nil {
 		return err
 	}
 
-	servCat := &models.ServiceCategory{}
-	if err := tx.Where("id = ?", master.ServCatID).First(&servCat).Error; err != nil {
+	if err := saveMasterServices(tx, master.ID, master.ServIDs); err != nil {
 		return err
 	}
 
-	updatedMaster := models.Master{
-		ID:          master.ID,
-		Name:        master.Name,
-		Description: master.Description,
-		Contact:     master.Contact,
-		CityID:      city.ID,
-		CityName:    city.Name,
-		ServCatID:   servCat.ID,
-		ServCatName: servCat.Name,
-		ServIDs:     master.ServIDs,
-		Status:      entities.APPROVED,
+	if master.Status != entities.APPROVED {
+		return nil
 	}
 
-	if err := tx.Model(&models.Master{}).Where("id = ?", master.ID).UpdateColumns(&updatedMaster).Error; err != nil {
+	return markApproved(tx, master.ID)
+}
+
+// saveMasterServices replaces the service selection of the master
+func saveMasterServices(tx *gorm.DB, masterID string, servIDs []string) error {
+
+	if err := tx.Where("master_id = ?", masterID).Delete(&models.MasterService{}).Error; err != nil {
 		return err
 	}
 
-	if err := tx.Where("master_id = ?", updatedMaster.ID).Delete(&models.MasterServRelation{}).Error; err != nil {
-		return err
+	records := make([]*models.MasterService, 0)
+	selected := make(map[string]bool)
+	for index, servID := range servIDs {
+		if selected[servID] {
+			continue
+		}
+		selected[servID] = true
+		records = append(records, &models.MasterService{MasterID: masterID, ServiceID: servID, Position: index + 1})
 	}
 
-	if updatedMaster.Status != entities.APPROVED {
+	if len(records) == 0 {
 		return nil
 	}
 
-	masterServRelations := make([]*models.MasterServRelation, 0)
+	return tx.Create(&records).Error
+}
 
-	for _, servID := range updatedMaster.ServIDs {
-		service := &models.Service{}
-		if err := tx.Where("id = ?", servID).First(&service).Error; err != nil {
+// checkMaster checks that the city, the category and the services of the master exist
+// and that the services belong to the category
+func checkMaster(tx *gorm.DB, master *entities.Master) error {
+
+	if err := checkExists(tx, &models.City{}, "city", master.CityID); err != nil {
+		return err
+	}
+
+	if err := checkExists(tx, &models.ServiceCategory{}, "category", master.ServCatID); err != nil {
+		return err
+	}
+
+	for _, servID := range master.ServIDs {
+		if err := checkExists(tx.Where("cat_id = ?", master.ServCatID), &models.Service{}, "service", servID); err != nil {
 			return err
 		}
-		record := &models.MasterServRelation{
-			MasterID:    updatedMaster.ID,
-			Name:        updatedMaster.Name,
-			Description: updatedMaster.Description,
-			Contact:     updatedMaster.Contact,
-			CityID:      city.ID,
-			CityName:    city.Name,
-			ServCatID:   servCat.ID,
-			ServCatName: servCat.Name,
-			ServID:      service.ID,
-			ServName:    service.Name,
-		}
-		masterServRelations = append(masterServRelations, record)
 	}
+	return nil
+}
 
-	if err := tx.Create(&masterServRelations).Error; err != nil {
+// checkExists returns gorm.ErrRecordNotFound naming the missing record
+func checkExists(tx *gorm.DB, model interface{}, kind, id string) error {
+	var count int64
+	if err := tx.Model(model).Where("id = ?", id).Count(&count).Error; err != nil {
 		return err
 	}
+	if count == 0 {
+		return fmt.Errorf("%s %s: %w", kind, id, gorm.ErrRecordNotFound)
+	}
+	return nil
+}
 
-	if err := tx.Commit().Error; err != nil {
+// notFound names the missing record in gorm.ErrRecordNotFound
+func notFound(err error, kind, id string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%s %s: %w", kind, id, err)
+	}
+	return err
+}
+
+// checkName returns gorm.ErrDuplicatedKey when another record in the scope has the same name,
+// the names are compared ignoring case
+func checkName(scope *gorm.DB, id, name string) error {
+	var count int64
+	if err := scope.Where("LOWER(name) = LOWER(?) AND id <> ?", name, id).Count(&count).Error; err != nil {
 		return err
 	}
+	if count > 0 {
+		return fmt.Errorf("name %q is already taken: %w", name, gorm.ErrDuplicatedKey)
+	}
+	return nil
+}
 
-	d.logger.Infof("Master info updated successfully: %s", master.Name)
+// updateVersioned updates the record if it still has the version the client read and bumps the version,
+// the zero version updates the record whatever its version is
+func updateVersioned(tx *gorm.DB, model interface{}, kind, id string, version int64, columns map[string]interface{}) error {
+
+	columns["version"] = gorm.Expr("version + 1")
+
+	query := tx.Model(model).Where("id = ?", id)
+	if version != 0 {
+		query = query.Where("version = ?", version)
+	}
+
+	query = query.UpdateColumns(columns)
+	if err := query.Error; err != nil {
+		return err
+	}
+	if query.RowsAffected != 0 {
+		return nil
+	}
+	return checkVersion(tx, model, kind, id, version)
+}
+
+// checkVersion returns gorm.ErrRecordNotFound for a missing record and ErrVersionMismatch
+// if the record has another version
+func checkVersion(tx *gorm.DB, model interface{}, kind, id string, version int64) error {
+
+	if err := checkExists(tx, model, kind, id); err != nil {
+		return err
+	}
+
+	if version == 0 {
+		return nil
+	}
+
+	var count int64
+	if err := tx.Model(model).Where("id = ? AND version = ?", id, version).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%s %s: %w", kind, id, ErrVersionMismatch)
+	}
 	return nil
 }
 
+// withRelations selects the masters with the names of their city and category and their service selection.
+// The city and the category are NULL once deleted, gorm reads them as empty
+func withRelations(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.Master{}).
+		Select(`masters.*, cities.name AS city_name, service_categories.name AS serv_cat_name,
+			ARRAY(SELECT service_id FROM master_services WHERE master_services.master_id = masters.id ORDER BY position)::text[] AS serv_ids`).
+		Joins("LEFT JOIN cities ON cities.id = masters.city_id").
+		Joins("LEFT JOIN service_categories ON service_categories.id = masters.serv_cat_id")
+}
+
+// withCategory selects the services with the names of their category
+func withCategory(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.Service{}).
+		Select("services.*, service_categories.name AS cat_name").
+		Joins("JOIN service_categories ON service_categories.id = services.cat_id")
+}
+
 func (d *DBAdapter) DeleteCity(id string) error {
 
 	tx := d.DBConn.Begin()
 	defer tx.Rollback()
 
-	if err := tx.Where("id = ?", id).Delete(&models.City{}).Error; err != nil {
+	query := tx.Where("id = ?", id).Delete(&models.City{})
+	if err := query.Error; err != nil {
 		return err
 	}
-
-	if err := tx.Where("city_id = ?", id).Delete(&models.MasterServRelation{}).Error; err != nil {
-		return err
+	if query.RowsAffected == 0 {
+		return fmt.Errorf("city %s: %w", id, gorm.ErrRecordNotFound)
 	}
 
 	if err := tx.Commit().Error; err != nil {
 		return err
 	}
 
+	d.cache.Invalidate(citiesKey, categoriesKey, servicesKey)
 	d.logger.Infof("City was deleted successfully: %s", id)
 	return nil
 }
@@ -554,22 +851,20 @@ func (d *DBAdapter) DeleteServCategory(id string) error {
 	tx := d.DBConn.Begin()
 	defer tx.Rollback()
 
-	if err := tx.Where("id = ?", id).Delete(&models.ServiceCategory{}).Error; err != nil {
+	// the services and their master selections are deleted by the database
+	query := tx.Where("id = ?", id).Delete(&models.ServiceCategory{})
+	if err := query.Error; err != nil {
 		return err
 	}
-
-	if err := tx.Where("cat_id = ?", id).Delete(&models.Service{}).Error; err != nil {
-		return err
-	}
-
-	if err := tx.Where("serv_cat_id = ?", id).Delete(&models.MasterServRelation{}).Error; err != nil {
-		return err
+	if query.RowsAffected == 0 {
+		return fmt.Errorf("category %s: %w", id, gorm.ErrRecordNotFound)
 	}
 
 	if err := tx.Commit().Error; err != nil {
 		return err
 	}
 
+	d.cache.Invalidate(citiesKey, categoriesKey, servicesKey)
 	d.logger.Infof("ServiceCategory was deleted successfully: %s", id)
 	return nil
 }
@@ -579,39 +874,35 @@ func (d *DBAdapter) DeleteService(id string) error {
 	tx := d.DBConn.Begin()
 	defer tx.Rollback()
 
-	if err := tx.Where("id = ?", id).Delete(&models.Service{}).Error; err != nil {
+	query := tx.Where("id = ?", id).Delete(&models.Service{})
+	if err := query.Error; err != nil {
 		return err
 	}
-
-	if err := tx.Where("serv_id = ?", id).Delete(&models.MasterServRelation{}).Error; err != nil {
-		return err
+	if query.RowsAffected == 0 {
+		return fmt.Errorf("service %s: %w", id, gorm.ErrRecordNotFound)
 	}
 
 	if err := tx.Commit().Error; err != nil {
 		return err
 	}
 
+	d.cache.Invalidate(citiesKey, categoriesKey, servicesKey)
 	d.logger.Infof("Service was deleted successfully: %s", id)
 	return nil
 }
 
+// DeleteMaster deletes the master, its services, images, revisions and favorites go with it by their foreign keys
 func (d *DBAdapter) DeleteMaster(id string) error {
 
-	tx := d.DBConn.Begin()
-	defer tx.Rollback()
-
-	if err := tx.Where("id = ?", id).Delete(&models.Master{}).Error; err != nil {
+	query := d.DBConn.Where("id = ?", id).Delete(&models.Master{})
+	if err := query.Error; err != nil {
 		return err
 	}
-
-	if err := tx.Where("master_id = ?", id).Delete(&models.MasterServRelation{}).Error; err != nil {
-		return err
-	}
-
-	if err := tx.Commit().Error; err != nil {
-		return err
+	if query.RowsAffected == 0 {
+		return fmt.Errorf("master %s: %w", id, gorm.ErrRecordNotFound)
 	}
 
+	d.cache.Invalidate(citiesKey, categoriesKey, servicesKey, imagesKey+id)
 	d.logger.Infof("Master was deleted successfully: %s", id)
 	return nil
 }
@@ -626,39 +917,17 @@ func (d *DBAdapter) ApproveMaster(id string) error {
 		return err
 	}
 
-	city := &models.City{}
-	if err := tx.Where("id = ?", master.CityID).First(&city).Error; err != nil {
+	// the master is listed under the services it selected
+	if err := tx.Where("id = ?", master.CityID).First(&models.City{}).Error; err != nil {
 		return err
 	}
 
-	services := make([]*models.Service, 0)
-	if err := tx.Where("cat_id = ?", master.ServCatID).Find(&services).Error; err != nil {
-		return err
-	}
-
-	masterServRelations := make([]*models.MasterServRelation, 0)
-
-	for _, service := range services {
-		record := &models.MasterServRelation{
-			MasterID:    master.ID,
-			Name:        master.Name,
-			Description: master.Description,
-			Contact:     master.Contact,
-			CityID:      city.ID,
-			CityName:    city.Name,
-			ServCatID:   service.CatID,
-			ServCatName: service.CatName,
-			ServID:      service.ID,
-			ServName:    service.Name,
-		}
-		masterServRelations = append(masterServRelations, record)
-	}
-
-	if err := tx.Create(&masterServRelations).Error; err != nil {
+	columns := map[string]interface{}{"status": entities.APPROVED}
+	if err := updateVersioned(tx, &models.Master{}, "master", id, 0, columns); err != nil {
 		return err
 	}
 
-	if err := tx.Model(&models.Master{}).Where("id = ?", id).UpdateColumn("status", entities.APPROVED).Error; err != nil {
+	if err := markApproved(tx, id); err != nil {
 		return err
 	}
 
@@ -666,6 +935,16 @@ func (d *DBAdapter) ApproveMaster(id string) error {
 		return err
 	}
 
+	d.cache.Invalidate(citiesKey, categoriesKey, servicesKey)
 	d.logger.Infof("Master %s was approved", id)
+	if master.Status != entities.APPROVED {
+		d.publish(&entities.ModerationEvent{Type: entities.MasterStatusChanged, MasterID: id, MasterName: master.Name, Status: entities.APPROVED})
+	}
 	return nil
 }
+
+// markApproved keeps the time of the first approval, later edits don't move it
+func markApproved(tx *gorm.DB, id string) error {
+	query := tx.Model(&models.Master{}).Where("id = ? AND approved_at IS NULL", id)
+	return query.UpdateColumn("approved_at", time.Now()).Error
+}
@@ -0,0 +1,467 @@
+package dbadapter
+
+import (
+	"bot/internal/dbtest"
+	"bot/internal/entities"
+	"bot/internal/logger"
+	"bot/internal/models"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// newTestAdapter connects to the database of TEST_POSTGRES_HOST and migrates it,
+// the test is skipped without one
+func newTestAdapter(t *testing.T) *DBAdapter {
+	t.Helper()
+
+	adapter, err := NewDbAdapter(logger.NewLogger(), dbtest.Config(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := adapter.AutoMigrate(); err != nil {
+		t.Fatal(err)
+	}
+	return adapter
+}
+
+// newTestMaster saves a master in a new city and category, they are deleted after the test
+func newTestMaster(t *testing.T, adapter *DBAdapter, suffix string, approved bool) string {
+	t.Helper()
+
+	cityID, err := adapter.SaveCity("city " + suffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	categoryID, err := adapter.SaveServiceCategory("category " + suffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	save := adapter.SaveMaster
+	if approved {
+		save = adapter.SaveApprovedMaster
+	}
+	masterID, err := save(&entities.Master{Name: "master " + suffix, Contact: "@master", CityID: cityID, ServCatID: categoryID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		adapter.DeleteMaster(masterID)
+		adapter.DeleteServCategory(categoryID)
+		adapter.DeleteCity(cityID)
+	})
+	return masterID
+}
+
+func TestDeletedCityUnlistsMaster(t *testing.T) {
+
+	adapter := newTestAdapter(t)
+	suffix := uuid.NewString()
+
+	cityID, err := adapter.SaveCity("city " + suffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	categoryID, err := adapter.SaveServiceCategory("category " + suffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serviceID, err := adapter.SaveService("service "+suffix, categoryID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	masterID, err := adapter.SaveMaster(&entities.Master{
+		Name:      "master " + suffix,
+		Contact:   "@master",
+		CityID:    cityID,
+		ServCatID: categoryID,
+		ServIDs:   []string{serviceID},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		adapter.DeleteMaster(masterID)
+		adapter.DeleteServCategory(categoryID)
+	})
+
+	if err := adapter.DeleteCity(cityID); err != nil {
+		t.Fatal(err)
+	}
+
+	master, err := adapter.GetMaster(masterID)
+	if err != nil {
+		t.Fatalf("GetMaster after the city was deleted: %s", err)
+	}
+	if master.CityID != "" || master.ServCatID != categoryID {
+		t.Errorf("got city %q and category %q, want no city and %q", master.CityID, master.ServCatID, categoryID)
+	}
+
+	masters, err := adapter.GetMastersAdmin(&entities.MastersFilter{Search: suffix}, 0, 10)
+	if err != nil {
+		t.Fatalf("GetMastersAdmin after the city was deleted: %s", err)
+	}
+	found := false
+	for _, short := range masters {
+		found = found || short.ID == masterID
+	}
+	if !found {
+		t.Errorf("master %s is missing from the admin list", masterID)
+	}
+
+	if _, err := adapter.GetStats(7); err != nil {
+		t.Fatalf("GetStats after the city was deleted: %s", err)
+	}
+}
+
+// the rows of the master go with it and the clients lose the deleted city, whatever deletes them
+func TestForeignKeysCascade(t *testing.T) {
+
+	adapter := newTestAdapter(t)
+	suffix := uuid.NewString()
+	telegramID := time.Now().UnixNano()
+
+	cityID, err := adapter.SaveCity("city " + suffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	categoryID, err := adapter.SaveServiceCategory("category " + suffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	masterID, err := adapter.SaveApprovedMaster(&entities.Master{Name: "master " + suffix, Contact: "@master", CityID: cityID, ServCatID: categoryID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		adapter.DeleteServCategory(categoryID)
+		adapter.DBConn.Delete(&models.Client{}, "telegram_id = ?", telegramID)
+	})
+
+	if err := adapter.SaveMasterImage(masterID, "key-"+suffix, &entities.Image{Name: suffix}, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := adapter.SaveClient(&entities.Client{TelegramID: telegramID, DefaultCityID: cityID}, []string{ClientDefaultCity}); err != nil {
+		t.Fatal(err)
+	}
+	if err := adapter.SaveFavorite(telegramID, masterID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := adapter.DBConn.Exec("DELETE FROM masters WHERE id = ?", masterID).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, model := range []interface{}{&models.Image{}, &models.MasterRevision{}, &models.Favorite{}} {
+		var count int64
+		if err := adapter.DBConn.Model(model).Where("master_id = ?", masterID).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("%T: %d rows left of the deleted master", model, count)
+		}
+	}
+
+	if err := adapter.DeleteCity(cityID); err != nil {
+		t.Fatal(err)
+	}
+	client, err := adapter.GetClient(telegramID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.DefaultCityID != "" {
+		t.Errorf("got the default city %q, want none", client.DefaultCityID)
+	}
+}
+
+func TestImageChangesWaitForRevision(t *testing.T) {
+
+	adapter := newTestAdapter(t)
+	suffix := uuid.NewString()
+
+	masterID := newTestMaster(t, adapter, suffix, true)
+
+	live := func() []*entities.Image {
+		t.Helper()
+		images, err := adapter.GetMasterImages(masterID, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return images
+	}
+
+	// the admin changes the listing right away
+	if err := adapter.SaveMasterImage(masterID, "key-a", &entities.Image{Name: "a-" + suffix, Caption: "first"}, false); err != nil {
+		t.Fatal(err)
+	}
+	if images := live(); len(images) != 1 || !images[0].IsPrimary {
+		t.Fatalf("got %d live images, want the cover", len(images))
+	}
+
+	// the changes of the master wait for the revision
+	if err := adapter.SaveMasterImage(masterID, "key-b", &entities.Image{Name: "b-" + suffix}, true); err != nil {
+		t.Fatal(err)
+	}
+	replaced, err := adapter.ReplaceMasterImage(masterID, "a-"+suffix, "key-c", &entities.Image{Name: "c-" + suffix}, true)
+	if err != nil || replaced != "" {
+		t.Fatalf("got %q, %v, want the old object kept", replaced, err)
+	}
+	if err := adapter.UpdateMasterImageCaption(masterID, "a-"+suffix, "second", true); err != nil {
+		t.Fatal(err)
+	}
+
+	if images := live(); len(images) != 1 || images[0].Name != "a-"+suffix || images[0].Caption != "first" {
+		t.Fatalf("the live images changed before the review: %+v", images[0])
+	}
+
+	revisions, err := adapter.GetMasterRevisions(masterID, entities.PENDING, 0, 10)
+	if err != nil || len(revisions) != 1 {
+		t.Fatalf("got %d pending revisions, %v", len(revisions), err)
+	}
+	pending, err := adapter.GetPendingImages([]string{masterID})
+	if err != nil || len(pending[masterID]) != 3 {
+		t.Fatalf("got %d pending image changes, %v", len(pending[masterID]), err)
+	}
+
+	objectKeys, err := adapter.ApproveMasterRevision(revisions[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objectKeys) != 1 || objectKeys[0] != "key-a" {
+		t.Errorf("got the objects %v to delete, want key-a", objectKeys)
+	}
+
+	images := live()
+	if len(images) != 2 || images[0].Name != "c-"+suffix || !images[0].IsPrimary || images[0].Caption != "second" || images[1].Name != "b-"+suffix {
+		t.Errorf("the changes were not published: %+v", images)
+	}
+}
+
+func TestRevisionKeepsLaterChanges(t *testing.T) {
+
+	adapter := newTestAdapter(t)
+	suffix := uuid.NewString()
+
+	cityID, err := adapter.SaveCity("city " + suffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	categoryID, err := adapter.SaveServiceCategory("category " + suffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	master := entities.Master{Name: "master " + suffix, Contact: "@master", CityID: cityID, ServCatID: categoryID}
+	masterID, err := adapter.SaveApprovedMaster(&master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		adapter.DeleteMaster(masterID)
+		adapter.DeleteServCategory(categoryID)
+		adapter.DeleteCity(cityID)
+	})
+
+	pendingRevision := func() *entities.MasterRevision {
+		t.Helper()
+		revisions, err := adapter.GetMasterRevisions(masterID, entities.PENDING, 0, 10)
+		if err != nil || len(revisions) != 1 {
+			t.Fatalf("got %d pending revisions, %v", len(revisions), err)
+		}
+		return revisions[0]
+	}
+	adminUpdate := func(contact string) {
+		t.Helper()
+		update := &entities.MasterLong{ID: masterID, Master: master}
+		update.Contact = contact
+		update.Status = entities.APPROVED
+		if err := adapter.UpdateMaster(update); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the admin changes the master after the master submitted the revision
+	submitted := master
+	submitted.Name = "renamed " + suffix
+	if _, err := adapter.SaveMasterRevision(masterID, &submitted); err != nil {
+		t.Fatal(err)
+	}
+	adminUpdate("@admin")
+
+	revision := pendingRevision()
+	if _, err := adapter.ApproveMasterRevision(revision.ID); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("got %v approving a stale revision, want %v", err, ErrVersionMismatch)
+	}
+	if _, err := adapter.RejectMasterRevision(revision.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// the revision of the image changes publishes the images only
+	if err := adapter.SaveMasterImage(masterID, "key-"+suffix, &entities.Image{Name: suffix}, true); err != nil {
+		t.Fatal(err)
+	}
+	adminUpdate("@later")
+
+	revision = pendingRevision()
+	if !revision.ImagesOnly {
+		t.Fatal("the revision of an image upload changes the profile")
+	}
+	if _, err := adapter.ApproveMasterRevision(revision.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	live, err := adapter.GetMaster(masterID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if live.Contact != "@later" || live.Name != master.Name {
+		t.Errorf("got %q and %q, want the profile the admin left", live.Name, live.Contact)
+	}
+	images, err := adapter.GetMasterImages(masterID, false)
+	if err != nil || len(images) != 1 {
+		t.Errorf("got %d live images, %v", len(images), err)
+	}
+}
+
+func TestSaveClientKeepsOtherColumns(t *testing.T) {
+
+	adapter := newTestAdapter(t)
+	suffix := uuid.NewString()
+
+	cityID, err := adapter.SaveCity("city " + suffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { adapter.DeleteCity(cityID) })
+
+	telegramID := time.Now().UnixNano()
+	client := &entities.Client{TelegramID: telegramID, Language: "de", DefaultCityID: cityID}
+	if _, err := adapter.SaveClient(client, []string{ClientLanguage, ClientDefaultCity}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the language changes, the default city stays
+	saved, err := adapter.SaveClient(&entities.Client{TelegramID: telegramID, Language: "en"}, []string{ClientLanguage})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Language != "en" || saved.DefaultCityID != cityID {
+		t.Errorf("got language %q and city %q, want en and %q", saved.Language, saved.DefaultCityID, cityID)
+	}
+}
+
+func TestMasterDiff(t *testing.T) {
+
+	old := &entities.Master{Name: "Anna", Contact: "@anna", CityID: "c1", ServIDs: []string{"s1"}, Status: entities.APPROVED}
+	changed := *old
+	changed.Contact = "@anna_new"
+	changed.ServIDs = []string{"s1", "s2"}
+
+	fields := masterDiff(old, &changed)
+	if len(fields) != 2 {
+		t.Fatalf("got %d changed fields, want 2", len(fields))
+	}
+	if fields[0].Field != "contact" || fields[0].Old != "@anna" || fields[0].New != "@anna_new" {
+		t.Errorf("got %+v", fields[0])
+	}
+	if fields[1].Field != "servIDs" || fields[1].Old != "s1" || fields[1].New != "s1,s2" {
+		t.Errorf("got %+v", fields[1])
+	}
+
+	if fields := masterDiff(old, old); len(fields) != 0 {
+		t.Errorf("got %d changed fields of the same master", len(fields))
+	}
+}
+
+func TestConcurrentUploadsTakeOneCover(t *testing.T) {
+
+	adapter := newTestAdapter(t)
+	suffix := uuid.NewString()
+
+	masterID := newTestMaster(t, adapter, suffix, false)
+
+	const uploads = 5
+	errs := make(chan error, uploads)
+	for index := 0; index < uploads; index++ {
+		go func(index int) {
+			name := fmt.Sprintf("%s-%d", suffix, index)
+			errs <- adapter.SaveMasterImage(masterID, "key-"+name, &entities.Image{Name: name}, false)
+		}(index)
+	}
+	for index := 0; index < uploads; index++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	images, err := adapter.GetMasterImages(masterID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	covers, positions := 0, make(map[int]bool)
+	for _, image := range images {
+		if image.IsPrimary {
+			covers++
+		}
+		positions[image.Position] = true
+	}
+	if len(images) != uploads || covers != 1 || len(positions) != uploads {
+		t.Errorf("got %d images, %d covers and %d positions", len(images), covers, len(positions))
+	}
+}
+
+func TestConcurrentCaptionsTakeOneRevision(t *testing.T) {
+
+	adapter := newTestAdapter(t)
+	suffix := uuid.NewString()
+
+	masterID := newTestMaster(t, adapter, suffix, true)
+
+	const images = 5
+	for index := 0; index < images; index++ {
+		name := fmt.Sprintf("%s-%d", suffix, index)
+		if err := adapter.SaveMasterImage(masterID, "key-"+name, &entities.Image{Name: name}, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	errs := make(chan error, images)
+	for index := 0; index < images; index++ {
+		go func(index int) {
+			errs <- adapter.UpdateMasterImageCaption(masterID, fmt.Sprintf("%s-%d", suffix, index), "caption", true)
+		}(index)
+	}
+	for index := 0; index < images; index++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	revisions, err := adapter.GetMasterRevisions(masterID, entities.PENDING, 0, 10)
+	if err != nil || len(revisions) != 1 {
+		t.Fatalf("got %d pending revisions, %v", len(revisions), err)
+	}
+	pending, err := adapter.GetPendingImages([]string{masterID})
+	if err != nil || len(pending[masterID]) != images {
+		t.Errorf("got %d pending captions, %v", len(pending[masterID]), err)
+	}
+}
+
+func TestSaveMasterImageTwice(t *testing.T) {
+
+	adapter := newTestAdapter(t)
+	suffix := uuid.NewString()
+
+	masterID := newTestMaster(t, adapter, suffix, false)
+
+	image := &entities.Image{Name: uuid.NewString()}
+	if err := adapter.SaveMasterImage(masterID, "key", image, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := adapter.SaveMasterImage(masterID, "key", image, false); !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Errorf("got %v, want gorm.ErrDuplicatedKey", err)
+	}
+}
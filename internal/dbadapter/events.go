@@ -0,0 +1,126 @@
+package dbadapter
+
+import (
+	"bot/internal/entities"
+	"bot/internal/models"
+	"context"
+	"time"
+)
+
+// SaveEvents appends the events, the table is never updated. The events older than
+// the retention are deleted once their days are rolled up
+func (d *DBAdapter) SaveEvents(events []*entities.Event) error {
+
+	records := make([]*models.Event, 0)
+	for _, event := range events {
+		records = append(records, &models.Event{
+			CreatedAt:  event.Time,
+			Type:       event.Type,
+			TelegramID: event.TelegramID,
+			CityID:     event.CityID,
+			CategoryID: event.CategoryID,
+			ServiceID:  event.ServiceID,
+			MasterID:   event.MasterID,
+		})
+	}
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	return d.DBConn.Create(&records).Error
+}
+
+// RollupEvents recounts the events of the given day, running it again
+// for the same day overwrites the previous counts
+func (d *DBAdapter) RollupEvents(day time.Time) error {
+
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	to := from.AddDate(0, 0, 1)
+
+	query := d.DBConn.Exec(`
+		INSERT INTO event_rollups (day, type, city_id, category_id, service_id, master_id, count)
+		SELECT ?::date, type, city_id, category_id, service_id, master_id, COUNT(*)
+		FROM events
+		WHERE created_at >= ? AND created_at < ?
+		GROUP BY type, city_id, category_id, service_id, master_id
+		ON CONFLICT (day, type, city_id, category_id, service_id, master_id)
+		DO UPDATE SET count = EXCLUDED.count`, from.Format("2006-01-02"), from, to)
+	if err := query.Error; err != nil {
+		return err
+	}
+
+	d.logger.Infof("Events of %s rolled up, rows: %d", from.Format("2006-01-02"), query.RowsAffected)
+	return nil
+}
+
+// PurgeEvents deletes the events of the days before the given one, their rollups stay
+func (d *DBAdapter) PurgeEvents(day time.Time) error {
+
+	before := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	query := d.DBConn.Where("created_at < ?", before).Delete(&models.Event{})
+	if err := query.Error; err != nil {
+		return err
+	}
+
+	if query.RowsAffected > 0 {
+		d.logger.Infof("Events before %s deleted: %d", before.Format("2006-01-02"), query.RowsAffected)
+	}
+	return nil
+}
+
+// RunEventRollups rolls up today and yesterday on every tick until the context is done,
+// yesterday is repeated to pick up the events that came in after its last run.
+// Then the events older than the retention are deleted, the config keeps yesterday in
+func (d *DBAdapter) RunEventRollups(ctx context.Context) {
+
+	ticker := time.NewTicker(time.Duration(d.cfg.RollupInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		for _, day := range []time.Time{now.AddDate(0, 0, -1), now} {
+			if err := d.RollupEvents(day); err != nil {
+				d.logger.Error("dbadapter::RunEventRollups::RollupEvents", err)
+			}
+		}
+
+		if d.cfg.EventRetentionDays > 0 {
+			if err := d.PurgeEvents(now.AddDate(0, 0, -int(d.cfg.EventRetentionDays)+1)); err != nil {
+				d.logger.Error("dbadapter::RunEventRollups::PurgeEvents", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetMastersViews returns the rolled up number of master page views
+func (d *DBAdapter) GetMastersViews(masterIDs []string) (map[string]int64, error) {
+
+	result := make(map[string]int64)
+	if len(masterIDs) == 0 {
+		return result, nil
+	}
+
+	rows := make([]struct {
+		MasterID string
+		Views    int64
+	}, 0)
+	query := d.DBConn.Model(&models.EventRollup{}).
+		Select("master_id, SUM(count) AS views").
+		Where("type = ? AND master_id IN ?", entities.ViewedMaster, masterIDs).
+		Group("master_id")
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		result[row.MasterID] = row.Views
+	}
+	return result, nil
+}
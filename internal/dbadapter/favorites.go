@@ -0,0 +1,83 @@
+package dbadapter
+
+import (
+	"bot/internal/entities"
+	"bot/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetFavorites returns the approved favorite masters of the client, the latest added first
+func (d *DBAdapter) GetFavorites(telegramID int64, page, limit int) ([]*entities.MasterShort, error) {
+
+	query := withRelations(d.DBConn).
+		Joins("JOIN favorites ON favorites.master_id = masters.id").
+		Where("favorites.client_id = ? AND masters.status = ?", telegramID, entities.APPROVED).
+		Order("favorites.created_at DESC").
+		Offset(page * limit).Limit(limit)
+
+	masterRecs := make([]*models.Master, 0)
+	if err := query.Find(&masterRecs).Error; err != nil {
+		return nil, err
+	}
+
+	masters := make([]*entities.MasterShort, 0)
+	for _, rec := range masterRecs {
+		master := &entities.MasterShort{
+			ID:          rec.ID,
+			Name:        rec.Name,
+			Description: rec.Description,
+			Contact:     rec.Contact,
+			CityName:    rec.CityName,
+			ServCatName: rec.ServCatName,
+			RegDate:     rec.CreatedAt.Format("2006-01-02"),
+		}
+
+		masters = append(masters, master)
+	}
+
+	return masters, nil
+}
+
+// SaveFavorite adds the master to the client favorites, adding it twice is not an error
+func (d *DBAdapter) SaveFavorite(telegramID int64, masterID string) error {
+
+	tx := d.DBConn.Begin()
+	defer tx.Rollback()
+
+	if err := tx.Where("id = ? AND status = ?", masterID, entities.APPROVED).First(&models.Master{}).Error; err != nil {
+		return err
+	}
+
+	if err := touchClient(tx, telegramID); err != nil {
+		return err
+	}
+
+	record := &models.Favorite{ClientID: telegramID, MasterID: masterID, CreatedAt: time.Now()}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(record).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	d.logger.Infof("Master %s added to favorites of client %d", masterID, telegramID)
+	return nil
+}
+
+func (d *DBAdapter) DeleteFavorite(telegramID int64, masterID string) error {
+
+	query := d.DBConn.Where("client_id = ? AND master_id = ?", telegramID, masterID).Delete(&models.Favorite{})
+	if err := query.Error; err != nil {
+		return err
+	}
+	if query.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	d.logger.Infof("Master %s removed from favorites of client %d", masterID, telegramID)
+	return nil
+}
@@ -0,0 +1,572 @@
+package dbadapter
+
+import (
+	"bot/internal/cache"
+	"bot/internal/entities"
+	"bot/internal/mapper"
+	"bot/internal/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetMasterImages returns the live images of the master, withPending adds the changes
+// waiting for review, which only the master and the admins see
+func (d *DBAdapter) GetMasterImages(masterID string, withPending bool) ([]*entities.Image, error) {
+	if withPending {
+		return loadImages(d.DBConn.Where("master_id = ?", masterID))
+	}
+	return cache.GetOrLoad(d.cache, imagesKey+masterID, func() ([]*entities.Image, error) {
+		return loadImages(d.DBConn.Where("master_id = ? AND NOT pending", masterID))
+	})
+}
+
+func loadImages(query *gorm.DB) ([]*entities.Image, error) {
+
+	images := make([]*models.Image, 0)
+	if err := query.Order("is_primary DESC, position ASC").Find(&images).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]*entities.Image, 0)
+	for _, image := range images {
+		result = append(result, mapper.FromImageModel(image))
+	}
+	return result, nil
+}
+
+// GetPendingImages returns the image changes waiting for review, by master
+func (d *DBAdapter) GetPendingImages(masterIDs []string) (map[string][]*entities.Image, error) {
+
+	result := make(map[string][]*entities.Image)
+	if len(masterIDs) == 0 {
+		return result, nil
+	}
+
+	images := make([]*models.Image, 0)
+	query := d.DBConn.Where("master_id IN ? AND (pending OR new_caption IS NOT NULL)", masterIDs).Order("master_id, position ASC")
+	if err := query.Find(&images).Error; err != nil {
+		return nil, err
+	}
+
+	for _, image := range images {
+		result[image.MasterID] = append(result[image.MasterID], mapper.FromImageModel(image))
+	}
+	return result, nil
+}
+
+// GetMastersImages loads images of several masters with a single query, cover first
+func (d *DBAdapter) GetMastersImages(masterIDs []string) (map[string][]*entities.Image, error) {
+
+	result := make(map[string][]*entities.Image)
+	if len(masterIDs) == 0 {
+		return result, nil
+	}
+
+	images := make([]*models.Image, 0)
+	query := d.DBConn.Where("master_id IN ? AND NOT pending", masterIDs).Order("master_id, is_primary DESC, position ASC")
+	if err := query.Find(&images).Error; err != nil {
+		return nil, err
+	}
+
+	for _, image := range images {
+		result[image.MasterID] = append(result[image.MasterID], mapper.FromImageModel(image))
+	}
+	return result, nil
+}
+
+// SaveMasterImage adds the image at the end. An image the master adds to an approved listing
+// waits for the review of a revision, byMaster tells that the change comes from the master
+func (d *DBAdapter) SaveMasterImage(masterID, objectKey string, image *entities.Image, byMaster bool) error {
+
+	tx := d.DBConn.Begin()
+	defer tx.Rollback()
+
+	if err := lockMaster(tx, masterID); err != nil {
+		return err
+	}
+
+	// an upload confirmed twice is registered once
+	var existing int64
+	if err := tx.Model(&models.Image{}).Where("id = ?", image.Name).Count(&existing).Error; err != nil {
+		return err
+	}
+	if existing != 0 {
+		return fmt.Errorf("image %s is already registered: %w", image.Name, gorm.ErrDuplicatedKey)
+	}
+
+	review, err := heldForReview(tx, masterID, byMaster)
+	if err != nil {
+		return err
+	}
+
+	var count int64
+	if err := tx.Model(&models.Image{}).Where("master_id = ? AND NOT pending", masterID).Count(&count).Error; err != nil {
+		return err
+	}
+
+	var position int
+	if err := tx.Model(&models.Image{}).Where("master_id = ?", masterID).Select("COALESCE(MAX(position), 0)").Scan(&position).Error; err != nil {
+		return err
+	}
+
+	record := &models.Image{
+		ID:          image.Name,
+		MasterID:    masterID,
+		CreatedAt:   time.Now(),
+		ObjectKey:   objectKey,
+		ContentType: image.ContentType,
+		Size:        image.Size,
+		Width:       image.Width,
+		Height:      image.Height,
+		Position:    position + 1,
+		Caption:     image.Caption,
+		IsPrimary:   count == 0 && !review,
+		Pending:     review,
+	}
+	image.Pending = review
+
+	if err := tx.Create(record).Error; err != nil {
+		return err
+	}
+
+	var revision *entities.ModerationEvent
+	if review {
+		if revision, err = imageRevision(tx, masterID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	d.cache.Invalidate(imagesKey + masterID)
+	d.logger.Infof("Image %s of master %s saved", image.Name, masterID)
+	d.publish(&entities.ModerationEvent{Type: entities.ImageUploaded, MasterID: masterID, ImageName: image.Name})
+	if revision != nil {
+		d.publish(revision)
+	}
+	return nil
+}
+
+// RegisterMasterImage stores the object key of an image, adding a record
+// for images that were uploaded before the metadata was kept in the database
+func (d *DBAdapter) RegisterMasterImage(masterID string, image *entities.Image) error {
+
+	query := d.DBConn.Model(&models.Image{}).Where("master_id = ? AND id = ?", masterID, image.Name).UpdateColumn("object_key", image.ObjectKey)
+	if err := query.Error; err != nil {
+		return err
+	}
+	if query.RowsAffected != 0 {
+		d.cache.Invalidate(imagesKey + masterID)
+		return nil
+	}
+
+	return d.SaveMasterImage(masterID, image.ObjectKey, image, false)
+}
+
+// ReplaceMasterImage points the existing image record to a new object, keeping its position, caption and cover flag.
+// Returns the key of the replaced object. The master replacing a live image of an approved listing
+// adds a pending replacement instead, the key of an earlier pending replacement is returned then
+func (d *DBAdapter) ReplaceMasterImage(masterID, oldName, objectKey string, image *entities.Image, byMaster bool) (string, error) {
+
+	tx := d.DBConn.Begin()
+	defer tx.Rollback()
+
+	if err := lockMaster(tx, masterID); err != nil {
+		return "", err
+	}
+
+	review, err := heldForReview(tx, masterID, byMaster)
+	if err != nil {
+		return "", err
+	}
+
+	old := &models.Image{}
+	if err := tx.Where("master_id = ? AND id = ?", masterID, oldName).First(&old).Error; err != nil {
+		return "", err
+	}
+
+	if review && !old.Pending {
+		return d.replaceForReview(tx, old, objectKey, image)
+	}
+
+	update := map[string]interface{}{
+		"id":           image.Name,
+		"object_key":   objectKey,
+		"content_type": image.ContentType,
+		"size":         image.Size,
+		"width":        image.Width,
+		"height":       image.Height,
+	}
+
+	if err := tx.Model(&models.Image{}).Where("id = ?", old.ID).Updates(update).Error; err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return "", err
+	}
+
+	d.cache.Invalidate(imagesKey + masterID)
+	d.logger.Infof("Image %s of master %s replaced with %s", oldName, masterID, image.Name)
+	d.publish(&entities.ModerationEvent{Type: entities.ImageUploaded, MasterID: masterID, ImageName: image.Name})
+	return old.ObjectKey, nil
+}
+
+// UpdateMasterImageCaption changes the caption, the caption the master gives to a live image
+// of an approved listing waits for the review of a revision
+func (d *DBAdapter) UpdateMasterImageCaption(masterID, name, caption string, byMaster bool) error {
+
+	tx := d.DBConn.Begin()
+	defer tx.Rollback()
+
+	if err := lockMaster(tx, masterID); err != nil {
+		return err
+	}
+
+	review, err := heldForReview(tx, masterID, byMaster)
+	if err != nil {
+		return err
+	}
+
+	image := &models.Image{}
+	if err := tx.Where("master_id = ? AND id = ?", masterID, name).First(&image).Error; err != nil {
+		return err
+	}
+
+	var revision *entities.ModerationEvent
+	if review && !image.Pending {
+		if err := tx.Model(&models.Image{}).Where("id = ?", image.ID).UpdateColumn("new_caption", caption).Error; err != nil {
+			return err
+		}
+		if revision, err = imageRevision(tx, masterID); err != nil {
+			return err
+		}
+	} else if err := tx.Model(&models.Image{}).Where("id = ?", image.ID).UpdateColumn("caption", caption).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	d.cache.Invalidate(imagesKey + masterID)
+	d.logger.Infof("Caption of image %s of master %s changed", name, masterID)
+	if revision != nil {
+		d.publish(revision)
+	}
+	return nil
+}
+
+// ErrInvalidOrder is returned when the names don't list each image of the master once
+var ErrInvalidOrder = errors.New("invalid image order")
+
+// ReorderMasterImages expects the full list of the master's live image names in the new order
+func (d *DBAdapter) ReorderMasterImages(masterID string, names []string) error {
+
+	tx := d.DBConn.Begin()
+	defer tx.Rollback()
+
+	if err := lockMaster(tx, masterID); err != nil {
+		return err
+	}
+
+	images := make([]*models.Image, 0)
+	if err := tx.Where("master_id = ? AND NOT pending", masterID).Find(&images).Error; err != nil {
+		return err
+	}
+
+	if len(images) != len(names) {
+		return fmt.Errorf("%w: expected %d image names, got %d", ErrInvalidOrder, len(images), len(names))
+	}
+
+	known := make(map[string]bool)
+	for _, image := range images {
+		known[image.ID] = true
+	}
+
+	for index, name := range names {
+		if !known[name] {
+			return fmt.Errorf("%w: unknown or duplicate image %s", ErrInvalidOrder, name)
+		}
+		delete(known, name)
+
+		query := tx.Model(&models.Image{}).Where("master_id = ? AND id = ?", masterID, name)
+		if err := query.UpdateColumn("position", index+1).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	d.cache.Invalidate(imagesKey + masterID)
+	d.logger.Infof("Images of master %s reordered", masterID)
+	return nil
+}
+
+func (d *DBAdapter) SetMasterCover(masterID, name string) error {
+
+	tx := d.DBConn.Begin()
+	defer tx.Rollback()
+
+	if err := lockMaster(tx, masterID); err != nil {
+		return err
+	}
+
+	// a pending image becomes the cover once it is approved, not before
+	image := &models.Image{}
+	if err := tx.Where("master_id = ? AND id = ? AND NOT pending", masterID, name).First(&image).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(&models.Image{}).Where("master_id = ?", masterID).UpdateColumn("is_primary", false).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(&models.Image{}).Where("id = ?", image.ID).UpdateColumn("is_primary", true).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	d.cache.Invalidate(imagesKey + masterID)
+	d.logger.Infof("Image %s set as cover of master %s", name, masterID)
+	return nil
+}
+
+// DeleteMasterImage removes the image record and returns the key of its object
+func (d *DBAdapter) DeleteMasterImage(masterID, name string) (string, error) {
+
+	tx := d.DBConn.Begin()
+	defer tx.Rollback()
+
+	if err := lockMaster(tx, masterID); err != nil {
+		return "", err
+	}
+
+	image := &models.Image{}
+	if err := tx.Where("master_id = ? AND id = ?", masterID, name).First(&image).Error; err != nil {
+		return "", err
+	}
+
+	if err := tx.Delete(&models.Image{}, "id = ?", image.ID).Error; err != nil {
+		return "", err
+	}
+
+	// the first remaining image becomes the cover
+	if image.IsPrimary {
+		next := &models.Image{}
+		err := tx.Where("master_id = ? AND NOT pending", masterID).Order("position ASC").First(&next).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return "", err
+		}
+		if err == nil {
+			if err := tx.Model(&models.Image{}).Where("id = ?", next.ID).UpdateColumn("is_primary", true).Error; err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return "", err
+	}
+
+	d.cache.Invalidate(imagesKey + masterID)
+	d.logger.Infof("Image %s of master %s deleted", name, masterID)
+	return image.ObjectKey, nil
+}
+
+// lockMaster locks the master row until the end of the transaction, the concurrent changes of the images
+// of the master wait for each other, so that they don't both take the cover or the same position
+func lockMaster(tx *gorm.DB, masterID string) error {
+	if err := lockRecord(tx, masterID).Select("id").First(&models.Master{}).Error; err != nil {
+		return notFound(err, "master", masterID)
+	}
+	return nil
+}
+
+// replaceForReview adds the new object as a pending replacement of the live image,
+// an earlier pending replacement of the image is dropped and the key of its object returned
+func (d *DBAdapter) replaceForReview(tx *gorm.DB, old *models.Image, objectKey string, image *entities.Image) (string, error) {
+
+	previous := &models.Image{}
+	err := tx.Where("master_id = ? AND replaces = ?", old.MasterID, old.ID).First(&previous).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+	if err == nil {
+		if err := tx.Delete(&models.Image{}, "id = ?", previous.ID).Error; err != nil {
+			return "", err
+		}
+	}
+
+	record := &models.Image{
+		ID:          image.Name,
+		MasterID:    old.MasterID,
+		CreatedAt:   time.Now(),
+		ObjectKey:   objectKey,
+		ContentType: image.ContentType,
+		Size:        image.Size,
+		Width:       image.Width,
+		Height:      image.Height,
+		Position:    old.Position,
+		Caption:     old.Caption,
+		Pending:     true,
+		Replaces:    old.ID,
+	}
+	if err := tx.Create(record).Error; err != nil {
+		return "", err
+	}
+
+	revision, err := imageRevision(tx, old.MasterID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return "", err
+	}
+
+	d.cache.Invalidate(imagesKey + old.MasterID)
+	d.logger.Infof("Replacement %s of image %s of master %s saved for review", image.Name, old.ID, old.MasterID)
+	d.publish(&entities.ModerationEvent{Type: entities.ImageUploaded, MasterID: old.MasterID, ImageName: image.Name})
+	if revision != nil {
+		d.publish(revision)
+	}
+	return previous.ObjectKey, nil
+}
+
+// heldForReview tells if the change of the images by the master waits for a review, the listing is approved
+func heldForReview(tx *gorm.DB, masterID string, byMaster bool) (bool, error) {
+
+	if !byMaster {
+		return false, nil
+	}
+
+	status, err := masterStatus(tx, masterID)
+	if err != nil {
+		return false, err
+	}
+	return status == entities.APPROVED, nil
+}
+
+// imageRevision returns the event of the revision created for the image changes of the master,
+// nil if the master has a pending revision already, the image changes are reviewed with it
+func imageRevision(tx *gorm.DB, masterID string) (*entities.ModerationEvent, error) {
+
+	var count int64
+	if err := tx.Model(&models.MasterRevision{}).Where("master_id = ? AND status = ?", masterID, entities.PENDING).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count != 0 {
+		return nil, nil
+	}
+
+	// the revision shows the profile as it is, its approval publishes only the images
+	master := &models.Master{}
+	if err := withRelations(tx).Where("masters.id = ?", masterID).First(&master).Error; err != nil {
+		return nil, notFound(err, "master", masterID)
+	}
+
+	revision := &models.MasterRevision{
+		ID:          uuid.NewString(),
+		MasterID:    masterID,
+		CreatedAt:   time.Now(),
+		Name:        master.Name,
+		Description: master.Description,
+		Contact:     master.Contact,
+		CityID:      master.CityID,
+		ServCatID:   master.ServCatID,
+		ServIDs:     master.ServIDs,
+		Status:      entities.PENDING,
+		Version:     master.Version,
+		ImagesOnly:  true,
+	}
+	if err := tx.Create(revision).Error; err != nil {
+		return nil, err
+	}
+
+	return &entities.ModerationEvent{Type: entities.RevisionCreated, MasterID: masterID, MasterName: master.Name, RevisionID: revision.ID}, nil
+}
+
+// publishImages makes the pending image changes of the master live, it returns the keys
+// of the objects of the replaced images
+func publishImages(tx *gorm.DB, masterID string) ([]string, error) {
+
+	pending := make([]*models.Image, 0)
+	if err := tx.Where("master_id = ? AND pending AND replaces <> ''", masterID).Find(&pending).Error; err != nil {
+		return nil, err
+	}
+
+	// a replacement takes the place of the image, it stays as a new image if the image was deleted since
+	replaced := make([]string, 0)
+	for _, image := range pending {
+		old := &models.Image{}
+		err := tx.Where("master_id = ? AND id = ? AND NOT pending", masterID, image.Replaces).First(&old).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err := tx.Delete(&models.Image{}, "id = ?", old.ID).Error; err != nil {
+			return nil, err
+		}
+		update := map[string]interface{}{"position": old.Position, "is_primary": old.IsPrimary, "new_caption": old.NewCaption}
+		if err := tx.Model(&models.Image{}).Where("id = ?", image.ID).Updates(update).Error; err != nil {
+			return nil, err
+		}
+		replaced = append(replaced, old.ObjectKey)
+	}
+
+	statements := []string{
+		`UPDATE images SET pending = false, replaces = '' WHERE master_id = ? AND pending`,
+		`UPDATE images SET caption = new_caption, new_caption = NULL WHERE master_id = ? AND new_caption IS NOT NULL`,
+		// the first image becomes the cover if the master had none
+		`UPDATE images SET is_primary = true WHERE id = (
+			SELECT id FROM images WHERE master_id = ? ORDER BY position LIMIT 1)
+			AND NOT EXISTS (SELECT 1 FROM images WHERE master_id = ? AND is_primary)`,
+	}
+	for index, statement := range statements {
+		args := []interface{}{masterID}
+		if index == 2 {
+			args = append(args, masterID)
+		}
+		if err := tx.Exec(statement, args...).Error; err != nil {
+			return nil, err
+		}
+	}
+	return replaced, nil
+}
+
+// discardImages drops the pending image changes of the master, it returns the keys of their objects
+func discardImages(tx *gorm.DB, masterID string) ([]string, error) {
+
+	pending := make([]*models.Image, 0)
+	if err := tx.Where("master_id = ? AND pending", masterID).Find(&pending).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Delete(&models.Image{}, "master_id = ? AND pending", masterID).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.Image{}).Where("master_id = ?", masterID).UpdateColumn("new_caption", nil).Error; err != nil {
+		return nil, err
+	}
+
+	discarded := make([]string, 0)
+	for _, image := range pending {
+		discarded = append(discarded, image.ObjectKey)
+	}
+	return discarded, nil
+}
@@ -0,0 +1,167 @@
+package dbadapter
+
+import (
+	"bot/internal/models"
+	"fmt"
+)
+
+// migrateRelations moves the service selections from the denormalized
+// master_serv_relations table and the masters.serv_ids column into
+// master_services and drops the copied names. Rows pointing to deleted
+// records are cleaned up first, so that the foreign keys can be created.
+func (d *DBAdapter) migrateRelations() error {
+
+	migrator := d.DBConn.Migrator()
+	hasRelations := migrator.HasTable("master_serv_relations")
+	hasServIDs := migrator.HasColumn("masters", "serv_ids")
+	if !hasRelations && !hasServIDs {
+		return nil
+	}
+
+	tx := d.DBConn.Begin()
+	defer tx.Rollback()
+
+	cleanup := []string{
+		`DELETE FROM services WHERE NOT EXISTS (SELECT 1 FROM service_categories WHERE service_categories.id = services.cat_id)`,
+		`UPDATE masters SET city_id = NULL WHERE NOT EXISTS (SELECT 1 FROM cities WHERE cities.id = masters.city_id)`,
+		`UPDATE masters SET serv_cat_id = NULL WHERE NOT EXISTS (SELECT 1 FROM service_categories WHERE service_categories.id = masters.serv_cat_id)`,
+	}
+	for _, statement := range cleanup {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+
+	if !tx.Migrator().HasTable(&models.MasterService{}) {
+		if err := tx.Migrator().CreateTable(&models.MasterService{}); err != nil {
+			return err
+		}
+	}
+
+	// the selection of the master is the source, the relations only cover approved masters
+	if hasServIDs {
+		err := tx.Exec(`
+			INSERT INTO master_services (master_id, service_id, position)
+			SELECT masters.id, selected.service_id, MIN(selected.position)
+			FROM masters
+			CROSS JOIN unnest(masters.serv_ids) WITH ORDINALITY AS selected(service_id, position)
+			JOIN services ON services.id = selected.service_id
+			GROUP BY masters.id, selected.service_id
+			ON CONFLICT DO NOTHING`).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	if hasRelations {
+		err := tx.Exec(`
+			INSERT INTO master_services (master_id, service_id, position)
+			SELECT relations.master_id, relations.serv_id, MIN(relations.id)
+			FROM master_serv_relations AS relations
+			JOIN masters ON masters.id = relations.master_id
+			JOIN services ON services.id = relations.serv_id
+			WHERE NOT EXISTS (SELECT 1 FROM master_services WHERE master_services.master_id = relations.master_id)
+			GROUP BY relations.master_id, relations.serv_id
+			ON CONFLICT DO NOTHING`).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	drop := []string{
+		`DROP TABLE IF EXISTS master_serv_relations`,
+		`ALTER TABLE masters DROP COLUMN IF EXISTS serv_ids`,
+		`ALTER TABLE masters DROP COLUMN IF EXISTS city_name`,
+		`ALTER TABLE masters DROP COLUMN IF EXISTS serv_cat_name`,
+		`ALTER TABLE services DROP COLUMN IF EXISTS cat_name`,
+	}
+	for _, statement := range drop {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	d.logger.Info("Master service relations migrated to master_services")
+	return nil
+}
+
+// migrateNames renames the duplicated city, category and service names
+// before their unique indexes are created. The names are compared ignoring case,
+// the first record keeps the name, the others get their id appended.
+// The earlier indexes, which told the case apart, are dropped.
+func (d *DBAdapter) migrateNames() error {
+
+	migrator := d.DBConn.Migrator()
+	tables := []struct {
+		model     interface{}
+		table     string
+		index     string
+		oldIndex  string
+		partition string
+	}{
+		{&models.City{}, "cities", "idx_cities_lower_name", "idx_cities_name", "lower(name)"},
+		{&models.ServiceCategory{}, "service_categories", "idx_service_categories_lower_name", "idx_service_categories_name", "lower(name)"},
+		{&models.Service{}, "services", "idx_services_cat_id_lower_name", "idx_services_cat_id_name", "cat_id, lower(name)"},
+	}
+
+	for _, table := range tables {
+		if !migrator.HasTable(table.model) || migrator.HasIndex(table.model, table.index) {
+			continue
+		}
+
+		query := d.DBConn.Exec(fmt.Sprintf(`
+			UPDATE %[1]s SET name = %[1]s.name || ' (' || %[1]s.id || ')'
+			FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY %[2]s ORDER BY id) AS number FROM %[1]s) AS duplicates
+			WHERE duplicates.id = %[1]s.id AND duplicates.number > 1`, table.table, table.partition))
+		if err := query.Error; err != nil {
+			return err
+		}
+		if query.RowsAffected > 0 {
+			d.logger.Infof("Renamed %d duplicated names in %s", query.RowsAffected, table.table)
+		}
+
+		if migrator.HasIndex(table.model, table.oldIndex) {
+			if err := migrator.DropIndex(table.model, table.oldIndex); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// migrateOrphans deletes the images, revisions and favorites of the masters deleted before
+// the foreign keys were added, and clears the default cities that are gone
+func (d *DBAdapter) migrateOrphans() error {
+
+	migrator := d.DBConn.Migrator()
+	tables := []struct {
+		model      interface{}
+		table      string
+		constraint string
+		statement  string
+	}{
+		{&models.Image{}, "images", "Master", `DELETE FROM images WHERE NOT EXISTS (SELECT 1 FROM masters WHERE masters.id = images.master_id)`},
+		{&models.MasterRevision{}, "master_revisions", "Master", `DELETE FROM master_revisions WHERE NOT EXISTS (SELECT 1 FROM masters WHERE masters.id = master_revisions.master_id)`},
+		{&models.Favorite{}, "favorites", "Master", `DELETE FROM favorites WHERE NOT EXISTS (SELECT 1 FROM masters WHERE masters.id = favorites.master_id)`},
+		{&models.Client{}, "clients", "DefaultCity", `UPDATE clients SET default_city_id = NULL WHERE NOT EXISTS (SELECT 1 FROM cities WHERE cities.id = clients.default_city_id)`},
+	}
+
+	for _, table := range tables {
+		if !migrator.HasTable(table.model) || migrator.HasConstraint(table.model, table.constraint) {
+			continue
+		}
+
+		query := d.DBConn.Exec(table.statement)
+		if err := query.Error; err != nil {
+			return err
+		}
+		if query.RowsAffected > 0 {
+			d.logger.Infof("Cleaned up %d orphaned rows in %s", query.RowsAffected, table.table)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package dbadapter
+
+import (
+	"bot/internal/entities"
+	"bot/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// SubscribeUpdates returns the moderation events after lastID that are still buffered and the channel of
+// the next ones, see broker.Broker.Subscribe
+func (d *DBAdapter) SubscribeUpdates(lastID int64) ([]*entities.ModerationEvent, <-chan *entities.ModerationEvent, func()) {
+	return d.broker.Subscribe(lastID)
+}
+
+// publish is called after the commit, the subscribers never see a change that was rolled back
+func (d *DBAdapter) publish(event *entities.ModerationEvent) {
+	d.broker.Publish(event)
+}
+
+// publishMasterUpdate reports the update of the master and the change of its status if there was one
+func (d *DBAdapter) publishMasterUpdate(master *entities.MasterLong, previousStatus uint) {
+
+	d.publish(&entities.ModerationEvent{
+		Type:       entities.MasterUpdated,
+		MasterID:   master.ID,
+		MasterName: master.Name,
+		Status:     master.Status,
+	})
+
+	if master.Status != previousStatus {
+		d.publish(&entities.ModerationEvent{
+			Type:       entities.MasterStatusChanged,
+			MasterID:   master.ID,
+			MasterName: master.Name,
+			Status:     master.Status,
+		})
+	}
+}
+
+func masterStatus(tx *gorm.DB, id string) (uint, error) {
+
+	record := &models.Master{}
+	if err := tx.Select("status").Where("id = ?", id).First(record).Error; err != nil {
+		return 0, notFound(err, "master", id)
+	}
+	return record.Status, nil
+}
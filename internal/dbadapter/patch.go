@@ -0,0 +1,136 @@
+package dbadapter
+
+import (
+	"bot/internal/entities"
+	"bot/internal/mapper"
+	"bot/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// The Patch methods lock the stored record, let apply change it and save the result
+// in the same transaction, so concurrent patches are applied one after another
+
+func (d *DBAdapter) PatchCity(id string, apply func(city *entities.City) error) error {
+
+	tx := d.DBConn.Begin()
+	defer tx.Rollback()
+
+	record := &models.City{}
+	if err := lockRecord(tx, id).First(record).Error; err != nil {
+		return notFound(err, "city", id)
+	}
+
+	city := mapper.FromCityModel(record)
+	if err := apply(city); err != nil {
+		return err
+	}
+	city.ID = id
+
+	if err := updateCity(tx, city); err != nil {
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	d.cache.Invalidate(citiesKey)
+	d.logger.Infof("City patched successfully: %s", city.Name)
+	return nil
+}
+
+func (d *DBAdapter) PatchServCategory(id string, apply func(category *entities.ServiceCategory) error) error {
+
+	tx := d.DBConn.Begin()
+	defer tx.Rollback()
+
+	record := &models.ServiceCategory{}
+	if err := lockRecord(tx, id).First(record).Error; err != nil {
+		return notFound(err, "category", id)
+	}
+
+	category := mapper.FromServCatModel(record)
+	if err := apply(category); err != nil {
+		return err
+	}
+	category.ID = id
+
+	if err := updateServCategory(tx, category); err != nil {
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	d.cache.Invalidate(categoriesKey, servicesKey)
+	d.logger.Infof("Service category patched successfully: %s", category.Name)
+	return nil
+}
+
+func (d *DBAdapter) PatchService(id string, apply func(service *entities.Service) error) error {
+
+	tx := d.DBConn.Begin()
+	defer tx.Rollback()
+
+	record := &models.Service{}
+	if err := lockRecord(tx, id).First(record).Error; err != nil {
+		return notFound(err, "service", id)
+	}
+
+	service := mapper.FromServiceModel(record)
+	if err := apply(service); err != nil {
+		return err
+	}
+	service.ID = id
+
+	if err := updateService(tx, service); err != nil {
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	d.cache.Invalidate(citiesKey, categoriesKey, servicesKey)
+	d.logger.Infof("Service patched successfully: %s", service.Name)
+	return nil
+}
+
+func (d *DBAdapter) PatchMaster(id string, apply func(master *entities.MasterLong) error) error {
+
+	tx := d.DBConn.Begin()
+	defer tx.Rollback()
+
+	// the joined city and category rows are not locked, they may be null
+	record := &models.Master{}
+	query := withRelations(tx).Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "masters"}})
+	if err := query.Where("masters.id = ?", id).First(record).Error; err != nil {
+		return notFound(err, "master", id)
+	}
+
+	master := mapper.FromMasterModel(record)
+	if err := apply(master); err != nil {
+		return err
+	}
+	master.ID = id
+
+	if err := updateMaster(tx, master); err != nil {
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	d.cache.Invalidate(citiesKey, categoriesKey, servicesKey)
+	d.logger.Infof("Master patched successfully: %s", master.Name)
+	d.publishMasterUpdate(master, record.Status)
+	return nil
+}
+
+func lockRecord(tx *gorm.DB, id string) *gorm.DB {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id)
+}
@@ -0,0 +1,222 @@
+package dbadapter
+
+import (
+	"bot/internal/entities"
+	"bot/internal/mapper"
+	"bot/internal/models"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetMasterByTelegramID returns the latest master registered by the Telegram user
+func (d *DBAdapter) GetMasterByTelegramID(telegramID int64) (*entities.MasterLong, error) {
+
+	masterRec := &models.Master{}
+	if err := d.DBConn.Where("telegram_id = ?", telegramID).Order("created_at DESC").First(&masterRec).Error; err != nil {
+		return nil, err
+	}
+
+	return d.GetMaster(masterRec.ID)
+}
+
+// SaveMasterRevision stores the changes of an approved master for review,
+// a newer submission replaces the pending one
+func (d *DBAdapter) SaveMasterRevision(masterID string, master *entities.Master) (string, error) {
+
+	tx := d.DBConn.Begin()
+	defer tx.Rollback()
+
+	if err := checkVersion(tx, &models.Master{}, "master", masterID, master.Version); err != nil {
+		return "", err
+	}
+
+	if err := checkMaster(tx, master); err != nil {
+		return "", err
+	}
+
+	// without a version the revision is based on the current master
+	version := master.Version
+	if version == 0 {
+		record := &models.Master{}
+		if err := tx.Select("version").Where("id = ?", masterID).First(record).Error; err != nil {
+			return "", err
+		}
+		version = record.Version
+	}
+
+	query := tx.Where("master_id = ? AND status = ?", masterID, entities.PENDING)
+	if err := query.Delete(&models.MasterRevision{}).Error; err != nil {
+		return "", err
+	}
+
+	id := uuid.NewString()
+	revision := &models.MasterRevision{
+		ID:          id,
+		MasterID:    masterID,
+		CreatedAt:   time.Now(),
+		Name:        master.Name,
+		Description: master.Description,
+		Contact:     master.Contact,
+		CityID:      master.CityID,
+		ServCatID:   master.ServCatID,
+		ServIDs:     master.ServIDs,
+		Status:      entities.PENDING,
+		Version:     version,
+	}
+
+	if err := tx.Create(revision).Error; err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return "", err
+	}
+
+	d.logger.Infof("Revision %s of master %s saved", id, masterID)
+	d.publish(&entities.ModerationEvent{Type: entities.RevisionCreated, MasterID: masterID, MasterName: master.Name, RevisionID: id})
+	return id, nil
+}
+
+func (d *DBAdapter) GetMasterRevisions(masterID string, status uint, page, limit int) ([]*entities.MasterRevision, error) {
+
+	query := d.DBConn.Offset(page * limit).Limit(limit).Order("created_at")
+	if len(masterID) != 0 {
+		query = query.Where("master_id = ?", masterID)
+	}
+	if status != 0 {
+		query = query.Where("status = ?", status)
+	}
+
+	revisions := make([]*models.MasterRevision, 0)
+	if err := query.Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]*entities.MasterRevision, 0)
+	for _, revision := range revisions {
+		result = append(result, mapper.FromMasterRevisionModel(revision))
+	}
+	return result, nil
+}
+
+// ApproveMasterRevision applies the revision and the pending image changes to the live listing,
+// it returns the keys of the objects of the replaced images. The profile changes fail with
+// ErrVersionMismatch once the master changed after the revision was submitted
+func (d *DBAdapter) ApproveMasterRevision(id string) ([]string, error) {
+
+	tx := d.DBConn.Begin()
+	defer tx.Rollback()
+
+	revision, err := pendingRevision(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// the image changes of the master wait until the revision is reviewed
+	if err := lockMaster(tx, revision.MasterID); err != nil {
+		return nil, err
+	}
+
+	master := &entities.MasterLong{
+		ID: revision.MasterID,
+		Master: entities.Master{
+			Name:        revision.Name,
+			Description: revision.Description,
+			Contact:     revision.Contact,
+			CityID:      revision.CityID,
+			ServCatID:   revision.ServCatID,
+			ServIDs:     revision.ServIDs,
+			Status:      entities.APPROVED,
+			Version:     revision.Version,
+		},
+	}
+
+	previousStatus, err := masterStatus(tx, master.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !revision.ImagesOnly {
+		if err := updateMaster(tx, master); err != nil {
+			return nil, err
+		}
+	}
+
+	replaced, err := publishImages(tx, master.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := reviewRevision(tx, id, entities.APPROVED); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	d.cache.Invalidate(citiesKey, categoriesKey, servicesKey, imagesKey+master.ID)
+	d.logger.Infof("Revision %s of master %s approved", id, revision.MasterID)
+	d.publish(&entities.ModerationEvent{Type: entities.RevisionReviewed, MasterID: revision.MasterID, RevisionID: id, Status: entities.APPROVED})
+	if !revision.ImagesOnly {
+		d.publishMasterUpdate(master, previousStatus)
+	}
+	return replaced, nil
+}
+
+// RejectMasterRevision drops the revision and the pending image changes,
+// it returns the keys of the objects of the dropped images
+func (d *DBAdapter) RejectMasterRevision(id string) ([]string, error) {
+
+	tx := d.DBConn.Begin()
+	defer tx.Rollback()
+
+	revision, err := pendingRevision(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// the image changes of the master wait until the revision is reviewed
+	if err := lockMaster(tx, revision.MasterID); err != nil {
+		return nil, err
+	}
+
+	discarded, err := discardImages(tx, revision.MasterID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := reviewRevision(tx, id, entities.DECLINED); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	d.cache.Invalidate(imagesKey + revision.MasterID)
+	d.logger.Infof("Revision %s of master %s rejected", id, revision.MasterID)
+	d.publish(&entities.ModerationEvent{Type: entities.RevisionReviewed, MasterID: revision.MasterID, RevisionID: id, Status: entities.DECLINED})
+	return discarded, nil
+}
+
+func pendingRevision(tx *gorm.DB, id string) (*models.MasterRevision, error) {
+
+	revision := &models.MasterRevision{}
+	if err := tx.Where("id = ?", id).First(&revision).Error; err != nil {
+		return nil, err
+	}
+
+	if revision.Status != entities.PENDING {
+		return nil, fmt.Errorf("revision %s was already reviewed", id)
+	}
+	return revision, nil
+}
+
+func reviewRevision(tx *gorm.DB, id string, status uint) error {
+	update := map[string]interface{}{"status": status, "reviewed_at": time.Now()}
+	return tx.Model(&models.MasterRevision{}).Where("id = ?", id).Updates(update).Error
+}
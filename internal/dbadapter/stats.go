@@ -0,0 +1,96 @@
+package dbadapter
+
+import (
+	"bot/internal/entities"
+	"bot/internal/models"
+	"database/sql"
+	"time"
+)
+
+// GetStats computes the dashboard numbers in SQL. Cities and categories count
+// all masters, services count the approved ones. The masters of a deleted city
+// or category are counted under the empty ID.
+// Registrations are reported per day for the last days, including today.
+func (d *DBAdapter) GetStats(days int) (*entities.Stats, error) {
+
+	stats := &entities.Stats{
+		ByStatus:      make([]*entities.StatusCount, 0),
+		ByCity:        make([]*entities.NamedCount, 0),
+		ByCategory:    make([]*entities.NamedCount, 0),
+		ByService:     make([]*entities.NamedCount, 0),
+		Registrations: make([]*entities.DailyCount, 0),
+	}
+
+	query := d.DBConn.Model(&models.Master{}).Select("status, COUNT(*) AS count").Group("status").Order("status")
+	if err := query.Scan(&stats.ByStatus).Error; err != nil {
+		return nil, err
+	}
+
+	query = d.DBConn.Model(&models.Master{}).
+		Select("masters.city_id AS id, MAX(cities.name) AS name, COUNT(*) AS count").
+		Joins("LEFT JOIN cities ON cities.id = masters.city_id").
+		Group("masters.city_id").Order("count DESC, name")
+	if err := query.Scan(&stats.ByCity).Error; err != nil {
+		return nil, err
+	}
+
+	query = d.DBConn.Model(&models.Master{}).
+		Select("masters.serv_cat_id AS id, MAX(service_categories.name) AS name, COUNT(*) AS count").
+		Joins("LEFT JOIN service_categories ON service_categories.id = masters.serv_cat_id").
+		Group("masters.serv_cat_id").Order("count DESC, name")
+	if err := query.Scan(&stats.ByCategory).Error; err != nil {
+		return nil, err
+	}
+
+	query = d.DBConn.Model(&models.MasterService{}).
+		Select("services.id, MAX(services.name) AS name, COUNT(*) AS count").
+		Joins("JOIN services ON services.id = master_services.service_id").
+		Joins("JOIN masters ON masters.id = master_services.master_id").
+		Where("masters.status = ?", entities.APPROVED).
+		Group("services.id").Order("count DESC, name")
+	if err := query.Scan(&stats.ByService).Error; err != nil {
+		return nil, err
+	}
+
+	// the series keeps the days without registrations in the result
+	from := time.Now().AddDate(0, 0, 1-days).Format("2006-01-02")
+	registrations := d.DBConn.Raw(`
+		SELECT to_char(series.day, 'YYYY-MM-DD') AS day, COUNT(masters.id) AS count
+		FROM generate_series(?::date, CURRENT_DATE, '1 day') AS series(day)
+		LEFT JOIN masters ON masters.created_at::date = series.day
+		GROUP BY series.day
+		ORDER BY series.day`, from)
+	if err := registrations.Scan(&stats.Registrations).Error; err != nil {
+		return nil, err
+	}
+
+	// the registrations are approved on save for now, the moderation backlog is in the revisions
+	var oldestMaster, oldestRevision sql.NullTime
+	row := d.DBConn.Model(&models.Master{}).Select("COUNT(*), MIN(created_at)").Where("status = ?", entities.PENDING).Row()
+	if err := row.Scan(&stats.Backlog.PendingMasters, &oldestMaster); err != nil {
+		return nil, err
+	}
+	row = d.DBConn.Model(&models.MasterRevision{}).Select("COUNT(*), MIN(created_at)").Where("status = ?", entities.PENDING).Row()
+	if err := row.Scan(&stats.Backlog.PendingRevisions, &oldestRevision); err != nil {
+		return nil, err
+	}
+	for _, oldest := range []sql.NullTime{oldestMaster, oldestRevision} {
+		if oldest.Valid && (stats.Backlog.OldestPendingAt == nil || oldest.Time.Before(*stats.Backlog.OldestPendingAt)) {
+			stats.Backlog.OldestPendingAt = &oldest.Time
+		}
+	}
+
+	// an auto-approved master tells nothing about the moderation, the time is taken from the revisions an admin approved
+	var median sql.NullFloat64
+	row = d.DBConn.Model(&models.MasterRevision{}).
+		Select("percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM reviewed_at - created_at))").
+		Where("status = ? AND reviewed_at IS NOT NULL", entities.APPROVED).Row()
+	if err := row.Scan(&median); err != nil {
+		return nil, err
+	}
+	if median.Valid {
+		stats.MedianApprovalSeconds = &median.Float64
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,40 @@
+// Package dbtest points the tests to the database of TEST_POSTGRES_HOST,
+// the tests that need one are skipped without it
+package dbtest
+
+import (
+	"bot/internal/config"
+	"os"
+	"strconv"
+	"testing"
+)
+
+// Config returns the config of the test database, the test is skipped without one
+func Config(t testing.TB) *config.Config {
+	t.Helper()
+
+	host := os.Getenv("TEST_POSTGRES_HOST")
+	if len(host) == 0 {
+		t.Skip("TEST_POSTGRES_HOST is not set")
+	}
+
+	getenv := func(key, fallback string) string {
+		if value := os.Getenv(key); len(value) != 0 {
+			return value
+		}
+		return fallback
+	}
+	port, err := strconv.ParseInt(getenv("TEST_POSTGRES_PORT", "5432"), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &config.Config{
+		PsqlHost:         host,
+		PsqlPort:         port,
+		PsqlUser:         getenv("TEST_POSTGRES_USER", "postgres"),
+		PsqlPass:         getenv("TEST_POSTGRES_PASSWORD", ""),
+		PsqlDb:           getenv("TEST_POSTGRES_DB", "postgres"),
+		StreamBufferSize: 10,
+	}
+}
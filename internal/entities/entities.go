@@ -1,5 +1,7 @@
 package entities
 
+import "time"
+
 const (
 	PENDING = iota + 1
 	APPROVED
@@ -7,25 +9,40 @@ const (
 )
 
 type City struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID      string `json:"id" validate:"required"`
+	Name    string `json:"name" validate:"required,max=100"`
+	Version int64  `json:"version"`
 }
 
 type ServiceCategory struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID      string `json:"id" validate:"required"`
+	Name    string `json:"name" validate:"required,max=100"`
+	Version int64  `json:"version"`
 }
 
 type Service struct {
 	ID      string `json:"id"`
-	Name    string `json:"name" validate:"required"`
+	Name    string `json:"name" validate:"required,max=100"`
 	CatID   string `json:"catID" validate:"required"`
 	CatName string `json:"catName"`
+	Version int64  `json:"version"`
 }
 
 type Image struct {
-	Name string `json:"name" validate:"required"`
-	URL  string `json:"url" validate:"required"`
+	Name        string `json:"name" validate:"required"`
+	URL         string `json:"url" validate:"required"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Position    int    `json:"position"`
+	Caption     string `json:"caption"`
+	IsPrimary   bool   `json:"isPrimary"`
+	ObjectKey   string `json:"-"`
+	// the changes made by the master of an approved listing, shown until the revision is reviewed
+	Pending    bool    `json:"pending,omitempty"`
+	Replaces   string  `json:"replaces,omitempty"`
+	NewCaption *string `json:"newCaption,omitempty"`
 }
 
 type Master struct {
@@ -34,8 +51,18 @@ type Master struct {
 	Contact     string   `json:"contact" validate:"required"`
 	CityID      string   `json:"cityID" validate:"required"`
 	ServCatID   string   `json:"servCatID" validate:"required"`
-	ServIDs     []string `json:"servIDs" validate:"required"`
+	ServIDs     []string `json:"servIDs" validate:"required,min=1,dive,required"`
 	Status      uint     `json:"status" validate:"required"`
+	TelegramID  int64    `json:"telegramID,omitempty"`
+	Version     int64    `json:"version,omitempty"`
+}
+
+type Client struct {
+	TelegramID    int64     `json:"telegramID"`
+	Language      string    `json:"language"`
+	DefaultCityID string    `json:"defaultCityID"`
+	CreatedAt     time.Time `json:"createdAt"`
+	LastSeenAt    time.Time `json:"lastSeenAt"`
 }
 
 type MasterLong struct {
@@ -43,6 +70,17 @@ type MasterLong struct {
 	ID string `json:"id" validate:"required"`
 }
 
+type MasterRevision struct {
+	Master
+	ID         string     `json:"id"`
+	MasterID   string     `json:"masterID"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ReviewedAt *time.Time `json:"reviewedAt,omitempty"`
+	// the image changes published with a pending revision
+	Images     []*Image `json:"images,omitempty"`
+	ImagesOnly bool     `json:"imagesOnly,omitempty"`
+}
+
 type MasterShort struct {
 	ID          string   `json:"id"`
 	Name        string   `json:"name"`
@@ -52,4 +90,139 @@ type MasterShort struct {
 	ServCatName string   `json:"servCatName"`
 	RegDate     string   `json:"regDate"`
 	Images      []string `json:"images"`
+	TelegramID  int64    `json:"telegramID,omitempty"`
+	Views       int64    `json:"views"`
+	Status      uint     `json:"status,omitempty"`
+	CityID      string   `json:"cityID,omitempty"`
+	ServCatID   string   `json:"servCatID,omitempty"`
+	ServIDs     []string `json:"servIDs,omitempty"`
+	ServNames   []string `json:"servNames,omitempty"`
+}
+
+const (
+	SortByDate     = "date"
+	SortByDateDesc = "-date"
+	SortByName     = "name"
+	SortByNameDesc = "-name"
+)
+
+// MastersFilter narrows the control panel list, the zero values match everything
+type MastersFilter struct {
+	Status    uint
+	CityID    string
+	ServCatID string
+	From      time.Time
+	To        time.Time
+	Search    string
+	Sort      string
+}
+
+const (
+	CityKind     = "city"
+	CategoryKind = "category"
+	ServiceKind  = "service"
+	MasterKind   = "master"
+)
+
+// Catalog is the unit of bulk import and export. On import the references
+// between the records may be given by ID or by name.
+type Catalog struct {
+	Cities     []*City            `json:"cities"`
+	Categories []*ServiceCategory `json:"categories"`
+	Services   []*Service         `json:"services"`
+	Masters    []*MasterLong      `json:"masters"`
+}
+
+type CatalogChange struct {
+	Kind   string `json:"kind"`
+	Action string `json:"action"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	// the fields an update changes
+	Fields []*FieldChange `json:"fields,omitempty"`
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+type ImportResult struct {
+	DryRun    bool             `json:"dryRun"`
+	Created   int              `json:"created"`
+	Updated   int              `json:"updated"`
+	Unchanged int              `json:"unchanged"`
+	Changes   []*CatalogChange `json:"changes"`
+}
+
+type StatusCount struct {
+	Status uint  `json:"status"`
+	Count  int64 `json:"count"`
+}
+
+type NamedCount struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+type DailyCount struct {
+	Day   string `json:"day"`
+	Count int64  `json:"count"`
+}
+
+// Backlog is what waits for the moderation, OldestPendingAt covers the masters and the revisions
+type Backlog struct {
+	PendingMasters   int64      `json:"pendingMasters"`
+	PendingRevisions int64      `json:"pendingRevisions"`
+	OldestPendingAt  *time.Time `json:"oldestPendingAt"`
+}
+
+type Stats struct {
+	ByStatus      []*StatusCount `json:"byStatus"`
+	ByCity        []*NamedCount  `json:"byCity"`
+	ByCategory    []*NamedCount  `json:"byCategory"`
+	ByService     []*NamedCount  `json:"byService"`
+	Registrations []*DailyCount  `json:"registrations"`
+	Backlog       Backlog        `json:"backlog"`
+	// the median time from a revision to its approval, nil until a revision is approved
+	MedianApprovalSeconds *float64 `json:"medianApprovalSeconds"`
+}
+
+const (
+	ViewedCategory = "viewed_category"
+	ViewedMaster   = "viewed_master"
+	ClickedContact = "clicked_contact"
+)
+
+type Event struct {
+	Type       string    `json:"type" validate:"required,oneof=viewed_category viewed_master clicked_contact"`
+	TelegramID int64     `json:"telegramID"`
+	CityID     string    `json:"cityID"`
+	CategoryID string    `json:"categoryID" validate:"required_if=Type viewed_category"`
+	ServiceID  string    `json:"serviceID"`
+	MasterID   string    `json:"masterID" validate:"required_unless=Type viewed_category"`
+	Time       time.Time `json:"time"`
+}
+
+const (
+	MasterCreated       = "master.created"
+	MasterUpdated       = "master.updated"
+	MasterStatusChanged = "master.status_changed"
+	ImageUploaded       = "image.uploaded"
+	RevisionCreated     = "revision.created"
+	RevisionReviewed    = "revision.reviewed"
+)
+
+// ModerationEvent is a change the control panel is notified about, the IDs grow with time
+type ModerationEvent struct {
+	ID         int64     `json:"id"`
+	Type       string    `json:"type"`
+	MasterID   string    `json:"masterID"`
+	MasterName string    `json:"masterName,omitempty"`
+	Status     uint      `json:"status,omitempty"`
+	ImageName  string    `json:"imageName,omitempty"`
+	RevisionID string    `json:"revisionID,omitempty"`
+	Time       time.Time `json:"time"`
 }
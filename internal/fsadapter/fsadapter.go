@@ -0,0 +1,287 @@
+package fsadapter
+
+import (
+	"bot/internal/config"
+	"bot/internal/entities"
+	"bot/internal/logger"
+	"bot/internal/storage"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FSAdapter keeps the images in a local directory and serves them itself,
+// meant for development and test setups without an object store
+type FSAdapter struct {
+	logger logger.Logger
+	cfg    *config.Config
+	root   string
+	secret []byte
+}
+
+func NewFSAdapter(logger logger.Logger, cfg *config.Config) (*FSAdapter, error) {
+
+	root, err := filepath.Abs(cfg.StorageRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+
+	// a generated secret wouldn't survive a restart, the signed links would break
+	secret := []byte(cfg.StorageSecret)
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("storage.secret is required to sign the image links")
+	}
+
+	logger.Infof("Images are stored in %s", root)
+	return &FSAdapter{logger: logger, cfg: cfg, root: root, secret: secret}, nil
+}
+
+func (f *FSAdapter) filePath(objectKey string) (string, error) {
+	cleaned := path.Clean("/" + objectKey)
+	if cleaned == "/" || strings.Contains(objectKey, "..") {
+		return "", fmt.Errorf("invalid object key: %s", objectKey)
+	}
+	return filepath.Join(f.root, filepath.FromSlash(cleaned)), nil
+}
+
+func (f *FSAdapter) sign(values ...string) string {
+	mac := hmac.New(sha256.New, f.secret)
+	mac.Write([]byte(strings.Join(values, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (f *FSAdapter) GetImageURL(objectKey string) (string, error) {
+
+	if !f.cfg.PrivateImages {
+		return fmt.Sprintf("%s/%s", f.cfg.ImagePrefix, objectKey), nil
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(time.Duration(f.cfg.DownloadURLExpiry)*time.Second).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", f.sign(http.MethodGet, objectKey, expires))
+	return fmt.Sprintf("%s/%s?%s", f.cfg.ImagePrefix, objectKey, query.Encode()), nil
+}
+
+func (f *FSAdapter) PutMasterImage(objectKey string, file io.Reader, size int64, contentType string) error {
+
+	filePath, err := f.filePath(objectKey)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, io.LimitReader(file, size+1))
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if written != size {
+		return fmt.Errorf("expected %d bytes, got %d", size, written)
+	}
+
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return err
+	}
+
+	f.logger.Infof("Object saved: %s", objectKey)
+	return nil
+}
+
+func (f *FSAdapter) DeleteMasterImage(objectKey string) error {
+
+	filePath, err := f.filePath(objectKey)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	f.logger.Infof("Object deleted: %s", objectKey)
+	return nil
+}
+
+func (f *FSAdapter) DeleteMasterImages(masterID string) error {
+
+	dirPath, err := f.filePath(storage.MasterImageKey(masterID, ""))
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(dirPath); err != nil {
+		return err
+	}
+
+	f.logger.Infof("Images of master %s deleted", masterID)
+	return nil
+}
+
+// PresignUpload returns a signed PUT URL handled by ServeHTTP
+func (f *FSAdapter) PresignUpload(objectKey, contentType string, size int64) (string, http.Header, error) {
+
+	headers := http.Header{}
+	headers.Set("Content-Type", contentType)
+	headers.Set("Content-Length", strconv.FormatInt(size, 10))
+
+	expires := strconv.FormatInt(time.Now().Add(time.Duration(f.cfg.UploadURLExpiry)*time.Second).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", f.sign(http.MethodPut, objectKey, expires, contentType, strconv.FormatInt(size, 10)))
+	return fmt.Sprintf("%s/%s?%s", f.cfg.ImagePrefix, objectKey, query.Encode()), headers, nil
+}
+
+func (f *FSAdapter) StatMasterImage(objectKey string) (*entities.Image, error) {
+
+	filePath, err := f.filePath(objectKey)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	contentType, err := detectContentType(file)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &entities.Image{
+		ObjectKey:   objectKey,
+		ContentType: contentType,
+		Size:        info.Size(),
+	}
+
+	if config, _, err := image.DecodeConfig(file); err == nil {
+		meta.Width = config.Width
+		meta.Height = config.Height
+	}
+
+	return meta, nil
+}
+
+func detectContentType(file *os.File) (string, error) {
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return http.DetectContentType(sniff[:n]), nil
+}
+
+// ServeHTTP serves the stored files and accepts uploads to presigned URLs,
+// the request path is the object key
+func (f *FSAdapter) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+
+	objectKey := strings.TrimPrefix(req.URL.Path, "/")
+	query := req.URL.Query()
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		if f.cfg.PrivateImages && !f.verify(query, http.MethodGet, objectKey) {
+			http.Error(rw, "invalid or expired signature", http.StatusForbidden)
+			return
+		}
+		f.serveFile(rw, req, objectKey)
+	case http.MethodPut:
+		contentType := req.Header.Get("Content-Type")
+		size := strconv.FormatInt(req.ContentLength, 10)
+		if !f.verify(query, http.MethodPut, objectKey, contentType, size) {
+			http.Error(rw, "invalid or expired signature", http.StatusForbidden)
+			return
+		}
+		if err := f.PutMasterImage(objectKey, req.Body, req.ContentLength, contentType); err != nil {
+			f.logger.Error("fsadapter::ServeHTTP::PutMasterImage", err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+	default:
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *FSAdapter) verify(query url.Values, method, objectKey string, values ...string) bool {
+
+	expires := query.Get("expires")
+	deadline, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > deadline {
+		return false
+	}
+
+	expected := f.sign(append([]string{method, objectKey, expires}, values...)...)
+	return hmac.Equal([]byte(expected), []byte(query.Get("signature")))
+}
+
+func (f *FSAdapter) serveFile(rw http.ResponseWriter, req *http.Request, objectKey string) {
+
+	filePath, err := f.filePath(objectKey)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		http.NotFound(rw, req)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(rw, req)
+		return
+	}
+
+	// the names have no extension, so ServeContent would guess from the content anyway
+	contentType, err := detectContentType(file)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", contentType)
+	http.ServeContent(rw, req, info.Name(), info.ModTime(), file)
+}
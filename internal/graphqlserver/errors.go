@@ -0,0 +1,53 @@
+package graphqlserver
+
+import (
+	"bot/internal/dbadapter"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+const (
+	codeNotFound        = "NOT_FOUND"
+	codeConflict        = "CONFLICT"
+	codeVersionMismatch = "VERSION_MISMATCH"
+	codeBadRequest      = "BAD_REQUEST"
+	codeInternal        = "INTERNAL"
+)
+
+// resolverError puts the code into the extensions of the error, the clients check it
+// the way they check the status codes of the HTTP API
+type resolverError struct {
+	err  error
+	code string
+}
+
+func (e *resolverError) Error() string {
+	return e.err.Error()
+}
+
+func (e *resolverError) Unwrap() error {
+	return e.err
+}
+
+func (e *resolverError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+// toError maps the errors of DBAdapter the way the HTTP handlers map them to the status codes
+func toError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return &resolverError{err: err, code: codeNotFound}
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return &resolverError{err: err, code: codeConflict}
+	case errors.Is(err, dbadapter.ErrVersionMismatch):
+		return &resolverError{err: err, code: codeVersionMismatch}
+	default:
+		return &resolverError{err: err, code: codeInternal}
+	}
+}
+
+func badRequest(err error) error {
+	return &resolverError{err: err, code: codeBadRequest}
+}
@@ -0,0 +1,98 @@
+// Package graphqlserver serves the GraphQL API of the control panel, the schema is in schema.graphql.
+// The relationship fields are batched per request, see loader
+package graphqlserver
+
+import (
+	"bot/internal/config"
+	"bot/internal/dbadapter"
+	"bot/internal/logger"
+	"bot/internal/storage"
+	_ "embed"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schema string
+
+type Handler struct {
+	logger       logger.Logger
+	schema       *graphql.Schema
+	DBAdapter    *dbadapter.DBAdapter
+	ImageStorage storage.ImageStorage
+}
+
+type Request struct {
+	Query         string                 `json:"query" validate:"required"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+func NewHandler(logger logger.Logger, cfg *config.Config, DBAdapter *dbadapter.DBAdapter, ImageStorage storage.ImageStorage) (*Handler, error) {
+
+	resolver := &resolver{logger: logger, DBAdapter: DBAdapter, ImageStorage: ImageStorage}
+	parsed, err := graphql.ParseSchema(schema, resolver, graphql.MaxDepth(int(cfg.GraphQLMaxDepth)))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Handler{logger: logger, schema: parsed, DBAdapter: DBAdapter, ImageStorage: ImageStorage}, nil
+}
+
+// @Summary GraphQL
+// @Description Run a GraphQL query or mutation, the schema is internal/graphqlserver/schema.graphql. The errors carry a code in the extensions: NOT_FOUND, CONFLICT, VERSION_MISMATCH, BAD_REQUEST or INTERNAL. Queries deeper than graphql.max_depth are rejected. Used by admin panel.
+// @Tags GraphQL
+// @Param request body graphqlserver.Request true "Query, operation name and variables"
+// @Accept json
+// @Produce json
+// @Success 200 {object} object "data and errors"
+// @Failure 400 {string} string "Error message"
+// @Failure 401 {string} string "Error message"
+// @Router /graphql [post]
+func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		h.logger.Error("graphqlserver::ServeHTTP::ReadAll", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	request := &Request{}
+	if err := json.Unmarshal(body, request); err != nil {
+		h.logger.Error("graphqlserver::ServeHTTP::Unmarshal", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(request); err != nil {
+		h.logger.Error("graphqlserver::ServeHTTP::Struct", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the loaders are per request, the records can't go stale between the requests
+	ctx := withLoaders(req.Context(), newLoaders(h.DBAdapter, h.ImageStorage))
+	response := h.schema.Exec(ctx, request.Query, request.OperationName, request.Variables)
+
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		h.logger.Error("graphqlserver::ServeHTTP::Marshal", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	if _, err := rw.Write(responseJSON); err != nil {
+		h.logger.Error("graphqlserver::ServeHTTP::Write", err)
+		return
+	}
+	h.logger.Info("Response sent")
+}
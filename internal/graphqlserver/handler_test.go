@@ -0,0 +1,173 @@
+package graphqlserver
+
+import (
+	"bot/internal/config"
+	"bot/internal/dbadapter"
+	"bot/internal/dbtest"
+	"bot/internal/entities"
+	"bot/internal/fsadapter"
+	"bot/internal/logger"
+	"bot/internal/storage"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type response struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func execute(t *testing.T, handler *Handler, query string) *response {
+	t.Helper()
+
+	body, err := json.Marshal(&Request{Query: query})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+	if rw.Code != http.StatusOK {
+		t.Fatalf("got %d: %s", rw.Code, rw.Body.String())
+	}
+
+	result := &response{}
+	if err := json.Unmarshal(rw.Body.Bytes(), result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// the depth is checked with the query, before any resolver reaches the database
+func TestMaxDepth(t *testing.T) {
+
+	handler, err := NewHandler(logger.NewLogger(), &config.Config{GraphQLMaxDepth: 3}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := execute(t, handler, `{ categories { services { category { services { id } } } } }`)
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Message, "exceeds max depth 3") {
+		t.Fatalf("got %+v, want the query rejected for its depth", result.Errors)
+	}
+	if string(result.Data) != "" && string(result.Data) != "null" {
+		t.Errorf("got the data %s of a rejected query", result.Data)
+	}
+}
+
+func TestMastersBatched(t *testing.T) {
+
+	cfg := dbtest.Config(t)
+	cfg.GraphQLMaxDepth = 6
+	cfg.StorageRoot = t.TempDir()
+	cfg.StorageSecret = "secret"
+	cfg.ImagePrefix = "http://localhost/images"
+
+	log := logger.NewLogger()
+	adapter, err := dbadapter.NewDbAdapter(log, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := adapter.AutoMigrate(); err != nil {
+		t.Fatal(err)
+	}
+	imageStorage, err := fsadapter.NewFSAdapter(log, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := NewHandler(log, cfg, adapter, imageStorage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every master has its own city, category, service and image, a lookup per master would show in the counts
+	const n = 3
+	suffix := uuid.NewString()
+	for i := 0; i < n; i++ {
+		cityID, err := adapter.SaveCity(uuid.NewString())
+		if err != nil {
+			t.Fatal(err)
+		}
+		categoryID, err := adapter.SaveServiceCategory(uuid.NewString())
+		if err != nil {
+			t.Fatal(err)
+		}
+		serviceID, err := adapter.SaveService(uuid.NewString(), categoryID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		masterID, err := adapter.SaveApprovedMaster(&entities.Master{
+			Name:      "master " + suffix,
+			Contact:   "@master",
+			CityID:    cityID,
+			ServCatID: categoryID,
+			ServIDs:   []string{serviceID},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			adapter.DeleteMaster(masterID)
+			adapter.DeleteService(serviceID)
+			adapter.DeleteServCategory(categoryID)
+			adapter.DeleteCity(cityID)
+		})
+
+		name := uuid.NewString()
+		image := &entities.Image{Name: name, ContentType: "image/png", Size: 1}
+		if err := adapter.SaveMasterImage(masterID, storage.MasterImageKey(masterID, name), image, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mu sync.Mutex
+	queries := make(map[string]int)
+	err = adapter.DBConn.Callback().Query().After("gorm:query").Register("test:count", func(db *gorm.DB) {
+		mu.Lock()
+		defer mu.Unlock()
+		queries[db.Statement.Table]++
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { adapter.DBConn.Callback().Query().Remove("test:count") })
+
+	result := execute(t, handler, `{ masters(q: "`+suffix+`") { id city { name } category { name } services { name } images { url } } }`)
+	if len(result.Errors) != 0 {
+		t.Fatalf("got the errors %+v", result.Errors)
+	}
+
+	masters := struct {
+		Masters []struct {
+			City     *struct{ Name string }
+			Category *struct{ Name string }
+			Services []struct{ Name string }
+			Images   []struct{ URL string }
+		}
+	}{}
+	if err := json.Unmarshal(result.Data, &masters); err != nil {
+		t.Fatal(err)
+	}
+	if len(masters.Masters) != n {
+		t.Fatalf("got %d masters, want %d", len(masters.Masters), n)
+	}
+	for _, master := range masters.Masters {
+		if master.City == nil || master.Category == nil || len(master.Services) != 1 || len(master.Images) != 1 {
+			t.Errorf("got %+v, want the relations of the master", master)
+		}
+	}
+
+	for _, table := range []string{"cities", "service_categories", "services", "images"} {
+		if queries[table] != 1 {
+			t.Errorf("got %d queries of %s for %d masters, want 1", queries[table], table, n)
+		}
+	}
+}
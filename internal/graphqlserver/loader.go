@@ -0,0 +1,106 @@
+package graphqlserver
+
+import (
+	"bot/internal/dbadapter"
+	"bot/internal/entities"
+	"bot/internal/storage"
+	"context"
+	"sync"
+)
+
+// loader batches the lookups of one request. The list resolvers prime the keys their items
+// will need, the first load fetches all of them with one query.
+type loader[V any] struct {
+	mu      sync.Mutex
+	fetch   func(keys []string) (map[string]V, error)
+	pending map[string]bool
+	// the keys without a record keep the zero value
+	values map[string]V
+}
+
+func newLoader[V any](fetch func(keys []string) (map[string]V, error)) *loader[V] {
+	return &loader[V]{fetch: fetch, pending: make(map[string]bool), values: make(map[string]V)}
+}
+
+func (l *loader[V]) prime(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		if _, ok := l.values[key]; !ok && len(key) != 0 {
+			l.pending[key] = true
+		}
+	}
+}
+
+func (l *loader[V]) load(key string) (V, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if value, ok := l.values[key]; ok || len(key) == 0 {
+		return value, nil
+	}
+
+	l.pending[key] = true
+	keys := make([]string, 0, len(l.pending))
+	for pending := range l.pending {
+		keys = append(keys, pending)
+	}
+	l.pending = make(map[string]bool)
+
+	values, err := l.fetch(keys)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	for _, key := range keys {
+		l.values[key] = values[key]
+	}
+	return l.values[key], nil
+}
+
+type loaders struct {
+	cities           *loader[*entities.City]
+	categories       *loader[*entities.ServiceCategory]
+	services         *loader[*entities.Service]
+	categoryServices *loader[[]*entities.Service]
+	images           *loader[[]*entities.Image]
+}
+
+func newLoaders(DBAdapter *dbadapter.DBAdapter, ImageStorage storage.ImageStorage) *loaders {
+	return &loaders{
+		cities:           newLoader(DBAdapter.GetCitiesByIDs),
+		categories:       newLoader(DBAdapter.GetServCategoriesByIDs),
+		services:         newLoader(DBAdapter.GetServicesByIDs),
+		categoryServices: newLoader(DBAdapter.GetServicesByCategories),
+		images: newLoader(func(masterIDs []string) (map[string][]*entities.Image, error) {
+			images, err := DBAdapter.GetMastersImages(masterIDs)
+			if err != nil {
+				return nil, err
+			}
+
+			// the URLs are set on copies as the handlers do
+			result := make(map[string][]*entities.Image)
+			for masterID, masterImages := range images {
+				for _, image := range masterImages {
+					withURL := *image
+					if withURL.URL, err = ImageStorage.GetImageURL(image.ObjectKey); err != nil {
+						return nil, err
+					}
+					result[masterID] = append(result[masterID], &withURL)
+				}
+			}
+			return result, nil
+		}),
+	}
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
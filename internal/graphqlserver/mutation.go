@@ -0,0 +1,377 @@
+package graphqlserver
+
+import (
+	"bot/internal/entities"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/graph-gophers/graphql-go"
+)
+
+const nameRules = "required,max=100"
+
+var errVersionRequired = &resolverError{err: errors.New("the version is required"), code: codeBadRequest}
+
+type nameArgs struct {
+	Name string
+}
+
+func (r *resolver) CreateCity(ctx context.Context, args nameArgs) (*cityResolver, error) {
+
+	name := strings.TrimSpace(args.Name)
+	validator := validator.New()
+	if err := validator.Var(name, nameRules); err != nil {
+		r.logger.Error("graphqlserver::CreateCity::Var", err)
+		return nil, badRequest(err)
+	}
+
+	id, err := r.DBAdapter.SaveCity(name)
+	if err != nil {
+		r.logger.Error("graphqlserver::CreateCity::SaveCity", err)
+		return nil, toError(err)
+	}
+	return r.getCity(id)
+}
+
+type updateNameArgs struct {
+	ID      graphql.ID
+	Name    string
+	Version int32
+}
+
+func (r *resolver) UpdateCity(ctx context.Context, args updateNameArgs) (*cityResolver, error) {
+
+	city := &entities.City{
+		ID:      string(args.ID),
+		Name:    strings.TrimSpace(args.Name),
+		Version: int64(args.Version),
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(city); err != nil {
+		r.logger.Error("graphqlserver::UpdateCity::Struct", err)
+		return nil, badRequest(err)
+	}
+	if city.Version <= 0 {
+		return nil, errVersionRequired
+	}
+
+	if err := r.DBAdapter.UpdateCity(city); err != nil {
+		r.logger.Error("graphqlserver::UpdateCity::UpdateCity", err)
+		return nil, toError(err)
+	}
+	return r.getCity(city.ID)
+}
+
+func (r *resolver) DeleteCity(ctx context.Context, args idArgs) (bool, error) {
+
+	if err := r.DBAdapter.DeleteCity(string(args.ID)); err != nil {
+		r.logger.Error("graphqlserver::DeleteCity::DeleteCity", err)
+		return false, toError(err)
+	}
+	return true, nil
+}
+
+func (r *resolver) CreateCategory(ctx context.Context, args nameArgs) (*categoryResolver, error) {
+
+	name := strings.TrimSpace(args.Name)
+	validator := validator.New()
+	if err := validator.Var(name, nameRules); err != nil {
+		r.logger.Error("graphqlserver::CreateCategory::Var", err)
+		return nil, badRequest(err)
+	}
+
+	id, err := r.DBAdapter.SaveServiceCategory(name)
+	if err != nil {
+		r.logger.Error("graphqlserver::CreateCategory::SaveServiceCategory", err)
+		return nil, toError(err)
+	}
+	return r.getCategory(id)
+}
+
+func (r *resolver) UpdateCategory(ctx context.Context, args updateNameArgs) (*categoryResolver, error) {
+
+	category := &entities.ServiceCategory{
+		ID:      string(args.ID),
+		Name:    strings.TrimSpace(args.Name),
+		Version: int64(args.Version),
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(category); err != nil {
+		r.logger.Error("graphqlserver::UpdateCategory::Struct", err)
+		return nil, badRequest(err)
+	}
+	if category.Version <= 0 {
+		return nil, errVersionRequired
+	}
+
+	if err := r.DBAdapter.UpdateServCategory(category); err != nil {
+		r.logger.Error("graphqlserver::UpdateCategory::UpdateServCategory", err)
+		return nil, toError(err)
+	}
+	return r.getCategory(category.ID)
+}
+
+func (r *resolver) DeleteCategory(ctx context.Context, args idArgs) (bool, error) {
+
+	if err := r.DBAdapter.DeleteServCategory(string(args.ID)); err != nil {
+		r.logger.Error("graphqlserver::DeleteCategory::DeleteServCategory", err)
+		return false, toError(err)
+	}
+	return true, nil
+}
+
+type createServiceArgs struct {
+	Name       string
+	CategoryID graphql.ID
+}
+
+func (r *resolver) CreateService(ctx context.Context, args createServiceArgs) (*serviceResolver, error) {
+
+	service := &entities.Service{
+		Name:  strings.TrimSpace(args.Name),
+		CatID: string(args.CategoryID),
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(service); err != nil {
+		r.logger.Error("graphqlserver::CreateService::Struct", err)
+		return nil, badRequest(err)
+	}
+
+	id, err := r.DBAdapter.SaveService(service.Name, service.CatID)
+	if err != nil {
+		r.logger.Error("graphqlserver::CreateService::SaveService", err)
+		return nil, toError(err)
+	}
+	return r.getService(id)
+}
+
+type updateServiceArgs struct {
+	ID         graphql.ID
+	Name       string
+	CategoryID graphql.ID
+	Version    int32
+}
+
+func (r *resolver) UpdateService(ctx context.Context, args updateServiceArgs) (*serviceResolver, error) {
+
+	service := &entities.Service{
+		ID:      string(args.ID),
+		Name:    strings.TrimSpace(args.Name),
+		CatID:   string(args.CategoryID),
+		Version: int64(args.Version),
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(service); err != nil {
+		r.logger.Error("graphqlserver::UpdateService::Struct", err)
+		return nil, badRequest(err)
+	}
+	if err := validator.Var(service.ID, "required"); err != nil {
+		r.logger.Error("graphqlserver::UpdateService::Var", err)
+		return nil, badRequest(err)
+	}
+	if service.Version <= 0 {
+		return nil, errVersionRequired
+	}
+
+	if err := r.DBAdapter.UpdateService(service); err != nil {
+		r.logger.Error("graphqlserver::UpdateService::UpdateService", err)
+		return nil, toError(err)
+	}
+	return r.getService(service.ID)
+}
+
+func (r *resolver) DeleteService(ctx context.Context, args idArgs) (bool, error) {
+
+	if err := r.DBAdapter.DeleteService(string(args.ID)); err != nil {
+		r.logger.Error("graphqlserver::DeleteService::DeleteService", err)
+		return false, toError(err)
+	}
+	return true, nil
+}
+
+type masterInput struct {
+	Name        string
+	Description *string
+	Contact     string
+	CityID      graphql.ID
+	CategoryID  graphql.ID
+	ServiceIDs  []graphql.ID
+	Status      int32
+}
+
+type updateMasterArgs struct {
+	ID      graphql.ID
+	Master  masterInput
+	Version int32
+}
+
+func (r *resolver) UpdateMaster(ctx context.Context, args updateMasterArgs) (*masterResolver, error) {
+
+	master := &entities.MasterLong{
+		ID: string(args.ID),
+		Master: entities.Master{
+			Name:      args.Master.Name,
+			Contact:   args.Master.Contact,
+			CityID:    string(args.Master.CityID),
+			ServCatID: string(args.Master.CategoryID),
+			ServIDs:   make([]string, 0),
+			Status:    uint(args.Master.Status),
+			Version:   int64(args.Version),
+		},
+	}
+	if args.Master.Description != nil {
+		master.Description = *args.Master.Description
+	}
+	for _, servID := range args.Master.ServiceIDs {
+		master.ServIDs = append(master.ServIDs, string(servID))
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(master); err != nil {
+		r.logger.Error("graphqlserver::UpdateMaster::Struct", err)
+		return nil, badRequest(err)
+	}
+	if master.Version <= 0 {
+		return nil, errVersionRequired
+	}
+
+	if err := r.DBAdapter.UpdateMaster(master); err != nil {
+		r.logger.Error("graphqlserver::UpdateMaster::UpdateMaster", err)
+		return nil, toError(err)
+	}
+	return r.getMaster(ctx, master.ID)
+}
+
+func (r *resolver) ApproveMaster(ctx context.Context, args idArgs) (*masterResolver, error) {
+
+	if err := r.DBAdapter.ApproveMaster(string(args.ID)); err != nil {
+		r.logger.Error("graphqlserver::ApproveMaster::ApproveMaster", err)
+		return nil, toError(err)
+	}
+	return r.getMaster(ctx, string(args.ID))
+}
+
+func (r *resolver) DeleteMaster(ctx context.Context, args idArgs) (bool, error) {
+
+	if err := r.DBAdapter.DeleteMaster(string(args.ID)); err != nil {
+		r.logger.Error("graphqlserver::DeleteMaster::DeleteMaster", err)
+		return false, toError(err)
+	}
+
+	if err := r.ImageStorage.DeleteMasterImages(string(args.ID)); err != nil {
+		r.logger.Error("graphqlserver::DeleteMaster::DeleteMasterImages", err)
+	}
+	return true, nil
+}
+
+type imageArgs struct {
+	MasterID graphql.ID
+	Name     string
+}
+
+func (r *resolver) SetMasterCover(ctx context.Context, args imageArgs) (bool, error) {
+
+	if err := r.DBAdapter.SetMasterCover(string(args.MasterID), args.Name); err != nil {
+		r.logger.Error("graphqlserver::SetMasterCover::SetMasterCover", err)
+		return false, toError(err)
+	}
+	return true, nil
+}
+
+type captionArgs struct {
+	MasterID graphql.ID
+	Name     string
+	Caption  string
+}
+
+func (r *resolver) UpdateImageCaption(ctx context.Context, args captionArgs) (bool, error) {
+
+	if err := r.DBAdapter.UpdateMasterImageCaption(string(args.MasterID), args.Name, args.Caption, false); err != nil {
+		r.logger.Error("graphqlserver::UpdateImageCaption::UpdateMasterImageCaption", err)
+		return false, toError(err)
+	}
+	return true, nil
+}
+
+type reorderArgs struct {
+	MasterID graphql.ID
+	Names    []string
+}
+
+func (r *resolver) ReorderMasterImages(ctx context.Context, args reorderArgs) (bool, error) {
+
+	validator := validator.New()
+	if err := validator.Var(args.Names, "required"); err != nil {
+		r.logger.Error("graphqlserver::ReorderMasterImages::Var", err)
+		return false, badRequest(err)
+	}
+
+	if err := r.DBAdapter.ReorderMasterImages(string(args.MasterID), args.Names); err != nil {
+		r.logger.Error("graphqlserver::ReorderMasterImages::ReorderMasterImages", err)
+		return false, badRequest(err)
+	}
+	return true, nil
+}
+
+func (r *resolver) DeleteMasterImage(ctx context.Context, args imageArgs) (bool, error) {
+
+	objectKey, err := r.DBAdapter.DeleteMasterImage(string(args.MasterID), args.Name)
+	if err != nil {
+		r.logger.Error("graphqlserver::DeleteMasterImage::DeleteMasterImage", err)
+		return false, toError(err)
+	}
+
+	if err := r.ImageStorage.DeleteMasterImage(objectKey); err != nil {
+		r.logger.Error("graphqlserver::DeleteMasterImage::DeleteMasterImage", err)
+		return false, toError(err)
+	}
+	return true, nil
+}
+
+// the mutations return the saved records, so the versions are the new ones
+
+func (r *resolver) getCity(id string) (*cityResolver, error) {
+
+	city, err := r.DBAdapter.GetCity(id)
+	if err != nil {
+		r.logger.Error("graphqlserver::getCity::GetCity", err)
+		return nil, toError(err)
+	}
+	return &cityResolver{city: city}, nil
+}
+
+func (r *resolver) getCategory(id string) (*categoryResolver, error) {
+
+	category, err := r.DBAdapter.GetServCategory(id)
+	if err != nil {
+		r.logger.Error("graphqlserver::getCategory::GetServCategory", err)
+		return nil, toError(err)
+	}
+	return &categoryResolver{category: category}, nil
+}
+
+func (r *resolver) getService(id string) (*serviceResolver, error) {
+
+	service, err := r.DBAdapter.GetService(id)
+	if err != nil {
+		r.logger.Error("graphqlserver::getService::GetService", err)
+		return nil, toError(err)
+	}
+	return &serviceResolver{service: service}, nil
+}
+
+func (r *resolver) getMaster(ctx context.Context, id string) (*masterResolver, error) {
+
+	master, err := r.DBAdapter.GetMaster(id)
+	if err != nil {
+		r.logger.Error("graphqlserver::getMaster::GetMaster", err)
+		return nil, toError(err)
+	}
+	return newMasterResolvers(ctx, []*entities.MasterLong{master})[0], nil
+}
@@ -0,0 +1,223 @@
+package graphqlserver
+
+import (
+	"bot/internal/dbadapter"
+	"bot/internal/entities"
+	"bot/internal/logger"
+	"bot/internal/storage"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"gorm.io/gorm"
+)
+
+// resolver is the root of the schema, it resolves the fields of both Query and Mutation
+type resolver struct {
+	logger       logger.Logger
+	DBAdapter    *dbadapter.DBAdapter
+	ImageStorage storage.ImageStorage
+}
+
+type pageArgs struct {
+	Page  *int32
+	Limit *int32
+}
+
+// pageLimit converts the paging arguments, without a limit the whole list is returned as in the HTTP API
+func (args pageArgs) pageLimit() (int, int) {
+	if args.Limit == nil || *args.Limit <= 0 {
+		return 0, -1
+	}
+	if args.Page == nil {
+		return 0, int(*args.Limit)
+	}
+	return int(*args.Page), int(*args.Limit)
+}
+
+type idArgs struct {
+	ID graphql.ID
+}
+
+type mastersArgs struct {
+	pageArgs
+	Status     *int32
+	CityID     *graphql.ID
+	CategoryID *graphql.ID
+	From       *string
+	To         *string
+	Q          *string
+	Sort       *string
+}
+
+func (r *resolver) Masters(ctx context.Context, args mastersArgs) ([]*masterResolver, error) {
+
+	filter := &entities.MastersFilter{}
+	if args.Status != nil {
+		filter.Status = uint(*args.Status)
+	}
+	if args.CityID != nil {
+		filter.CityID = string(*args.CityID)
+	}
+	if args.CategoryID != nil {
+		filter.ServCatID = string(*args.CategoryID)
+	}
+	if args.Q != nil {
+		filter.Search = strings.TrimSpace(*args.Q)
+	}
+	if args.Sort != nil {
+		filter.Sort = *args.Sort
+	}
+
+	switch filter.Sort {
+	case "", entities.SortByDate, entities.SortByDateDesc, entities.SortByName, entities.SortByNameDesc:
+	default:
+		return nil, badRequest(fmt.Errorf("unknown sort: %s", filter.Sort))
+	}
+
+	var err error
+	if args.From != nil && len(*args.From) != 0 {
+		if filter.From, err = time.ParseInLocation("2006-01-02", *args.From, time.Local); err != nil {
+			r.logger.Error("graphqlserver::Masters::ParseInLocation", err)
+			return nil, badRequest(err)
+		}
+	}
+
+	// the end date is inclusive
+	if args.To != nil && len(*args.To) != 0 {
+		if filter.To, err = time.ParseInLocation("2006-01-02", *args.To, time.Local); err != nil {
+			r.logger.Error("graphqlserver::Masters::ParseInLocation", err)
+			return nil, badRequest(err)
+		}
+		filter.To = filter.To.AddDate(0, 0, 1)
+	}
+
+	page, limit := args.pageLimit()
+	summaries, err := r.DBAdapter.GetMastersAdmin(filter, page, limit)
+	if err != nil {
+		r.logger.Error("graphqlserver::Masters::GetMastersAdmin", err)
+		return nil, toError(err)
+	}
+
+	// the summaries don't carry the versions, the records are loaded in one query and kept in the order of the page
+	ids := make([]string, 0)
+	for _, summary := range summaries {
+		ids = append(ids, summary.ID)
+	}
+	records, err := r.DBAdapter.GetMastersByIDs(ids)
+	if err != nil {
+		r.logger.Error("graphqlserver::Masters::GetMastersByIDs", err)
+		return nil, toError(err)
+	}
+
+	masters := make([]*entities.MasterLong, 0)
+	for _, id := range ids {
+		if master, ok := records[id]; ok {
+			masters = append(masters, master)
+		}
+	}
+	return newMasterResolvers(ctx, masters), nil
+}
+
+func (r *resolver) Master(ctx context.Context, args idArgs) (*masterResolver, error) {
+
+	master, err := r.DBAdapter.GetMaster(string(args.ID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("graphqlserver::Master::GetMaster", err)
+		return nil, toError(err)
+	}
+	return newMasterResolvers(ctx, []*entities.MasterLong{master})[0], nil
+}
+
+func (r *resolver) Cities(ctx context.Context, args pageArgs) ([]*cityResolver, error) {
+
+	page, limit := args.pageLimit()
+	cities, err := r.DBAdapter.GetCities("", page, limit)
+	if err != nil {
+		r.logger.Error("graphqlserver::Cities::GetCities", err)
+		return nil, toError(err)
+	}
+
+	resolvers := make([]*cityResolver, 0)
+	for _, city := range cities {
+		resolvers = append(resolvers, &cityResolver{city: city})
+	}
+	return resolvers, nil
+}
+
+func (r *resolver) City(ctx context.Context, args idArgs) (*cityResolver, error) {
+
+	city, err := r.DBAdapter.GetCity(string(args.ID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("graphqlserver::City::GetCity", err)
+		return nil, toError(err)
+	}
+	return &cityResolver{city: city}, nil
+}
+
+func (r *resolver) Categories(ctx context.Context, args pageArgs) ([]*categoryResolver, error) {
+
+	page, limit := args.pageLimit()
+	categories, err := r.DBAdapter.GetServCategories("", page, limit)
+	if err != nil {
+		r.logger.Error("graphqlserver::Categories::GetServCategories", err)
+		return nil, toError(err)
+	}
+	return newCategoryResolvers(ctx, categories), nil
+}
+
+func (r *resolver) Category(ctx context.Context, args idArgs) (*categoryResolver, error) {
+
+	category, err := r.DBAdapter.GetServCategory(string(args.ID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("graphqlserver::Category::GetServCategory", err)
+		return nil, toError(err)
+	}
+	return &categoryResolver{category: category}, nil
+}
+
+type servicesArgs struct {
+	pageArgs
+	CategoryID *graphql.ID
+}
+
+func (r *resolver) Services(ctx context.Context, args servicesArgs) ([]*serviceResolver, error) {
+
+	categoryID := ""
+	if args.CategoryID != nil {
+		categoryID = string(*args.CategoryID)
+	}
+
+	page, limit := args.pageLimit()
+	services, err := r.DBAdapter.GetServices(categoryID, "", page, limit)
+	if err != nil {
+		r.logger.Error("graphqlserver::Services::GetServices", err)
+		return nil, toError(err)
+	}
+	return newServiceResolvers(ctx, services), nil
+}
+
+func (r *resolver) Service(ctx context.Context, args idArgs) (*serviceResolver, error) {
+
+	service, err := r.DBAdapter.GetService(string(args.ID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("graphqlserver::Service::GetService", err)
+		return nil, toError(err)
+	}
+	return &serviceResolver{service: service}, nil
+}
@@ -0,0 +1,243 @@
+package graphqlserver
+
+import (
+	"bot/internal/entities"
+	"context"
+	"strconv"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+type cityResolver struct {
+	city *entities.City
+}
+
+func (r *cityResolver) ID() graphql.ID {
+	return graphql.ID(r.city.ID)
+}
+
+func (r *cityResolver) Name() string {
+	return r.city.Name
+}
+
+func (r *cityResolver) Version() int32 {
+	return int32(r.city.Version)
+}
+
+type categoryResolver struct {
+	category *entities.ServiceCategory
+}
+
+func (r *categoryResolver) ID() graphql.ID {
+	return graphql.ID(r.category.ID)
+}
+
+func (r *categoryResolver) Name() string {
+	return r.category.Name
+}
+
+func (r *categoryResolver) Version() int32 {
+	return int32(r.category.Version)
+}
+
+func (r *categoryResolver) Services(ctx context.Context) ([]*serviceResolver, error) {
+
+	services, err := loadersFrom(ctx).categoryServices.load(r.category.ID)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return newServiceResolvers(ctx, services), nil
+}
+
+type serviceResolver struct {
+	service *entities.Service
+}
+
+func (r *serviceResolver) ID() graphql.ID {
+	return graphql.ID(r.service.ID)
+}
+
+func (r *serviceResolver) Name() string {
+	return r.service.Name
+}
+
+func (r *serviceResolver) Version() int32 {
+	return int32(r.service.Version)
+}
+
+func (r *serviceResolver) Category(ctx context.Context) (*categoryResolver, error) {
+
+	category, err := loadersFrom(ctx).categories.load(r.service.CatID)
+	if err != nil {
+		return nil, toError(err)
+	}
+	if category == nil {
+		return nil, nil
+	}
+	return &categoryResolver{category: category}, nil
+}
+
+type imageResolver struct {
+	image *entities.Image
+}
+
+func (r *imageResolver) Name() string {
+	return r.image.Name
+}
+
+func (r *imageResolver) URL() string {
+	return r.image.URL
+}
+
+func (r *imageResolver) ContentType() string {
+	return r.image.ContentType
+}
+
+func (r *imageResolver) Size() int32 {
+	return int32(r.image.Size)
+}
+
+func (r *imageResolver) Width() int32 {
+	return int32(r.image.Width)
+}
+
+func (r *imageResolver) Height() int32 {
+	return int32(r.image.Height)
+}
+
+func (r *imageResolver) Position() int32 {
+	return int32(r.image.Position)
+}
+
+func (r *imageResolver) Caption() string {
+	return r.image.Caption
+}
+
+func (r *imageResolver) IsPrimary() bool {
+	return r.image.IsPrimary
+}
+
+type masterResolver struct {
+	master *entities.MasterLong
+}
+
+func (r *masterResolver) ID() graphql.ID {
+	return graphql.ID(r.master.ID)
+}
+
+func (r *masterResolver) Name() string {
+	return r.master.Name
+}
+
+func (r *masterResolver) Description() string {
+	return r.master.Description
+}
+
+func (r *masterResolver) Contact() string {
+	return r.master.Contact
+}
+
+func (r *masterResolver) Status() int32 {
+	return int32(r.master.Status)
+}
+
+func (r *masterResolver) TelegramID() string {
+	return strconv.FormatInt(r.master.TelegramID, 10)
+}
+
+func (r *masterResolver) Version() int32 {
+	return int32(r.master.Version)
+}
+
+func (r *masterResolver) City(ctx context.Context) (*cityResolver, error) {
+
+	city, err := loadersFrom(ctx).cities.load(r.master.CityID)
+	if err != nil {
+		return nil, toError(err)
+	}
+	if city == nil {
+		return nil, nil
+	}
+	return &cityResolver{city: city}, nil
+}
+
+func (r *masterResolver) Category(ctx context.Context) (*categoryResolver, error) {
+
+	category, err := loadersFrom(ctx).categories.load(r.master.ServCatID)
+	if err != nil {
+		return nil, toError(err)
+	}
+	if category == nil {
+		return nil, nil
+	}
+	return &categoryResolver{category: category}, nil
+}
+
+func (r *masterResolver) Services(ctx context.Context) ([]*serviceResolver, error) {
+
+	loader := loadersFrom(ctx).services
+	loader.prime(r.master.ServIDs...)
+
+	services := make([]*entities.Service, 0)
+	for _, servID := range r.master.ServIDs {
+		service, err := loader.load(servID)
+		if err != nil {
+			return nil, toError(err)
+		}
+		// the deleted services are skipped
+		if service != nil {
+			services = append(services, service)
+		}
+	}
+	return newServiceResolvers(ctx, services), nil
+}
+
+func (r *masterResolver) Images(ctx context.Context) ([]*imageResolver, error) {
+
+	images, err := loadersFrom(ctx).images.load(r.master.ID)
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	resolvers := make([]*imageResolver, 0)
+	for _, image := range images {
+		resolvers = append(resolvers, &imageResolver{image: image})
+	}
+	return resolvers, nil
+}
+
+func newCategoryResolvers(ctx context.Context, categories []*entities.ServiceCategory) []*categoryResolver {
+
+	loader := loadersFrom(ctx).categoryServices
+	resolvers := make([]*categoryResolver, 0)
+	for _, category := range categories {
+		loader.prime(category.ID)
+		resolvers = append(resolvers, &categoryResolver{category: category})
+	}
+	return resolvers
+}
+
+func newServiceResolvers(ctx context.Context, services []*entities.Service) []*serviceResolver {
+
+	loader := loadersFrom(ctx).categories
+	resolvers := make([]*serviceResolver, 0)
+	for _, service := range services {
+		loader.prime(service.CatID)
+		resolvers = append(resolvers, &serviceResolver{service: service})
+	}
+	return resolvers
+}
+
+func newMasterResolvers(ctx context.Context, masters []*entities.MasterLong) []*masterResolver {
+
+	l := loadersFrom(ctx)
+	resolvers := make([]*masterResolver, 0)
+	for _, master := range masters {
+		l.cities.prime(master.CityID)
+		l.categories.prime(master.ServCatID)
+		l.services.prime(master.ServIDs...)
+		l.images.prime(master.ID)
+		resolvers = append(resolvers, &masterResolver{master: master})
+	}
+	return resolvers
+}
@@ -0,0 +1,124 @@
+package grpcserver
+
+import (
+	"bot/internal/grpcserver/pb"
+	middleware "bot/internal/server/middleware"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// Self is the master ID selecting the master of the Telegram user
+const Self = "self"
+
+type rule int
+
+const (
+	public rule = iota
+	// the bot acting on its own behalf
+	botKey
+	admin
+)
+
+// rules mirror the middleware of the HTTP routes, the methods not listed are public
+var rules = map[string]rule{
+	pb.Cities_CreateCity_FullMethodName:         admin,
+	pb.Cities_UpdateCity_FullMethodName:         admin,
+	pb.Cities_DeleteCity_FullMethodName:         admin,
+	pb.Categories_CreateCategory_FullMethodName: admin,
+	pb.Categories_UpdateCategory_FullMethodName: admin,
+	pb.Categories_DeleteCategory_FullMethodName: admin,
+	pb.Services_CreateService_FullMethodName:    admin,
+	pb.Services_UpdateService_FullMethodName:    admin,
+	pb.Services_DeleteService_FullMethodName:    admin,
+	pb.Masters_ListMastersAdmin_FullMethodName:  admin,
+	pb.Masters_UpdateMaster_FullMethodName:      admin,
+	pb.Masters_DeleteMaster_FullMethodName:      admin,
+	pb.Masters_ApproveMaster_FullMethodName:     admin,
+	pb.Images_SetCover_FullMethodName:           admin,
+	pb.Images_ReorderImages_FullMethodName:      admin,
+	pb.Images_UpdateCaption_FullMethodName:      admin,
+	pb.Images_DeleteImage_FullMethodName:        admin,
+}
+
+// authorize checks the API key for the method. The image methods are also open to the bot
+// for the master of the Telegram user, the user is checked when the master is resolved.
+func (s *Server) authorize(ctx context.Context, method string, req interface{}) error {
+
+	required := rules[method]
+	if master, ok := req.(interface{ GetMasterId() string }); ok && master.GetMasterId() == Self {
+		required = botKey
+	}
+
+	key := getMetadata(ctx, middleware.APIKeyHeader)
+	switch {
+	case required == admin && !s.auth.IsAdminKey(key):
+		return status.Error(codes.Unauthenticated, "admin API key required")
+	case required == botKey && !s.auth.IsBotKey(key):
+		return status.Error(codes.Unauthenticated, "bot API key required")
+	}
+	return nil
+}
+
+// masterID resolves Self to the ID of the master linked to the Telegram user,
+// which only the bot may send
+func (s *Server) masterID(ctx context.Context, id string) (string, error) {
+
+	if id != Self {
+		return id, nil
+	}
+
+	if !s.auth.IsUserKey(getMetadata(ctx, middleware.APIKeyHeader)) {
+		return "", status.Error(codes.Unauthenticated, "bot API key required")
+	}
+
+	telegramID, err := getTelegramID(ctx)
+	if err != nil || telegramID == 0 {
+		s.logger.Error("grpcserver::masterID::getTelegramID", err)
+		return "", status.Error(codes.Unauthenticated, "valid "+middleware.TelegramUserIDHeader+" metadata required")
+	}
+
+	master, err := s.DBAdapter.GetMasterByTelegramID(telegramID)
+	if err != nil {
+		s.logger.Error("grpcserver::masterID::GetMasterByTelegramID", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", status.Error(codes.NotFound, "no master is linked to this Telegram user")
+		}
+		return "", status.Error(codes.Internal, err.Error())
+	}
+	return master.ID, nil
+}
+
+// byMaster tells that the image change doesn't come from the control panel, on an approved
+// listing it waits for a review like the uploads of the HTTP routes without the admin key
+func (s *Server) byMaster(ctx context.Context, id string) bool {
+	return id == Self || !s.auth.IsAdminKey(getMetadata(ctx, middleware.APIKeyHeader))
+}
+
+// getMetadata reads the value sent under the name of the HTTP header
+func getMetadata(ctx context.Context, header string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(strings.ToLower(header))
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// getTelegramID reads the ID of the Telegram user the bot acts for, 0 if it's absent
+func getTelegramID(ctx context.Context) (int64, error) {
+	value := getMetadata(ctx, middleware.TelegramUserIDHeader)
+	if len(value) == 0 {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
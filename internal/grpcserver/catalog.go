@@ -0,0 +1,256 @@
+package grpcserver
+
+import (
+	"bot/internal/entities"
+	"bot/internal/grpcserver/pb"
+	"context"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const nameRules = "required,max=100"
+
+func (s *Server) ListCities(req *pb.ListCitiesRequest, stream pb.Cities_ListCitiesServer) error {
+
+	page, limit := pageLimit(req.GetPage())
+	cities, err := s.DBAdapter.GetCities("", page, limit)
+	if err != nil {
+		s.logger.Error("grpcserver::ListCities::GetCities", err)
+		return toStatus(err)
+	}
+
+	for _, city := range cities {
+		if err := stream.Send(cityToPB(city)); err != nil {
+			s.logger.Error("grpcserver::ListCities::Send", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) GetCity(ctx context.Context, req *pb.GetCityRequest) (*pb.City, error) {
+
+	city, err := s.DBAdapter.GetCity(req.GetId())
+	if err != nil {
+		s.logger.Error("grpcserver::GetCity::GetCity", err)
+		return nil, toStatus(err)
+	}
+	return cityToPB(city), nil
+}
+
+func (s *Server) CreateCity(ctx context.Context, req *pb.CreateCityRequest) (*pb.City, error) {
+
+	name := strings.TrimSpace(req.GetName())
+	validator := validator.New()
+	if err := validator.Var(name, nameRules); err != nil {
+		s.logger.Error("grpcserver::CreateCity::Var", err)
+		return nil, invalidArgument(err)
+	}
+
+	id, err := s.DBAdapter.SaveCity(name)
+	if err != nil {
+		s.logger.Error("grpcserver::CreateCity::SaveCity", err)
+		return nil, toStatus(err)
+	}
+	return s.GetCity(ctx, &pb.GetCityRequest{Id: id})
+}
+
+func (s *Server) UpdateCity(ctx context.Context, req *pb.UpdateCityRequest) (*pb.City, error) {
+
+	city := &entities.City{
+		ID:      req.GetCity().GetId(),
+		Name:    strings.TrimSpace(req.GetCity().GetName()),
+		Version: req.GetCity().GetVersion(),
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(city); err != nil {
+		s.logger.Error("grpcserver::UpdateCity::Struct", err)
+		return nil, invalidArgument(err)
+	}
+	if city.Version <= 0 {
+		return nil, errVersionRequired
+	}
+
+	if err := s.DBAdapter.UpdateCity(city); err != nil {
+		s.logger.Error("grpcserver::UpdateCity::UpdateCity", err)
+		return nil, toStatus(err)
+	}
+	return s.GetCity(ctx, &pb.GetCityRequest{Id: city.ID})
+}
+
+func (s *Server) DeleteCity(ctx context.Context, req *pb.DeleteCityRequest) (*emptypb.Empty, error) {
+
+	if err := s.DBAdapter.DeleteCity(req.GetId()); err != nil {
+		s.logger.Error("grpcserver::DeleteCity::DeleteCity", err)
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) ListCategories(req *pb.ListCategoriesRequest, stream pb.Categories_ListCategoriesServer) error {
+
+	page, limit := pageLimit(req.GetPage())
+	categories, err := s.DBAdapter.GetServCategories("", page, limit)
+	if err != nil {
+		s.logger.Error("grpcserver::ListCategories::GetServCategories", err)
+		return toStatus(err)
+	}
+
+	for _, category := range categories {
+		if err := stream.Send(categoryToPB(category)); err != nil {
+			s.logger.Error("grpcserver::ListCategories::Send", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) GetCategory(ctx context.Context, req *pb.GetCategoryRequest) (*pb.Category, error) {
+
+	category, err := s.DBAdapter.GetServCategory(req.GetId())
+	if err != nil {
+		s.logger.Error("grpcserver::GetCategory::GetServCategory", err)
+		return nil, toStatus(err)
+	}
+	return categoryToPB(category), nil
+}
+
+func (s *Server) CreateCategory(ctx context.Context, req *pb.CreateCategoryRequest) (*pb.Category, error) {
+
+	name := strings.TrimSpace(req.GetName())
+	validator := validator.New()
+	if err := validator.Var(name, nameRules); err != nil {
+		s.logger.Error("grpcserver::CreateCategory::Var", err)
+		return nil, invalidArgument(err)
+	}
+
+	id, err := s.DBAdapter.SaveServiceCategory(name)
+	if err != nil {
+		s.logger.Error("grpcserver::CreateCategory::SaveServiceCategory", err)
+		return nil, toStatus(err)
+	}
+	return s.GetCategory(ctx, &pb.GetCategoryRequest{Id: id})
+}
+
+func (s *Server) UpdateCategory(ctx context.Context, req *pb.UpdateCategoryRequest) (*pb.Category, error) {
+
+	category := &entities.ServiceCategory{
+		ID:      req.GetCategory().GetId(),
+		Name:    strings.TrimSpace(req.GetCategory().GetName()),
+		Version: req.GetCategory().GetVersion(),
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(category); err != nil {
+		s.logger.Error("grpcserver::UpdateCategory::Struct", err)
+		return nil, invalidArgument(err)
+	}
+	if category.Version <= 0 {
+		return nil, errVersionRequired
+	}
+
+	if err := s.DBAdapter.UpdateServCategory(category); err != nil {
+		s.logger.Error("grpcserver::UpdateCategory::UpdateServCategory", err)
+		return nil, toStatus(err)
+	}
+	return s.GetCategory(ctx, &pb.GetCategoryRequest{Id: category.ID})
+}
+
+func (s *Server) DeleteCategory(ctx context.Context, req *pb.DeleteCategoryRequest) (*emptypb.Empty, error) {
+
+	if err := s.DBAdapter.DeleteServCategory(req.GetId()); err != nil {
+		s.logger.Error("grpcserver::DeleteCategory::DeleteServCategory", err)
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) ListServices(req *pb.ListServicesRequest, stream pb.Services_ListServicesServer) error {
+
+	page, limit := pageLimit(req.GetPage())
+	services, err := s.DBAdapter.GetServices(req.GetCategoryId(), "", page, limit)
+	if err != nil {
+		s.logger.Error("grpcserver::ListServices::GetServices", err)
+		return toStatus(err)
+	}
+
+	for _, service := range services {
+		if err := stream.Send(serviceToPB(service)); err != nil {
+			s.logger.Error("grpcserver::ListServices::Send", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) GetService(ctx context.Context, req *pb.GetServiceRequest) (*pb.Service, error) {
+
+	service, err := s.DBAdapter.GetService(req.GetId())
+	if err != nil {
+		s.logger.Error("grpcserver::GetService::GetService", err)
+		return nil, toStatus(err)
+	}
+	return serviceToPB(service), nil
+}
+
+func (s *Server) CreateService(ctx context.Context, req *pb.CreateServiceRequest) (*pb.Service, error) {
+
+	service := &entities.Service{
+		Name:  strings.TrimSpace(req.GetName()),
+		CatID: req.GetCategoryId(),
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(service); err != nil {
+		s.logger.Error("grpcserver::CreateService::Struct", err)
+		return nil, invalidArgument(err)
+	}
+
+	id, err := s.DBAdapter.SaveService(service.Name, service.CatID)
+	if err != nil {
+		s.logger.Error("grpcserver::CreateService::SaveService", err)
+		return nil, toStatus(err)
+	}
+	return s.GetService(ctx, &pb.GetServiceRequest{Id: id})
+}
+
+func (s *Server) UpdateService(ctx context.Context, req *pb.UpdateServiceRequest) (*pb.Service, error) {
+
+	service := &entities.Service{
+		ID:      req.GetService().GetId(),
+		Name:    strings.TrimSpace(req.GetService().GetName()),
+		CatID:   req.GetService().GetCategoryId(),
+		Version: req.GetService().GetVersion(),
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(service); err != nil {
+		s.logger.Error("grpcserver::UpdateService::Struct", err)
+		return nil, invalidArgument(err)
+	}
+	if err := validator.Var(service.ID, "required"); err != nil {
+		s.logger.Error("grpcserver::UpdateService::Var", err)
+		return nil, invalidArgument(err)
+	}
+	if service.Version <= 0 {
+		return nil, errVersionRequired
+	}
+
+	if err := s.DBAdapter.UpdateService(service); err != nil {
+		s.logger.Error("grpcserver::UpdateService::UpdateService", err)
+		return nil, toStatus(err)
+	}
+	return s.GetService(ctx, &pb.GetServiceRequest{Id: service.ID})
+}
+
+func (s *Server) DeleteService(ctx context.Context, req *pb.DeleteServiceRequest) (*emptypb.Empty, error) {
+
+	if err := s.DBAdapter.DeleteService(req.GetId()); err != nil {
+		s.logger.Error("grpcserver::DeleteService::DeleteService", err)
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
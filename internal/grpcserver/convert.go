@@ -0,0 +1,124 @@
+package grpcserver
+
+import (
+	"bot/internal/dbadapter"
+	"bot/internal/entities"
+	"bot/internal/grpcserver/pb"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+var errVersionRequired = status.Error(codes.FailedPrecondition, "the version field is required")
+
+// toStatus maps the errors of DBAdapter the way the HTTP handlers map them to the status codes
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, dbadapter.ErrVersionMismatch):
+		return status.Error(codes.Aborted, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+func invalidArgument(err error) error {
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
+// pageLimit converts the page of the request, the zero page returns the whole list as in the HTTP API
+func pageLimit(page *pb.Page) (int, int) {
+	if page.GetLimit() <= 0 {
+		return 0, -1
+	}
+	return int(page.GetPage()), int(page.GetLimit())
+}
+
+func cityToPB(city *entities.City) *pb.City {
+	return &pb.City{Id: city.ID, Name: city.Name, Version: city.Version}
+}
+
+func categoryToPB(category *entities.ServiceCategory) *pb.Category {
+	return &pb.Category{Id: category.ID, Name: category.Name, Version: category.Version}
+}
+
+func serviceToPB(service *entities.Service) *pb.Service {
+	return &pb.Service{
+		Id:           service.ID,
+		Name:         service.Name,
+		CategoryId:   service.CatID,
+		CategoryName: service.CatName,
+		Version:      service.Version,
+	}
+}
+
+func imageToPB(image *entities.Image) *pb.Image {
+	return &pb.Image{
+		Name:        image.Name,
+		Url:         image.URL,
+		ContentType: image.ContentType,
+		Size:        image.Size,
+		Width:       int32(image.Width),
+		Height:      int32(image.Height),
+		Position:    int32(image.Position),
+		Caption:     image.Caption,
+		IsPrimary:   image.IsPrimary,
+	}
+}
+
+func masterToPB(master *entities.MasterLong) *pb.Master {
+	return &pb.Master{
+		Id:          master.ID,
+		Name:        master.Name,
+		Description: master.Description,
+		Contact:     master.Contact,
+		CityId:      master.CityID,
+		CategoryId:  master.ServCatID,
+		ServiceIds:  master.ServIDs,
+		Status:      uint32(master.Status),
+		TelegramId:  master.TelegramID,
+		Version:     master.Version,
+	}
+}
+
+func masterFromPB(master *pb.Master) *entities.MasterLong {
+	return &entities.MasterLong{
+		ID: master.GetId(),
+		Master: entities.Master{
+			Name:        master.GetName(),
+			Description: master.GetDescription(),
+			Contact:     master.GetContact(),
+			CityID:      master.GetCityId(),
+			ServCatID:   master.GetCategoryId(),
+			ServIDs:     master.GetServiceIds(),
+			Status:      uint(master.GetStatus()),
+			TelegramID:  master.GetTelegramId(),
+			Version:     master.GetVersion(),
+		},
+	}
+}
+
+func masterSummaryToPB(master *entities.MasterShort) *pb.MasterSummary {
+	return &pb.MasterSummary{
+		Id:           master.ID,
+		Name:         master.Name,
+		Description:  master.Description,
+		Contact:      master.Contact,
+		CityName:     master.CityName,
+		CategoryName: master.ServCatName,
+		RegDate:      master.RegDate,
+		Images:       master.Images,
+		TelegramId:   master.TelegramID,
+		Views:        master.Views,
+		Status:       uint32(master.Status),
+		CityId:       master.CityID,
+		CategoryId:   master.ServCatID,
+		ServiceIds:   master.ServIDs,
+		ServiceNames: master.ServNames,
+	}
+}
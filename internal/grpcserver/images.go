@@ -0,0 +1,191 @@
+package grpcserver
+
+import (
+	"bot/internal/dbadapter"
+	"bot/internal/entities"
+	"bot/internal/grpcserver/pb"
+	"bot/internal/server/handler"
+	"bot/internal/storage"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"gorm.io/gorm"
+)
+
+func (s *Server) ListImages(req *pb.ListImagesRequest, stream pb.Images_ListImagesServer) error {
+
+	masterID, err := s.masterID(stream.Context(), req.GetMasterId())
+	if err != nil {
+		return err
+	}
+
+	// the master sees its changes waiting for review too
+	images, err := s.DBAdapter.GetMasterImages(masterID, req.GetMasterId() == Self)
+	if err != nil {
+		s.logger.Error("grpcserver::ListImages::GetMasterImages", err)
+		return toStatus(err)
+	}
+
+	for _, image := range images {
+		withURL, err := s.withURL(image)
+		if err != nil {
+			s.logger.Error("grpcserver::ListImages::withURL", err)
+			return toStatus(err)
+		}
+		if err := stream.Send(imageToPB(withURL)); err != nil {
+			s.logger.Error("grpcserver::ListImages::Send", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) UploadImage(ctx context.Context, req *pb.UploadImageRequest) (*pb.Image, error) {
+
+	masterID, err := s.masterID(ctx, req.GetMasterId())
+	if err != nil {
+		return nil, err
+	}
+
+	content := req.GetContent()
+	newImage := &entities.Image{
+		Name:        uuid.NewString(),
+		ContentType: req.GetContentType(),
+		Size:        int64(len(content)),
+		Caption:     req.GetCaption(),
+	}
+	if len(newImage.ContentType) == 0 || newImage.ContentType == "application/octet-stream" {
+		newImage.ContentType = http.DetectContentType(content)
+	}
+
+	if err := handler.CheckImage(s.cfg, newImage.ContentType, newImage.Size); err != nil {
+		s.logger.Error("grpcserver::UploadImage::CheckImage", err)
+		return nil, invalidArgument(err)
+	}
+
+	if config, _, err := image.DecodeConfig(bytes.NewReader(content)); err == nil {
+		newImage.Width = config.Width
+		newImage.Height = config.Height
+	}
+
+	objectKey := storage.MasterImageKey(masterID, newImage.Name)
+	if err := s.ImageStorage.PutMasterImage(objectKey, bytes.NewReader(content), newImage.Size, newImage.ContentType); err != nil {
+		s.logger.Error("grpcserver::UploadImage::PutMasterImage", err)
+		return nil, toStatus(err)
+	}
+
+	if err := s.DBAdapter.SaveMasterImage(masterID, objectKey, newImage, s.byMaster(ctx, req.GetMasterId())); err != nil {
+		s.logger.Error("grpcserver::UploadImage::SaveMasterImage", err)
+		if err := s.ImageStorage.DeleteMasterImage(objectKey); err != nil {
+			s.logger.Error("grpcserver::UploadImage::DeleteMasterImage", err)
+		}
+		return nil, toStatus(err)
+	}
+
+	// the position and the cover flag are set by the database
+	images, err := s.DBAdapter.GetMasterImages(masterID, newImage.Pending)
+	if err != nil {
+		s.logger.Error("grpcserver::UploadImage::GetMasterImages", err)
+		return nil, toStatus(err)
+	}
+	for _, saved := range images {
+		if saved.Name == newImage.Name {
+			withURL, err := s.withURL(saved)
+			if err != nil {
+				s.logger.Error("grpcserver::UploadImage::withURL", err)
+				return nil, toStatus(err)
+			}
+			return imageToPB(withURL), nil
+		}
+	}
+	return nil, toStatus(fmt.Errorf("image %s: %w", newImage.Name, gorm.ErrRecordNotFound))
+}
+
+func (s *Server) SetCover(ctx context.Context, req *pb.SetCoverRequest) (*emptypb.Empty, error) {
+
+	masterID, err := s.masterID(ctx, req.GetMasterId())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.DBAdapter.SetMasterCover(masterID, req.GetName()); err != nil {
+		s.logger.Error("grpcserver::SetCover::SetMasterCover", err)
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) ReorderImages(ctx context.Context, req *pb.ReorderImagesRequest) (*emptypb.Empty, error) {
+
+	masterID, err := s.masterID(ctx, req.GetMasterId())
+	if err != nil {
+		return nil, err
+	}
+
+	validator := validator.New()
+	if err := validator.Var(req.GetNames(), "required"); err != nil {
+		s.logger.Error("grpcserver::ReorderImages::Var", err)
+		return nil, invalidArgument(err)
+	}
+
+	// the names must list all the images of the master
+	if err := s.DBAdapter.ReorderMasterImages(masterID, req.GetNames()); err != nil {
+		s.logger.Error("grpcserver::ReorderImages::ReorderMasterImages", err)
+		if errors.Is(err, dbadapter.ErrInvalidOrder) {
+			return nil, invalidArgument(err)
+		}
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) UpdateCaption(ctx context.Context, req *pb.UpdateCaptionRequest) (*emptypb.Empty, error) {
+
+	masterID, err := s.masterID(ctx, req.GetMasterId())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.DBAdapter.UpdateMasterImageCaption(masterID, req.GetName(), req.GetCaption(), s.byMaster(ctx, req.GetMasterId())); err != nil {
+		s.logger.Error("grpcserver::UpdateCaption::UpdateMasterImageCaption", err)
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) DeleteImage(ctx context.Context, req *pb.DeleteImageRequest) (*emptypb.Empty, error) {
+
+	masterID, err := s.masterID(ctx, req.GetMasterId())
+	if err != nil {
+		return nil, err
+	}
+
+	objectKey, err := s.DBAdapter.DeleteMasterImage(masterID, req.GetName())
+	if err != nil {
+		s.logger.Error("grpcserver::DeleteImage::DeleteMasterImage", err)
+		return nil, toStatus(err)
+	}
+
+	if err := s.ImageStorage.DeleteMasterImage(objectKey); err != nil {
+		s.logger.Error("grpcserver::DeleteImage::DeleteMasterImage", err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// withURL returns a copy of the image with the link, the images are shared with the cache
+func (s *Server) withURL(image *entities.Image) (*entities.Image, error) {
+	withURL := *image
+	var err error
+	withURL.URL, err = s.ImageStorage.GetImageURL(image.ObjectKey)
+	return &withURL, err
+}
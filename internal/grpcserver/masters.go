@@ -0,0 +1,183 @@
+package grpcserver
+
+import (
+	"bot/internal/dbadapter"
+	"bot/internal/entities"
+	"bot/internal/grpcserver/pb"
+	"bot/internal/server/handler"
+	middleware "bot/internal/server/middleware"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func (s *Server) ListMasters(req *pb.ListMastersRequest, stream pb.Masters_ListMastersServer) error {
+
+	if order := req.GetOrder(); len(order) != 0 && order != dbadapter.PopularOrder {
+		return status.Error(codes.InvalidArgument, "unknown order: "+order)
+	}
+
+	page, limit := pageLimit(req.GetPage())
+	masters, err := s.DBAdapter.GetMastersBot(req.GetCityId(), "", req.GetServiceId(), req.GetOrder(), page, limit)
+	if err != nil {
+		s.logger.Error("grpcserver::ListMasters::GetMastersBot", err)
+		return toStatus(err)
+	}
+
+	return s.sendMasters(stream, masters)
+}
+
+func (s *Server) ListMastersAdmin(req *pb.ListMastersAdminRequest, stream pb.Masters_ListMastersAdminServer) error {
+
+	filter := &entities.MastersFilter{
+		Status:    uint(req.GetStatus()),
+		CityID:    req.GetCityId(),
+		ServCatID: req.GetCategoryId(),
+		Search:    strings.TrimSpace(req.GetQuery()),
+		Sort:      req.GetSort(),
+	}
+
+	switch filter.Sort {
+	case "", entities.SortByDate, entities.SortByDateDesc, entities.SortByName, entities.SortByNameDesc:
+	default:
+		return status.Error(codes.InvalidArgument, "unknown sort: "+filter.Sort)
+	}
+
+	var err error
+	if from := req.GetFrom(); len(from) != 0 {
+		if filter.From, err = time.ParseInLocation("2006-01-02", from, time.Local); err != nil {
+			s.logger.Error("grpcserver::ListMastersAdmin::ParseInLocation", err)
+			return invalidArgument(err)
+		}
+	}
+
+	// the end date is inclusive
+	if to := req.GetTo(); len(to) != 0 {
+		if filter.To, err = time.ParseInLocation("2006-01-02", to, time.Local); err != nil {
+			s.logger.Error("grpcserver::ListMastersAdmin::ParseInLocation", err)
+			return invalidArgument(err)
+		}
+		filter.To = filter.To.AddDate(0, 0, 1)
+	}
+
+	page, limit := pageLimit(req.GetPage())
+	masters, err := s.DBAdapter.GetMastersAdmin(filter, page, limit)
+	if err != nil {
+		s.logger.Error("grpcserver::ListMastersAdmin::GetMastersAdmin", err)
+		return toStatus(err)
+	}
+
+	return s.sendMasters(stream, masters)
+}
+
+func (s *Server) sendMasters(stream interface{ Send(*pb.MasterSummary) error }, masters []*entities.MasterShort) error {
+
+	if err := handler.FillMasters(s.DBAdapter, s.ImageStorage, masters); err != nil {
+		s.logger.Error("grpcserver::sendMasters::FillMasters", err)
+		return toStatus(err)
+	}
+
+	for _, master := range masters {
+		if err := stream.Send(masterSummaryToPB(master)); err != nil {
+			s.logger.Error("grpcserver::sendMasters::Send", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) GetMaster(ctx context.Context, req *pb.GetMasterRequest) (*pb.Master, error) {
+
+	masterID, err := s.masterID(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	master, err := s.DBAdapter.GetMaster(masterID)
+	if err != nil {
+		s.logger.Error("grpcserver::GetMaster::GetMaster", err)
+		return nil, toStatus(err)
+	}
+	return masterToPB(master), nil
+}
+
+func (s *Server) CreateMaster(ctx context.Context, req *pb.CreateMasterRequest) (*pb.Master, error) {
+
+	master := &masterFromPB(req.GetMaster()).Master
+
+	// the master is linked to a Telegram user only by the bot
+	if !s.auth.IsUserKey(getMetadata(ctx, middleware.APIKeyHeader)) {
+		master.TelegramID = 0
+	} else {
+		telegramID, err := getTelegramID(ctx)
+		if err != nil {
+			s.logger.Error("grpcserver::CreateMaster::getTelegramID", err)
+			return nil, invalidArgument(err)
+		}
+		if telegramID != 0 {
+			master.TelegramID = telegramID
+		}
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(master); err != nil {
+		s.logger.Error("grpcserver::CreateMaster::Struct", err)
+		return nil, invalidArgument(err)
+	}
+
+	// approved right away, temporary, while the approvement mechanism is not integrated
+	id, err := s.DBAdapter.SaveApprovedMaster(master)
+	if err != nil {
+		s.logger.Error("grpcserver::CreateMaster::SaveApprovedMaster", err)
+		return nil, toStatus(err)
+	}
+
+	return s.GetMaster(ctx, &pb.GetMasterRequest{Id: id})
+}
+
+func (s *Server) UpdateMaster(ctx context.Context, req *pb.UpdateMasterRequest) (*pb.Master, error) {
+
+	master := masterFromPB(req.GetMaster())
+
+	validator := validator.New()
+	if err := validator.Struct(master); err != nil {
+		s.logger.Error("grpcserver::UpdateMaster::Struct", err)
+		return nil, invalidArgument(err)
+	}
+	if master.Version <= 0 {
+		return nil, errVersionRequired
+	}
+
+	if err := s.DBAdapter.UpdateMaster(master); err != nil {
+		s.logger.Error("grpcserver::UpdateMaster::UpdateMaster", err)
+		return nil, toStatus(err)
+	}
+	return s.GetMaster(ctx, &pb.GetMasterRequest{Id: master.ID})
+}
+
+func (s *Server) DeleteMaster(ctx context.Context, req *pb.DeleteMasterRequest) (*emptypb.Empty, error) {
+
+	if err := s.DBAdapter.DeleteMaster(req.GetId()); err != nil {
+		s.logger.Error("grpcserver::DeleteMaster::DeleteMaster", err)
+		return nil, toStatus(err)
+	}
+
+	if err := s.ImageStorage.DeleteMasterImages(req.GetId()); err != nil {
+		s.logger.Error("grpcserver::DeleteMaster::DeleteMasterImages", err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) ApproveMaster(ctx context.Context, req *pb.ApproveMasterRequest) (*emptypb.Empty, error) {
+
+	if err := s.DBAdapter.ApproveMaster(req.GetId()); err != nil {
+		s.logger.Error("grpcserver::ApproveMaster::ApproveMaster", err)
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
@@ -0,0 +1,1530 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: bot/v1/bot.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Cities_ListCities_FullMethodName = "/bot.v1.Cities/ListCities"
+	Cities_GetCity_FullMethodName    = "/bot.v1.Cities/GetCity"
+	Cities_CreateCity_FullMethodName = "/bot.v1.Cities/CreateCity"
+	Cities_UpdateCity_FullMethodName = "/bot.v1.Cities/UpdateCity"
+	Cities_DeleteCity_FullMethodName = "/bot.v1.Cities/DeleteCity"
+)
+
+// CitiesClient is the client API for Cities service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CitiesClient interface {
+	ListCities(ctx context.Context, in *ListCitiesRequest, opts ...grpc.CallOption) (Cities_ListCitiesClient, error)
+	GetCity(ctx context.Context, in *GetCityRequest, opts ...grpc.CallOption) (*City, error)
+	// admin
+	CreateCity(ctx context.Context, in *CreateCityRequest, opts ...grpc.CallOption) (*City, error)
+	// admin, the version of the city is required
+	UpdateCity(ctx context.Context, in *UpdateCityRequest, opts ...grpc.CallOption) (*City, error)
+	// admin
+	DeleteCity(ctx context.Context, in *DeleteCityRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type citiesClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCitiesClient(cc grpc.ClientConnInterface) CitiesClient {
+	return &citiesClient{cc}
+}
+
+func (c *citiesClient) ListCities(ctx context.Context, in *ListCitiesRequest, opts ...grpc.CallOption) (Cities_ListCitiesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Cities_ServiceDesc.Streams[0], Cities_ListCities_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &citiesListCitiesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Cities_ListCitiesClient interface {
+	Recv() (*City, error)
+	grpc.ClientStream
+}
+
+type citiesListCitiesClient struct {
+	grpc.ClientStream
+}
+
+func (x *citiesListCitiesClient) Recv() (*City, error) {
+	m := new(City)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *citiesClient) GetCity(ctx context.Context, in *GetCityRequest, opts ...grpc.CallOption) (*City, error) {
+	out := new(City)
+	err := c.cc.Invoke(ctx, Cities_GetCity_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *citiesClient) CreateCity(ctx context.Context, in *CreateCityRequest, opts ...grpc.CallOption) (*City, error) {
+	out := new(City)
+	err := c.cc.Invoke(ctx, Cities_CreateCity_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *citiesClient) UpdateCity(ctx context.Context, in *UpdateCityRequest, opts ...grpc.CallOption) (*City, error) {
+	out := new(City)
+	err := c.cc.Invoke(ctx, Cities_UpdateCity_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *citiesClient) DeleteCity(ctx context.Context, in *DeleteCityRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Cities_DeleteCity_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CitiesServer is the server API for Cities service.
+// All implementations must embed UnimplementedCitiesServer
+// for forward compatibility
+type CitiesServer interface {
+	ListCities(*ListCitiesRequest, Cities_ListCitiesServer) error
+	GetCity(context.Context, *GetCityRequest) (*City, error)
+	// admin
+	CreateCity(context.Context, *CreateCityRequest) (*City, error)
+	// admin, the version of the city is required
+	UpdateCity(context.Context, *UpdateCityRequest) (*City, error)
+	// admin
+	DeleteCity(context.Context, *DeleteCityRequest) (*emptypb.Empty, error)
+	mustEmbedUnimplementedCitiesServer()
+}
+
+// UnimplementedCitiesServer must be embedded to have forward compatible implementations.
+type UnimplementedCitiesServer struct {
+}
+
+func (UnimplementedCitiesServer) ListCities(*ListCitiesRequest, Cities_ListCitiesServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListCities not implemented")
+}
+func (UnimplementedCitiesServer) GetCity(context.Context, *GetCityRequest) (*City, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCity not implemented")
+}
+func (UnimplementedCitiesServer) CreateCity(context.Context, *CreateCityRequest) (*City, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateCity not implemented")
+}
+func (UnimplementedCitiesServer) UpdateCity(context.Context, *UpdateCityRequest) (*City, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateCity not implemented")
+}
+func (UnimplementedCitiesServer) DeleteCity(context.Context, *DeleteCityRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteCity not implemented")
+}
+func (UnimplementedCitiesServer) mustEmbedUnimplementedCitiesServer() {}
+
+// UnsafeCitiesServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CitiesServer will
+// result in compilation errors.
+type UnsafeCitiesServer interface {
+	mustEmbedUnimplementedCitiesServer()
+}
+
+func RegisterCitiesServer(s grpc.ServiceRegistrar, srv CitiesServer) {
+	s.RegisterService(&Cities_ServiceDesc, srv)
+}
+
+func _Cities_ListCities_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListCitiesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CitiesServer).ListCities(m, &citiesListCitiesServer{stream})
+}
+
+type Cities_ListCitiesServer interface {
+	Send(*City) error
+	grpc.ServerStream
+}
+
+type citiesListCitiesServer struct {
+	grpc.ServerStream
+}
+
+func (x *citiesListCitiesServer) Send(m *City) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Cities_GetCity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CitiesServer).GetCity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cities_GetCity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CitiesServer).GetCity(ctx, req.(*GetCityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cities_CreateCity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CitiesServer).CreateCity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cities_CreateCity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CitiesServer).CreateCity(ctx, req.(*CreateCityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cities_UpdateCity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateCityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CitiesServer).UpdateCity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cities_UpdateCity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CitiesServer).UpdateCity(ctx, req.(*UpdateCityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Cities_DeleteCity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteCityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CitiesServer).DeleteCity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Cities_DeleteCity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CitiesServer).DeleteCity(ctx, req.(*DeleteCityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Cities_ServiceDesc is the grpc.ServiceDesc for Cities service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Cities_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bot.v1.Cities",
+	HandlerType: (*CitiesServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetCity",
+			Handler:    _Cities_GetCity_Handler,
+		},
+		{
+			MethodName: "CreateCity",
+			Handler:    _Cities_CreateCity_Handler,
+		},
+		{
+			MethodName: "UpdateCity",
+			Handler:    _Cities_UpdateCity_Handler,
+		},
+		{
+			MethodName: "DeleteCity",
+			Handler:    _Cities_DeleteCity_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListCities",
+			Handler:       _Cities_ListCities_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "bot/v1/bot.proto",
+}
+
+const (
+	Categories_ListCategories_FullMethodName = "/bot.v1.Categories/ListCategories"
+	Categories_GetCategory_FullMethodName    = "/bot.v1.Categories/GetCategory"
+	Categories_CreateCategory_FullMethodName = "/bot.v1.Categories/CreateCategory"
+	Categories_UpdateCategory_FullMethodName = "/bot.v1.Categories/UpdateCategory"
+	Categories_DeleteCategory_FullMethodName = "/bot.v1.Categories/DeleteCategory"
+)
+
+// CategoriesClient is the client API for Categories service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CategoriesClient interface {
+	ListCategories(ctx context.Context, in *ListCategoriesRequest, opts ...grpc.CallOption) (Categories_ListCategoriesClient, error)
+	GetCategory(ctx context.Context, in *GetCategoryRequest, opts ...grpc.CallOption) (*Category, error)
+	// admin
+	CreateCategory(ctx context.Context, in *CreateCategoryRequest, opts ...grpc.CallOption) (*Category, error)
+	// admin, the version of the category is required
+	UpdateCategory(ctx context.Context, in *UpdateCategoryRequest, opts ...grpc.CallOption) (*Category, error)
+	// admin
+	DeleteCategory(ctx context.Context, in *DeleteCategoryRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type categoriesClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCategoriesClient(cc grpc.ClientConnInterface) CategoriesClient {
+	return &categoriesClient{cc}
+}
+
+func (c *categoriesClient) ListCategories(ctx context.Context, in *ListCategoriesRequest, opts ...grpc.CallOption) (Categories_ListCategoriesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Categories_ServiceDesc.Streams[0], Categories_ListCategories_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &categoriesListCategoriesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Categories_ListCategoriesClient interface {
+	Recv() (*Category, error)
+	grpc.ClientStream
+}
+
+type categoriesListCategoriesClient struct {
+	grpc.ClientStream
+}
+
+func (x *categoriesListCategoriesClient) Recv() (*Category, error) {
+	m := new(Category)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *categoriesClient) GetCategory(ctx context.Context, in *GetCategoryRequest, opts ...grpc.CallOption) (*Category, error) {
+	out := new(Category)
+	err := c.cc.Invoke(ctx, Categories_GetCategory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *categoriesClient) CreateCategory(ctx context.Context, in *CreateCategoryRequest, opts ...grpc.CallOption) (*Category, error) {
+	out := new(Category)
+	err := c.cc.Invoke(ctx, Categories_CreateCategory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *categoriesClient) UpdateCategory(ctx context.Context, in *UpdateCategoryRequest, opts ...grpc.CallOption) (*Category, error) {
+	out := new(Category)
+	err := c.cc.Invoke(ctx, Categories_UpdateCategory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *categoriesClient) DeleteCategory(ctx context.Context, in *DeleteCategoryRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Categories_DeleteCategory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CategoriesServer is the server API for Categories service.
+// All implementations must embed UnimplementedCategoriesServer
+// for forward compatibility
+type CategoriesServer interface {
+	ListCategories(*ListCategoriesRequest, Categories_ListCategoriesServer) error
+	GetCategory(context.Context, *GetCategoryRequest) (*Category, error)
+	// admin
+	CreateCategory(context.Context, *CreateCategoryRequest) (*Category, error)
+	// admin, the version of the category is required
+	UpdateCategory(context.Context, *UpdateCategoryRequest) (*Category, error)
+	// admin
+	DeleteCategory(context.Context, *DeleteCategoryRequest) (*emptypb.Empty, error)
+	mustEmbedUnimplementedCategoriesServer()
+}
+
+// UnimplementedCategoriesServer must be embedded to have forward compatible implementations.
+type UnimplementedCategoriesServer struct {
+}
+
+func (UnimplementedCategoriesServer) ListCategories(*ListCategoriesRequest, Categories_ListCategoriesServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListCategories not implemented")
+}
+func (UnimplementedCategoriesServer) GetCategory(context.Context, *GetCategoryRequest) (*Category, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCategory not implemented")
+}
+func (UnimplementedCategoriesServer) CreateCategory(context.Context, *CreateCategoryRequest) (*Category, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateCategory not implemented")
+}
+func (UnimplementedCategoriesServer) UpdateCategory(context.Context, *UpdateCategoryRequest) (*Category, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateCategory not implemented")
+}
+func (UnimplementedCategoriesServer) DeleteCategory(context.Context, *DeleteCategoryRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteCategory not implemented")
+}
+func (UnimplementedCategoriesServer) mustEmbedUnimplementedCategoriesServer() {}
+
+// UnsafeCategoriesServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CategoriesServer will
+// result in compilation errors.
+type UnsafeCategoriesServer interface {
+	mustEmbedUnimplementedCategoriesServer()
+}
+
+func RegisterCategoriesServer(s grpc.ServiceRegistrar, srv CategoriesServer) {
+	s.RegisterService(&Categories_ServiceDesc, srv)
+}
+
+func _Categories_ListCategories_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListCategoriesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CategoriesServer).ListCategories(m, &categoriesListCategoriesServer{stream})
+}
+
+type Categories_ListCategoriesServer interface {
+	Send(*Category) error
+	grpc.ServerStream
+}
+
+type categoriesListCategoriesServer struct {
+	grpc.ServerStream
+}
+
+func (x *categoriesListCategoriesServer) Send(m *Category) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Categories_GetCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CategoriesServer).GetCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Categories_GetCategory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CategoriesServer).GetCategory(ctx, req.(*GetCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Categories_CreateCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CategoriesServer).CreateCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Categories_CreateCategory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CategoriesServer).CreateCategory(ctx, req.(*CreateCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Categories_UpdateCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CategoriesServer).UpdateCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Categories_UpdateCategory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CategoriesServer).UpdateCategory(ctx, req.(*UpdateCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Categories_DeleteCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CategoriesServer).DeleteCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Categories_DeleteCategory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CategoriesServer).DeleteCategory(ctx, req.(*DeleteCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Categories_ServiceDesc is the grpc.ServiceDesc for Categories service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Categories_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bot.v1.Categories",
+	HandlerType: (*CategoriesServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetCategory",
+			Handler:    _Categories_GetCategory_Handler,
+		},
+		{
+			MethodName: "CreateCategory",
+			Handler:    _Categories_CreateCategory_Handler,
+		},
+		{
+			MethodName: "UpdateCategory",
+			Handler:    _Categories_UpdateCategory_Handler,
+		},
+		{
+			MethodName: "DeleteCategory",
+			Handler:    _Categories_DeleteCategory_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListCategories",
+			Handler:       _Categories_ListCategories_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "bot/v1/bot.proto",
+}
+
+const (
+	Services_ListServices_FullMethodName  = "/bot.v1.Services/ListServices"
+	Services_GetService_FullMethodName    = "/bot.v1.Services/GetService"
+	Services_CreateService_FullMethodName = "/bot.v1.Services/CreateService"
+	Services_UpdateService_FullMethodName = "/bot.v1.Services/UpdateService"
+	Services_DeleteService_FullMethodName = "/bot.v1.Services/DeleteService"
+)
+
+// ServicesClient is the client API for Services service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ServicesClient interface {
+	ListServices(ctx context.Context, in *ListServicesRequest, opts ...grpc.CallOption) (Services_ListServicesClient, error)
+	GetService(ctx context.Context, in *GetServiceRequest, opts ...grpc.CallOption) (*Service, error)
+	// admin
+	CreateService(ctx context.Context, in *CreateServiceRequest, opts ...grpc.CallOption) (*Service, error)
+	// admin, the version of the service is required
+	UpdateService(ctx context.Context, in *UpdateServiceRequest, opts ...grpc.CallOption) (*Service, error)
+	// admin
+	DeleteService(ctx context.Context, in *DeleteServiceRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type servicesClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewServicesClient(cc grpc.ClientConnInterface) ServicesClient {
+	return &servicesClient{cc}
+}
+
+func (c *servicesClient) ListServices(ctx context.Context, in *ListServicesRequest, opts ...grpc.CallOption) (Services_ListServicesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Services_ServiceDesc.Streams[0], Services_ListServices_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &servicesListServicesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Services_ListServicesClient interface {
+	Recv() (*Service, error)
+	grpc.ClientStream
+}
+
+type servicesListServicesClient struct {
+	grpc.ClientStream
+}
+
+func (x *servicesListServicesClient) Recv() (*Service, error) {
+	m := new(Service)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *servicesClient) GetService(ctx context.Context, in *GetServiceRequest, opts ...grpc.CallOption) (*Service, error) {
+	out := new(Service)
+	err := c.cc.Invoke(ctx, Services_GetService_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *servicesClient) CreateService(ctx context.Context, in *CreateServiceRequest, opts ...grpc.CallOption) (*Service, error) {
+	out := new(Service)
+	err := c.cc.Invoke(ctx, Services_CreateService_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *servicesClient) UpdateService(ctx context.Context, in *UpdateServiceRequest, opts ...grpc.CallOption) (*Service, error) {
+	out := new(Service)
+	err := c.cc.Invoke(ctx, Services_UpdateService_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *servicesClient) DeleteService(ctx context.Context, in *DeleteServiceRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Services_DeleteService_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ServicesServer is the server API for Services service.
+// All implementations must embed UnimplementedServicesServer
+// for forward compatibility
+type ServicesServer interface {
+	ListServices(*ListServicesRequest, Services_ListServicesServer) error
+	GetService(context.Context, *GetServiceRequest) (*Service, error)
+	// admin
+	CreateService(context.Context, *CreateServiceRequest) (*Service, error)
+	// admin, the version of the service is required
+	UpdateService(context.Context, *UpdateServiceRequest) (*Service, error)
+	// admin
+	DeleteService(context.Context, *DeleteServiceRequest) (*emptypb.Empty, error)
+	mustEmbedUnimplementedServicesServer()
+}
+
+// UnimplementedServicesServer must be embedded to have forward compatible implementations.
+type UnimplementedServicesServer struct {
+}
+
+func (UnimplementedServicesServer) ListServices(*ListServicesRequest, Services_ListServicesServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListServices not implemented")
+}
+func (UnimplementedServicesServer) GetService(context.Context, *GetServiceRequest) (*Service, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetService not implemented")
+}
+func (UnimplementedServicesServer) CreateService(context.Context, *CreateServiceRequest) (*Service, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateService not implemented")
+}
+func (UnimplementedServicesServer) UpdateService(context.Context, *UpdateServiceRequest) (*Service, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateService not implemented")
+}
+func (UnimplementedServicesServer) DeleteService(context.Context, *DeleteServiceRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteService not implemented")
+}
+func (UnimplementedServicesServer) mustEmbedUnimplementedServicesServer() {}
+
+// UnsafeServicesServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ServicesServer will
+// result in compilation errors.
+type UnsafeServicesServer interface {
+	mustEmbedUnimplementedServicesServer()
+}
+
+func RegisterServicesServer(s grpc.ServiceRegistrar, srv ServicesServer) {
+	s.RegisterService(&Services_ServiceDesc, srv)
+}
+
+func _Services_ListServices_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListServicesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ServicesServer).ListServices(m, &servicesListServicesServer{stream})
+}
+
+type Services_ListServicesServer interface {
+	Send(*Service) error
+	grpc.ServerStream
+}
+
+type servicesListServicesServer struct {
+	grpc.ServerStream
+}
+
+func (x *servicesListServicesServer) Send(m *Service) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Services_GetService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetServiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServicesServer).GetService(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Services_GetService_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServicesServer).GetService(ctx, req.(*GetServiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Services_CreateService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateServiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServicesServer).CreateService(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Services_CreateService_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServicesServer).CreateService(ctx, req.(*CreateServiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Services_UpdateService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateServiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServicesServer).UpdateService(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Services_UpdateService_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServicesServer).UpdateService(ctx, req.(*UpdateServiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Services_DeleteService_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteServiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ServicesServer).DeleteService(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Services_DeleteService_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ServicesServer).DeleteService(ctx, req.(*DeleteServiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Services_ServiceDesc is the grpc.ServiceDesc for Services service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Services_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bot.v1.Services",
+	HandlerType: (*ServicesServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetService",
+			Handler:    _Services_GetService_Handler,
+		},
+		{
+			MethodName: "CreateService",
+			Handler:    _Services_CreateService_Handler,
+		},
+		{
+			MethodName: "UpdateService",
+			Handler:    _Services_UpdateService_Handler,
+		},
+		{
+			MethodName: "DeleteService",
+			Handler:    _Services_DeleteService_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListServices",
+			Handler:       _Services_ListServices_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "bot/v1/bot.proto",
+}
+
+const (
+	Masters_ListMasters_FullMethodName      = "/bot.v1.Masters/ListMasters"
+	Masters_ListMastersAdmin_FullMethodName = "/bot.v1.Masters/ListMastersAdmin"
+	Masters_GetMaster_FullMethodName        = "/bot.v1.Masters/GetMaster"
+	Masters_CreateMaster_FullMethodName     = "/bot.v1.Masters/CreateMaster"
+	Masters_UpdateMaster_FullMethodName     = "/bot.v1.Masters/UpdateMaster"
+	Masters_DeleteMaster_FullMethodName     = "/bot.v1.Masters/DeleteMaster"
+	Masters_ApproveMaster_FullMethodName    = "/bot.v1.Masters/ApproveMaster"
+)
+
+// MastersClient is the client API for Masters service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MastersClient interface {
+	// the approved masters shown by the bot
+	ListMasters(ctx context.Context, in *ListMastersRequest, opts ...grpc.CallOption) (Masters_ListMastersClient, error)
+	// admin, the masters of the control panel
+	ListMastersAdmin(ctx context.Context, in *ListMastersAdminRequest, opts ...grpc.CallOption) (Masters_ListMastersAdminClient, error)
+	GetMaster(ctx context.Context, in *GetMasterRequest, opts ...grpc.CallOption) (*Master, error)
+	// the Telegram user ID from the metadata overrides the one of the master,
+	// both are ignored unless the bot key is sent
+	CreateMaster(ctx context.Context, in *CreateMasterRequest, opts ...grpc.CallOption) (*Master, error)
+	// admin, the version of the master is required
+	UpdateMaster(ctx context.Context, in *UpdateMasterRequest, opts ...grpc.CallOption) (*Master, error)
+	// admin
+	DeleteMaster(ctx context.Context, in *DeleteMasterRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// admin
+	ApproveMaster(ctx context.Context, in *ApproveMasterRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type mastersClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMastersClient(cc grpc.ClientConnInterface) MastersClient {
+	return &mastersClient{cc}
+}
+
+func (c *mastersClient) ListMasters(ctx context.Context, in *ListMastersRequest, opts ...grpc.CallOption) (Masters_ListMastersClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Masters_ServiceDesc.Streams[0], Masters_ListMasters_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &mastersListMastersClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Masters_ListMastersClient interface {
+	Recv() (*MasterSummary, error)
+	grpc.ClientStream
+}
+
+type mastersListMastersClient struct {
+	grpc.ClientStream
+}
+
+func (x *mastersListMastersClient) Recv() (*MasterSummary, error) {
+	m := new(MasterSummary)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *mastersClient) ListMastersAdmin(ctx context.Context, in *ListMastersAdminRequest, opts ...grpc.CallOption) (Masters_ListMastersAdminClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Masters_ServiceDesc.Streams[1], Masters_ListMastersAdmin_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &mastersListMastersAdminClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Masters_ListMastersAdminClient interface {
+	Recv() (*MasterSummary, error)
+	grpc.ClientStream
+}
+
+type mastersListMastersAdminClient struct {
+	grpc.ClientStream
+}
+
+func (x *mastersListMastersAdminClient) Recv() (*MasterSummary, error) {
+	m := new(MasterSummary)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *mastersClient) GetMaster(ctx context.Context, in *GetMasterRequest, opts ...grpc.CallOption) (*Master, error) {
+	out := new(Master)
+	err := c.cc.Invoke(ctx, Masters_GetMaster_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mastersClient) CreateMaster(ctx context.Context, in *CreateMasterRequest, opts ...grpc.CallOption) (*Master, error) {
+	out := new(Master)
+	err := c.cc.Invoke(ctx, Masters_CreateMaster_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mastersClient) UpdateMaster(ctx context.Context, in *UpdateMasterRequest, opts ...grpc.CallOption) (*Master, error) {
+	out := new(Master)
+	err := c.cc.Invoke(ctx, Masters_UpdateMaster_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mastersClient) DeleteMaster(ctx context.Context, in *DeleteMasterRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Masters_DeleteMaster_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mastersClient) ApproveMaster(ctx context.Context, in *ApproveMasterRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Masters_ApproveMaster_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MastersServer is the server API for Masters service.
+// All implementations must embed UnimplementedMastersServer
+// for forward compatibility
+type MastersServer interface {
+	// the approved masters shown by the bot
+	ListMasters(*ListMastersRequest, Masters_ListMastersServer) error
+	// admin, the masters of the control panel
+	ListMastersAdmin(*ListMastersAdminRequest, Masters_ListMastersAdminServer) error
+	GetMaster(context.Context, *GetMasterRequest) (*Master, error)
+	// the Telegram user ID from the metadata overrides the one of the master,
+	// both are ignored unless the bot key is sent
+	CreateMaster(context.Context, *CreateMasterRequest) (*Master, error)
+	// admin, the version of the master is required
+	UpdateMaster(context.Context, *UpdateMasterRequest) (*Master, error)
+	// admin
+	DeleteMaster(context.Context, *DeleteMasterRequest) (*emptypb.Empty, error)
+	// admin
+	ApproveMaster(context.Context, *ApproveMasterRequest) (*emptypb.Empty, error)
+	mustEmbedUnimplementedMastersServer()
+}
+
+// UnimplementedMastersServer must be embedded to have forward compatible implementations.
+type UnimplementedMastersServer struct {
+}
+
+func (UnimplementedMastersServer) ListMasters(*ListMastersRequest, Masters_ListMastersServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListMasters not implemented")
+}
+func (UnimplementedMastersServer) ListMastersAdmin(*ListMastersAdminRequest, Masters_ListMastersAdminServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListMastersAdmin not implemented")
+}
+func (UnimplementedMastersServer) GetMaster(context.Context, *GetMasterRequest) (*Master, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMaster not implemented")
+}
+func (UnimplementedMastersServer) CreateMaster(context.Context, *CreateMasterRequest) (*Master, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateMaster not implemented")
+}
+func (UnimplementedMastersServer) UpdateMaster(context.Context, *UpdateMasterRequest) (*Master, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateMaster not implemented")
+}
+func (UnimplementedMastersServer) DeleteMaster(context.Context, *DeleteMasterRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteMaster not implemented")
+}
+func (UnimplementedMastersServer) ApproveMaster(context.Context, *ApproveMasterRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApproveMaster not implemented")
+}
+func (UnimplementedMastersServer) mustEmbedUnimplementedMastersServer() {}
+
+// UnsafeMastersServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MastersServer will
+// result in compilation errors.
+type UnsafeMastersServer interface {
+	mustEmbedUnimplementedMastersServer()
+}
+
+func RegisterMastersServer(s grpc.ServiceRegistrar, srv MastersServer) {
+	s.RegisterService(&Masters_ServiceDesc, srv)
+}
+
+func _Masters_ListMasters_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListMastersRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MastersServer).ListMasters(m, &mastersListMastersServer{stream})
+}
+
+type Masters_ListMastersServer interface {
+	Send(*MasterSummary) error
+	grpc.ServerStream
+}
+
+type mastersListMastersServer struct {
+	grpc.ServerStream
+}
+
+func (x *mastersListMastersServer) Send(m *MasterSummary) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Masters_ListMastersAdmin_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListMastersAdminRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MastersServer).ListMastersAdmin(m, &mastersListMastersAdminServer{stream})
+}
+
+type Masters_ListMastersAdminServer interface {
+	Send(*MasterSummary) error
+	grpc.ServerStream
+}
+
+type mastersListMastersAdminServer struct {
+	grpc.ServerStream
+}
+
+func (x *mastersListMastersAdminServer) Send(m *MasterSummary) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Masters_GetMaster_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMasterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MastersServer).GetMaster(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Masters_GetMaster_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MastersServer).GetMaster(ctx, req.(*GetMasterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Masters_CreateMaster_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateMasterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MastersServer).CreateMaster(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Masters_CreateMaster_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MastersServer).CreateMaster(ctx, req.(*CreateMasterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Masters_UpdateMaster_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateMasterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MastersServer).UpdateMaster(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Masters_UpdateMaster_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MastersServer).UpdateMaster(ctx, req.(*UpdateMasterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Masters_DeleteMaster_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteMasterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MastersServer).DeleteMaster(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Masters_DeleteMaster_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MastersServer).DeleteMaster(ctx, req.(*DeleteMasterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Masters_ApproveMaster_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApproveMasterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MastersServer).ApproveMaster(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Masters_ApproveMaster_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MastersServer).ApproveMaster(ctx, req.(*ApproveMasterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Masters_ServiceDesc is the grpc.ServiceDesc for Masters service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Masters_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bot.v1.Masters",
+	HandlerType: (*MastersServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetMaster",
+			Handler:    _Masters_GetMaster_Handler,
+		},
+		{
+			MethodName: "CreateMaster",
+			Handler:    _Masters_CreateMaster_Handler,
+		},
+		{
+			MethodName: "UpdateMaster",
+			Handler:    _Masters_UpdateMaster_Handler,
+		},
+		{
+			MethodName: "DeleteMaster",
+			Handler:    _Masters_DeleteMaster_Handler,
+		},
+		{
+			MethodName: "ApproveMaster",
+			Handler:    _Masters_ApproveMaster_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListMasters",
+			Handler:       _Masters_ListMasters_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ListMastersAdmin",
+			Handler:       _Masters_ListMastersAdmin_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "bot/v1/bot.proto",
+}
+
+const (
+	Images_ListImages_FullMethodName    = "/bot.v1.Images/ListImages"
+	Images_UploadImage_FullMethodName   = "/bot.v1.Images/UploadImage"
+	Images_SetCover_FullMethodName      = "/bot.v1.Images/SetCover"
+	Images_ReorderImages_FullMethodName = "/bot.v1.Images/ReorderImages"
+	Images_UpdateCaption_FullMethodName = "/bot.v1.Images/UpdateCaption"
+	Images_DeleteImage_FullMethodName   = "/bot.v1.Images/DeleteImage"
+)
+
+// ImagesClient is the client API for Images service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ImagesClient interface {
+	ListImages(ctx context.Context, in *ListImagesRequest, opts ...grpc.CallOption) (Images_ListImagesClient, error)
+	// without the admin key the image of an approved master waits for a revision
+	UploadImage(ctx context.Context, in *UploadImageRequest, opts ...grpc.CallOption) (*Image, error)
+	// admin or self
+	SetCover(ctx context.Context, in *SetCoverRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// admin or self
+	ReorderImages(ctx context.Context, in *ReorderImagesRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// admin or self
+	UpdateCaption(ctx context.Context, in *UpdateCaptionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// admin or self
+	DeleteImage(ctx context.Context, in *DeleteImageRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type imagesClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewImagesClient(cc grpc.ClientConnInterface) ImagesClient {
+	return &imagesClient{cc}
+}
+
+func (c *imagesClient) ListImages(ctx context.Context, in *ListImagesRequest, opts ...grpc.CallOption) (Images_ListImagesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Images_ServiceDesc.Streams[0], Images_ListImages_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &imagesListImagesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Images_ListImagesClient interface {
+	Recv() (*Image, error)
+	grpc.ClientStream
+}
+
+type imagesListImagesClient struct {
+	grpc.ClientStream
+}
+
+func (x *imagesListImagesClient) Recv() (*Image, error) {
+	m := new(Image)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *imagesClient) UploadImage(ctx context.Context, in *UploadImageRequest, opts ...grpc.CallOption) (*Image, error) {
+	out := new(Image)
+	err := c.cc.Invoke(ctx, Images_UploadImage_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imagesClient) SetCover(ctx context.Context, in *SetCoverRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Images_SetCover_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imagesClient) ReorderImages(ctx context.Context, in *ReorderImagesRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Images_ReorderImages_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imagesClient) UpdateCaption(ctx context.Context, in *UpdateCaptionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Images_UpdateCaption_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imagesClient) DeleteImage(ctx context.Context, in *DeleteImageRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Images_DeleteImage_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ImagesServer is the server API for Images service.
+// All implementations must embed UnimplementedImagesServer
+// for forward compatibility
+type ImagesServer interface {
+	ListImages(*ListImagesRequest, Images_ListImagesServer) error
+	// without the admin key the image of an approved master waits for a revision
+	UploadImage(context.Context, *UploadImageRequest) (*Image, error)
+	// admin or self
+	SetCover(context.Context, *SetCoverRequest) (*emptypb.Empty, error)
+	// admin or self
+	ReorderImages(context.Context, *ReorderImagesRequest) (*emptypb.Empty, error)
+	// admin or self
+	UpdateCaption(context.Context, *UpdateCaptionRequest) (*emptypb.Empty, error)
+	// admin or self
+	DeleteImage(context.Context, *DeleteImageRequest) (*emptypb.Empty, error)
+	mustEmbedUnimplementedImagesServer()
+}
+
+// UnimplementedImagesServer must be embedded to have forward compatible implementations.
+type UnimplementedImagesServer struct {
+}
+
+func (UnimplementedImagesServer) ListImages(*ListImagesRequest, Images_ListImagesServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListImages not implemented")
+}
+func (UnimplementedImagesServer) UploadImage(context.Context, *UploadImageRequest) (*Image, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UploadImage not implemented")
+}
+func (UnimplementedImagesServer) SetCover(context.Context, *SetCoverRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetCover not implemented")
+}
+func (UnimplementedImagesServer) ReorderImages(context.Context, *ReorderImagesRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReorderImages not implemented")
+}
+func (UnimplementedImagesServer) UpdateCaption(context.Context, *UpdateCaptionRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateCaption not implemented")
+}
+func (UnimplementedImagesServer) DeleteImage(context.Context, *DeleteImageRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteImage not implemented")
+}
+func (UnimplementedImagesServer) mustEmbedUnimplementedImagesServer() {}
+
+// UnsafeImagesServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ImagesServer will
+// result in compilation errors.
+type UnsafeImagesServer interface {
+	mustEmbedUnimplementedImagesServer()
+}
+
+func RegisterImagesServer(s grpc.ServiceRegistrar, srv ImagesServer) {
+	s.RegisterService(&Images_ServiceDesc, srv)
+}
+
+func _Images_ListImages_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListImagesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ImagesServer).ListImages(m, &imagesListImagesServer{stream})
+}
+
+type Images_ListImagesServer interface {
+	Send(*Image) error
+	grpc.ServerStream
+}
+
+type imagesListImagesServer struct {
+	grpc.ServerStream
+}
+
+func (x *imagesListImagesServer) Send(m *Image) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Images_UploadImage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UploadImageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImagesServer).UploadImage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Images_UploadImage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImagesServer).UploadImage(ctx, req.(*UploadImageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Images_SetCover_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetCoverRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImagesServer).SetCover(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Images_SetCover_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImagesServer).SetCover(ctx, req.(*SetCoverRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Images_ReorderImages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReorderImagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImagesServer).ReorderImages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Images_ReorderImages_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImagesServer).ReorderImages(ctx, req.(*ReorderImagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Images_UpdateCaption_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateCaptionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImagesServer).UpdateCaption(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Images_UpdateCaption_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImagesServer).UpdateCaption(ctx, req.(*UpdateCaptionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Images_DeleteImage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteImageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImagesServer).DeleteImage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Images_DeleteImage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImagesServer).DeleteImage(ctx, req.(*DeleteImageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Images_ServiceDesc is the grpc.ServiceDesc for Images service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Images_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "bot.v1.Images",
+	HandlerType: (*ImagesServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "UploadImage",
+			Handler:    _Images_UploadImage_Handler,
+		},
+		{
+			MethodName: "SetCover",
+			Handler:    _Images_SetCover_Handler,
+		},
+		{
+			MethodName: "ReorderImages",
+			Handler:    _Images_ReorderImages_Handler,
+		},
+		{
+			MethodName: "UpdateCaption",
+			Handler:    _Images_UpdateCaption_Handler,
+		},
+		{
+			MethodName: "DeleteImage",
+			Handler:    _Images_DeleteImage_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListImages",
+			Handler:       _Images_ListImages_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "bot/v1/bot.proto",
+}
@@ -0,0 +1,84 @@
+package grpcserver
+
+import (
+	"bot/internal/config"
+	"bot/internal/grpcserver/pb"
+	"bot/internal/ratelimiter"
+	middleware "bot/internal/server/middleware"
+	"context"
+	"fmt"
+	"math"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// limitedRoutes are the HTTP routes whose rate limits apply to the methods as well
+var limitedRoutes = map[string]string{
+	pb.Masters_CreateMaster_FullMethodName: "POST /masters",
+	pb.Images_UploadImage_FullMethodName:   "POST /masters/{master_id}/images",
+}
+
+func routeLimits(limits []config.RateLimit) map[string]ratelimiter.Limit {
+	result := make(map[string]ratelimiter.Limit)
+	for _, limit := range limits {
+		result[limit.Method+" "+limit.Path] = ratelimiter.Limit{Rate: limit.Rate, Burst: limit.Burst}
+	}
+	return result
+}
+
+// rateLimit applies the limit of the HTTP route to the method, the clients are told
+// when to retry in the retry-after header
+func (s *Server) rateLimit(ctx context.Context, method string) error {
+
+	route, ok := limitedRoutes[method]
+	if !ok {
+		return nil
+	}
+	limit, ok := s.limits[route]
+	if !ok {
+		return nil
+	}
+
+	client := s.clientKey(ctx)
+	allowed, retryAfter, err := s.limiter.Take(route+" "+client, limit)
+	if err != nil {
+		// the limiter must not take the API down with it
+		s.logger.Error("grpcserver::rateLimit::Take", err)
+		return nil
+	}
+
+	if !allowed {
+		s.logger.Infof("Rate limit exceeded: %s %s", method, client)
+		header := metadata.Pairs("retry-after", fmt.Sprintf("%d", int64(math.Ceil(retryAfter.Seconds()))))
+		if err := grpc.SetHeader(ctx, header); err != nil {
+			s.logger.Error("grpcserver::rateLimit::SetHeader", err)
+		}
+		return status.Error(codes.ResourceExhausted, "too many requests")
+	}
+	return nil
+}
+
+// clientKey identifies the clients the way the HTTP rate limits do, the peer address takes the place of the IP
+func (s *Server) clientKey(ctx context.Context) string {
+	if key := getMetadata(ctx, middleware.APIKeyHeader); s.auth.IsUserKey(key) {
+		if userID := getMetadata(ctx, middleware.TelegramUserIDHeader); len(userID) != 0 {
+			return "tg:" + userID
+		}
+		return "key:" + key
+	}
+
+	client, ok := peer.FromContext(ctx)
+	if !ok {
+		return "ip:"
+	}
+	host, _, err := net.SplitHostPort(client.Addr.String())
+	if err != nil {
+		host = client.Addr.String()
+	}
+	return "ip:" + host
+}
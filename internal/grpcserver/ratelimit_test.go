@@ -0,0 +1,121 @@
+package grpcserver
+
+import (
+	"bot/internal/config"
+	"bot/internal/grpcserver/pb"
+	"bot/internal/logger"
+	"bot/internal/ratelimiter"
+	middleware "bot/internal/server/middleware"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimitCreateMaster(t *testing.T) {
+
+	cfg := &config.Config{
+		BotKeys:    []string{"bot"},
+		RateLimits: []config.RateLimit{{Method: "POST", Path: "/masters", Rate: 0.001, Burst: 1}},
+	}
+	s := &Server{
+		logger:  logger.NewLogger(),
+		auth:    middleware.NewAuth(cfg),
+		limiter: ratelimiter.NewMemoryStore(time.Hour),
+		limits:  routeLimits(cfg.RateLimits),
+	}
+
+	fromIP := func(ip string, md ...string) context.Context {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 1234}})
+		return metadata.NewIncomingContext(ctx, metadata.Pairs(md...))
+	}
+	create := pb.Masters_CreateMaster_FullMethodName
+
+	if err := s.rateLimit(fromIP("192.0.2.1"), create); err != nil {
+		t.Fatalf("the first registration was limited: %s", err)
+	}
+	if err := s.rateLimit(fromIP("192.0.2.1"), create); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("got %v, want ResourceExhausted", err)
+	}
+	// another client and an unlimited method have their own budget
+	if err := s.rateLimit(fromIP("192.0.2.2"), create); err != nil {
+		t.Errorf("another IP was limited: %s", err)
+	}
+	if err := s.rateLimit(fromIP("192.0.2.1"), pb.Masters_GetMaster_FullMethodName); err != nil {
+		t.Errorf("an unlimited method was limited: %s", err)
+	}
+
+	// the bot is limited by the Telegram user, not by its address
+	bot := func(userID string) context.Context {
+		return fromIP("192.0.2.1", "x-api-key", "bot", "x-telegram-user-id", userID)
+	}
+	if err := s.rateLimit(bot("42"), create); err != nil {
+		t.Errorf("the user was limited by the address of the bot: %s", err)
+	}
+	if err := s.rateLimit(bot("43"), create); err != nil {
+		t.Errorf("another user was limited: %s", err)
+	}
+	if err := s.rateLimit(bot("42"), create); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("got %v, want ResourceExhausted", err)
+	}
+}
+
+// the uploads share the budget of the HTTP route, the message size doesn't matter
+func TestRateLimitUploadImage(t *testing.T) {
+
+	cfg := &config.Config{
+		RateLimits: []config.RateLimit{{Method: "POST", Path: "/masters/{master_id}/images", Rate: 0.001, Burst: 2}},
+	}
+	s := &Server{
+		logger:  logger.NewLogger(),
+		auth:    middleware.NewAuth(cfg),
+		limiter: ratelimiter.NewMemoryStore(time.Hour),
+		limits:  routeLimits(cfg.RateLimits),
+	}
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1234}})
+	upload := pb.Images_UploadImage_FullMethodName
+
+	for index := 0; index < 2; index++ {
+		if err := s.rateLimit(ctx, upload); err != nil {
+			t.Fatalf("upload %d was limited: %s", index+1, err)
+		}
+	}
+	if err := s.rateLimit(ctx, upload); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("got %v, want ResourceExhausted", err)
+	}
+}
+
+// an upload to an approved master goes live only with the admin key
+func TestUploadByMaster(t *testing.T) {
+
+	s := &Server{auth: middleware.NewAuth(&config.Config{AdminKeys: []string{"admin"}, BotKeys: []string{"bot"}})}
+
+	withKey := func(key string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", key))
+	}
+
+	tests := map[string]struct {
+		ctx      context.Context
+		masterID string
+		want     bool
+	}{
+		"anonymous":  {ctx: context.Background(), masterID: "1", want: true},
+		"bot":        {ctx: withKey("bot"), masterID: "1", want: true},
+		"admin":      {ctx: withKey("admin"), masterID: "1", want: false},
+		"self":       {ctx: withKey("bot"), masterID: Self, want: true},
+		"admin self": {ctx: withKey("admin"), masterID: Self, want: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := s.byMaster(test.ctx, test.masterID); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
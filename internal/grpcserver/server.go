@@ -0,0 +1,96 @@
+// Package grpcserver serves the gRPC API defined in proto/bot/v1/bot.proto,
+// it shares the storages and the auth rules with the HTTP handlers
+package grpcserver
+
+import (
+	"bot/internal/config"
+	"bot/internal/dbadapter"
+	"bot/internal/grpcserver/pb"
+	"bot/internal/logger"
+	"bot/internal/ratelimiter"
+	middleware "bot/internal/server/middleware"
+	"bot/internal/storage"
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+type Server struct {
+	pb.UnimplementedCitiesServer
+	pb.UnimplementedCategoriesServer
+	pb.UnimplementedServicesServer
+	pb.UnimplementedMastersServer
+	pb.UnimplementedImagesServer
+
+	logger       logger.Logger
+	cfg          *config.Config
+	auth         *middleware.Auth
+	limiter      ratelimiter.Store
+	limits       map[string]ratelimiter.Limit
+	DBAdapter    *dbadapter.DBAdapter
+	ImageStorage storage.ImageStorage
+}
+
+func NewServer(logger logger.Logger, cfg *config.Config, DBAdapter *dbadapter.DBAdapter, ImageStorage storage.ImageStorage, options ...grpc.ServerOption) *grpc.Server {
+
+	s := &Server{
+		logger:       logger,
+		cfg:          cfg,
+		auth:         middleware.NewAuth(cfg),
+		limiter:      ratelimiter.NewMemoryStore(time.Hour),
+		limits:       routeLimits(cfg.RateLimits),
+		DBAdapter:    DBAdapter,
+		ImageStorage: ImageStorage,
+	}
+
+	// the uploaded images come in a single message
+	options = append(options,
+		grpc.MaxRecvMsgSize(int(cfg.MaxImageSize)+(1<<20)),
+		grpc.UnaryInterceptor(s.unaryInterceptor),
+		grpc.StreamInterceptor(s.streamInterceptor),
+	)
+
+	server := grpc.NewServer(options...)
+	pb.RegisterCitiesServer(server, s)
+	pb.RegisterCategoriesServer(server, s)
+	pb.RegisterServicesServer(server, s)
+	pb.RegisterMastersServer(server, s)
+	pb.RegisterImagesServer(server, s)
+	return server
+}
+
+func (s *Server) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	s.logger.Infof("Request received: %s", info.FullMethod)
+
+	if err := s.authorize(ctx, info.FullMethod, req); err != nil {
+		s.logger.Error("grpcserver::authorize", err)
+		return nil, err
+	}
+
+	if err := s.rateLimit(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Response sent")
+	return resp, nil
+}
+
+func (s *Server) streamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	s.logger.Infof("Request received: %s", info.FullMethod)
+
+	if err := s.authorize(stream.Context(), info.FullMethod, nil); err != nil {
+		s.logger.Error("grpcserver::authorize", err)
+		return err
+	}
+
+	if err := handler(srv, stream); err != nil {
+		return err
+	}
+	s.logger.Info("Response sent")
+	return nil
+}
@@ -7,15 +7,17 @@ import (
 
 func FromCityModel(model *models.City) *entities.City {
 	return &entities.City{
-		ID:   model.ID,
-		Name: model.Name,
+		ID:      model.ID,
+		Name:    model.Name,
+		Version: model.Version,
 	}
 }
 
 func FromServCatModel(model *models.ServiceCategory) *entities.ServiceCategory {
 	return &entities.ServiceCategory{
-		ID:   model.ID,
-		Name: model.Name,
+		ID:      model.ID,
+		Name:    model.Name,
+		Version: model.Version,
 	}
 }
 
@@ -25,16 +27,85 @@ func FromServiceModel(model *models.Service) *entities.Service {
 		Name:    model.Name,
 		CatID:   model.CatID,
 		CatName: model.CatName,
+		Version: model.Version,
 	}
 }
 
-func FromMasterServRelationModel(model *models.MasterServRelation) *entities.MasterShort {
+func FromMasterModelShort(model *models.Master) *entities.MasterShort {
 	return &entities.MasterShort{
-		ID:          model.MasterID,
+		ID:          model.ID,
 		Name:        model.Name,
 		Description: model.Description,
 		Contact:     model.Contact,
 		CityName:    model.CityName,
 		ServCatName: model.ServCatName,
+		RegDate:     model.CreatedAt.Format("2006-01-02"),
+	}
+}
+
+func FromImageModel(model *models.Image) *entities.Image {
+	return &entities.Image{
+		Name:        model.ID,
+		ContentType: model.ContentType,
+		Size:        model.Size,
+		Width:       model.Width,
+		Height:      model.Height,
+		Position:    model.Position,
+		Caption:     model.Caption,
+		IsPrimary:   model.IsPrimary,
+		ObjectKey:   model.ObjectKey,
+		Pending:     model.Pending,
+		Replaces:    model.Replaces,
+		NewCaption:  model.NewCaption,
+	}
+}
+
+func FromClientModel(model *models.Client) *entities.Client {
+	client := &entities.Client{
+		TelegramID: model.TelegramID,
+		Language:   model.Language,
+		CreatedAt:  model.CreatedAt,
+		LastSeenAt: model.LastSeenAt,
+	}
+	if model.DefaultCityID != nil {
+		client.DefaultCityID = *model.DefaultCityID
+	}
+	return client
+}
+
+func FromMasterRevisionModel(model *models.MasterRevision) *entities.MasterRevision {
+	return &entities.MasterRevision{
+		ID:         model.ID,
+		MasterID:   model.MasterID,
+		CreatedAt:  model.CreatedAt,
+		ReviewedAt: model.ReviewedAt,
+		ImagesOnly: model.ImagesOnly,
+		Master: entities.Master{
+			Name:        model.Name,
+			Description: model.Description,
+			Contact:     model.Contact,
+			CityID:      model.CityID,
+			ServCatID:   model.ServCatID,
+			ServIDs:     model.ServIDs,
+			Status:      model.Status,
+			Version:     model.Version,
+		},
+	}
+}
+
+func FromMasterModel(model *models.Master) *entities.MasterLong {
+	return &entities.MasterLong{
+		ID: model.ID,
+		Master: entities.Master{
+			Name:        model.Name,
+			Description: model.Description,
+			Contact:     model.Contact,
+			CityID:      model.CityID,
+			ServCatID:   model.ServCatID,
+			ServIDs:     model.ServIDs,
+			Status:      model.Status,
+			TelegramID:  model.TelegramID,
+			Version:     model.Version,
+		},
 	}
 }
@@ -0,0 +1,50 @@
+package mergepatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const ContentType = "application/merge-patch+json"
+
+var ErrInvalidPatch = errors.New("invalid merge patch")
+
+// Apply applies the JSON merge patch (RFC 7396) to the document
+func Apply(doc, patch []byte) ([]byte, error) {
+
+	var target interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+
+	var changes interface{}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, err)
+	}
+
+	return json.Marshal(merge(target, changes))
+}
+
+// merge replaces the target unless both are objects, the null members of the patch remove the members of the target
+func merge(target, patch interface{}) interface{} {
+
+	changes, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	result, ok := target.(map[string]interface{})
+	if !ok {
+		result = make(map[string]interface{})
+	}
+
+	for key, value := range changes {
+		if value == nil {
+			delete(result, key)
+			continue
+		}
+		result[key] = merge(result[key], value)
+	}
+	return result
+}
@@ -0,0 +1,99 @@
+package mergepatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+
+	tests := map[string]struct {
+		doc   string
+		patch string
+		want  string
+	}{
+		"null deletes the key": {
+			doc:   `{"name": "Berlin", "version": 2}`,
+			patch: `{"version": null}`,
+			want:  `{"name": "Berlin"}`,
+		},
+		"null of a missing key": {
+			doc:   `{"name": "Berlin"}`,
+			patch: `{"version": null}`,
+			want:  `{"name": "Berlin"}`,
+		},
+		"new key": {
+			doc:   `{"name": "Berlin"}`,
+			patch: `{"version": 3}`,
+			want:  `{"name": "Berlin", "version": 3}`,
+		},
+		"nested objects merge": {
+			doc:   `{"name": "Anna", "contact": {"phone": "123", "telegram": "@anna"}}`,
+			patch: `{"contact": {"phone": "456", "telegram": null, "email": "anna@example.com"}}`,
+			want:  `{"name": "Anna", "contact": {"phone": "456", "email": "anna@example.com"}}`,
+		},
+		"object over a value": {
+			doc:   `{"contact": "@anna"}`,
+			patch: `{"contact": {"telegram": "@anna", "phone": null}}`,
+			want:  `{"contact": {"telegram": "@anna"}}`,
+		},
+		"arrays are replaced": {
+			doc:   `{"servIDs": ["1", "2", "3"]}`,
+			patch: `{"servIDs": ["4"]}`,
+			want:  `{"servIDs": ["4"]}`,
+		},
+		"array of objects is replaced": {
+			doc:   `{"images": [{"name": "a", "caption": "x"}]}`,
+			patch: `{"images": [{"name": "b"}]}`,
+			want:  `{"images": [{"name": "b"}]}`,
+		},
+		"array patch replaces the object": {
+			doc:   `{"name": "Berlin"}`,
+			patch: `["Berlin"]`,
+			want:  `["Berlin"]`,
+		},
+		"string patch replaces the object": {
+			doc:   `{"name": "Berlin"}`,
+			patch: `"Berlin"`,
+			want:  `"Berlin"`,
+		},
+		"null patch replaces the object": {
+			doc:   `{"name": "Berlin"}`,
+			patch: `null`,
+			want:  `null`,
+		},
+		"empty patch keeps the object": {
+			doc:   `{"name": "Berlin"}`,
+			patch: `{}`,
+			want:  `{"name": "Berlin"}`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := Apply([]byte(test.doc), []byte(test.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got, want interface{}
+			if err := json.Unmarshal(result, &got); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(test.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %s, want %s", result, test.want)
+			}
+		})
+	}
+}
+
+func TestApplyInvalid(t *testing.T) {
+	if _, err := Apply([]byte(`{"name": "Berlin"}`), []byte(`{"name": `)); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("got %v, want %v", err, ErrInvalidPatch)
+	}
+}
@@ -4,9 +4,18 @@ import (
 	"bot/internal/config"
 	"bot/internal/entities"
 	"bot/internal/logger"
+	"bot/internal/storage"
 	"context"
 	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
 	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
@@ -16,6 +25,8 @@ type MinIOAdapter struct {
 	logger logger.Logger
 	cfg    *config.Config
 	client *minio.Client
+	// signs URLs for the public endpoint, the same as client if none is configured
+	presignClient *minio.Client
 }
 
 func NewMinIOAdapter(logger logger.Logger, cfg *config.Config) (*MinIOAdapter, error) {
@@ -23,6 +34,7 @@ func NewMinIOAdapter(logger logger.Logger, cfg *config.Config) (*MinIOAdapter, e
 	options := &minio.Options{
 		Creds:  credentials.NewStaticV4(cfg.MinIOUser, cfg.MinIOPass, ""),
 		Secure: true,
+		Region: cfg.MinIORegion,
 	}
 
 	client, err := minio.New(fmt.Sprintf("%s:%d", cfg.MinIOHost, cfg.MinIOPort), options)
@@ -30,16 +42,38 @@ func NewMinIOAdapter(logger logger.Logger, cfg *config.Config) (*MinIOAdapter, e
 		return nil, err
 	}
 
-	return &MinIOAdapter{logger: logger, cfg: cfg, client: client}, nil
+	presignClient := client
+	if len(cfg.MinIOPublicEndpoint) != 0 {
+		if presignClient, err = minio.New(cfg.MinIOPublicEndpoint, options); err != nil {
+			return nil, err
+		}
+	}
+
+	return &MinIOAdapter{logger: logger, cfg: cfg, client: client, presignClient: presignClient}, nil
 }
 
-func (m *MinIOAdapter) MakeBucket(bucketName string) error {
+// EnsureBucket creates the images bucket if needed and allows anonymous
+// reads of the master images only, unless the images are private
+func (m *MinIOAdapter) EnsureBucket() error {
 
-	if err := m.client.MakeBucket(context.Background(), bucketName, minio.MakeBucketOptions{}); err != nil {
+	exists, err := m.client.BucketExists(context.Background(), m.cfg.MinIOBucket)
+	if err != nil {
 		return err
 	}
 
-	bucketPolicy := `
+	if !exists {
+		if err := m.client.MakeBucket(context.Background(), m.cfg.MinIOBucket, minio.MakeBucketOptions{}); err != nil {
+			return err
+		}
+		m.logger.Infof("Bucket created: %s", m.cfg.MinIOBucket)
+	}
+
+	if m.cfg.PrivateImages {
+		// an empty policy removes the anonymous access
+		return m.client.SetBucketPolicy(context.Background(), m.cfg.MinIOBucket, "")
+	}
+
+	bucketPolicy := fmt.Sprintf(`
 	{
 		"Version": "2012-10-17",
 		"Statement": [
@@ -51,78 +85,177 @@ func (m *MinIOAdapter) MakeBucket(bucketName string) error {
                 ]
 			},
 			"Action": [
-			  "s3:GetBucketLocation",
-			  "s3:ListBucket",
 			  "s3:GetObject"
 			],
 			"Resource": [
-			  "arn:aws:s3:::*"
+			  "arn:aws:s3:::%s/%s/*"
 			]
 		  }
 		]
-	  }`
+	  }`, m.cfg.MinIOBucket, storage.MastersPrefix)
 
-	if err := m.client.SetBucketPolicy(context.Background(), bucketName, bucketPolicy); err != nil {
+	if err := m.client.SetBucketPolicy(context.Background(), m.cfg.MinIOBucket, bucketPolicy); err != nil {
 		return err
 	}
 
-	m.logger.Infof("Bucket created: %s", bucketName)
 	return nil
 }
 
-func (m *MinIOAdapter) GetMasterImagesURLs(bucketName string) []string {
+// GetImageURL returns the public link of the image or a presigned one if the images are private
+func (m *MinIOAdapter) GetImageURL(objectKey string) (string, error) {
+
+	if !m.cfg.PrivateImages {
+		return fmt.Sprintf("%s/%s/%s", m.cfg.ImagePrefix, m.cfg.MinIOBucket, objectKey), nil
+	}
 
-	list := make([]string, 0)
-	for object := range m.client.ListObjects(context.Background(), bucketName, minio.ListObjectsOptions{}) {
-		list = append(list, fmt.Sprintf("%s/%s/%s", m.cfg.ImagePrefix, bucketName, object.Key))
+	expiry := time.Duration(m.cfg.DownloadURLExpiry) * time.Second
+	signed, err := m.presignClient.PresignedGetObject(context.Background(), m.cfg.MinIOBucket, objectKey, expiry, url.Values{})
+	if err != nil {
+		return "", err
 	}
-	return list
+	return signed.String(), nil
 }
 
-func (m *MinIOAdapter) GetMasterImages(bucketName string) []*entities.Image {
-	list := make([]*entities.Image, 0)
-	for object := range m.client.ListObjects(context.Background(), bucketName, minio.ListObjectsOptions{}) {
-		image := &entities.Image{
-			Name: object.Key,
-			URL:  fmt.Sprintf("%s/%s/%s", m.cfg.ImagePrefix, bucketName, object.Key),
-		}
-		list = append(list, image)
+// PresignUpload returns a PUT URL for the object, the upload is accepted only
+// with exactly the signed Content-Type and Content-Length headers
+func (m *MinIOAdapter) PresignUpload(objectKey, contentType string, size int64) (string, http.Header, error) {
+
+	headers := http.Header{}
+	headers.Set("Content-Type", contentType)
+	headers.Set("Content-Length", strconv.FormatInt(size, 10))
+
+	expiry := time.Duration(m.cfg.UploadURLExpiry) * time.Second
+	signed, err := m.presignClient.PresignHeader(context.Background(), http.MethodPut, m.cfg.MinIOBucket, objectKey, expiry, url.Values{}, headers)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed.String(), headers, nil
+}
+
+// StatMasterImage reads the stored object metadata and the image dimensions
+func (m *MinIOAdapter) StatMasterImage(objectKey string) (*entities.Image, error) {
+
+	info, err := m.client.StatObject(context.Background(), m.cfg.MinIOBucket, objectKey, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &entities.Image{
+		ObjectKey:   objectKey,
+		ContentType: info.ContentType,
+		Size:        info.Size,
+	}
+
+	object, err := m.client.GetObject(context.Background(), m.cfg.MinIOBucket, objectKey, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	if config, _, err := image.DecodeConfig(object); err == nil {
+		meta.Width = config.Width
+		meta.Height = config.Height
 	}
-	return list
+
+	return meta, nil
 }
 
-func (m *MinIOAdapter) PutMasterImage(bucketName, objectName string, file io.Reader, size int64, contentType string) error {
+func (m *MinIOAdapter) PutMasterImage(objectKey string, file io.Reader, size int64, contentType string) error {
 	options := minio.PutObjectOptions{
 		ContentType: contentType,
 	}
 
-	if _, err := m.client.PutObject(context.Background(), bucketName, objectName, file, size, options); err != nil {
+	if _, err := m.client.PutObject(context.Background(), m.cfg.MinIOBucket, objectKey, file, size, options); err != nil {
 		return err
 	}
 
-	m.logger.Infof("Object saved: %s %s", bucketName, objectName)
+	m.logger.Infof("Object saved: %s", objectKey)
 	return nil
 }
 
-func (m *MinIOAdapter) DeleteMasterImage(bucketName, objectName string) error {
-	if err := m.client.RemoveObject(context.Background(), bucketName, objectName, minio.RemoveObjectOptions{}); err != nil {
+func (m *MinIOAdapter) DeleteMasterImage(objectKey string) error {
+	if err := m.client.RemoveObject(context.Background(), m.cfg.MinIOBucket, objectKey, minio.RemoveObjectOptions{}); err != nil {
 		return err
 	}
 
-	m.logger.Infof("Object %s deletede from bucket %s", objectName, bucketName)
+	m.logger.Infof("Object deleted: %s", objectKey)
 	return nil
 }
 
-func (m *MinIOAdapter) DeleteMasterImages(bucketName string) error {
+func (m *MinIOAdapter) DeleteMasterImages(masterID string) error {
+
+	options := minio.ListObjectsOptions{Prefix: storage.MasterImageKey(masterID, ""), Recursive: true}
+	objects := m.client.ListObjects(context.Background(), m.cfg.MinIOBucket, options)
+	for err := range m.client.RemoveObjects(context.Background(), m.cfg.MinIOBucket, objects, minio.RemoveObjectsOptions{}) {
+		return err.Err
+	}
+
+	m.logger.Infof("Images of master %s deleted", masterID)
+	return nil
+}
+
+// ListLegacyBuckets returns every bucket except the images bucket,
+// left from the layout with one bucket per master
+func (m *MinIOAdapter) ListLegacyBuckets() ([]string, error) {
+
+	buckets, err := m.client.ListBuckets(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0)
+	for _, bucket := range buckets {
+		if bucket.Name != m.cfg.MinIOBucket {
+			result = append(result, bucket.Name)
+		}
+	}
+	return result, nil
+}
+
+// CopyLegacyBucket copies the objects of a per-master bucket into the images
+// bucket under the master prefix, the old bucket is left untouched
+func (m *MinIOAdapter) CopyLegacyBucket(masterID string) ([]*entities.Image, error) {
+
+	copied := make([]*entities.Image, 0)
+	for object := range m.client.ListObjects(context.Background(), masterID, minio.ListObjectsOptions{Recursive: true}) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+
+		dst := minio.CopyDestOptions{Bucket: m.cfg.MinIOBucket, Object: storage.MasterImageKey(masterID, object.Key)}
+		src := minio.CopySrcOptions{Bucket: masterID, Object: object.Key}
+		info, err := m.client.CopyObject(context.Background(), dst, src)
+		if err != nil {
+			return nil, err
+		}
+
+		stat, err := m.client.StatObject(context.Background(), info.Bucket, info.Key, minio.StatObjectOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		copied = append(copied, &entities.Image{
+			Name:        object.Key,
+			ObjectKey:   info.Key,
+			ContentType: stat.ContentType,
+			Size:        stat.Size,
+		})
+	}
+
+	m.logger.Infof("Bucket %s copied to %s/%s", masterID, m.cfg.MinIOBucket, storage.MasterImageKey(masterID, ""))
+	return copied, nil
+}
+
+func (m *MinIOAdapter) RemoveLegacyBucket(masterID string) error {
 
-	objects := m.client.ListObjects(context.Background(), bucketName, minio.ListObjectsOptions{})
-	for err := range m.client.RemoveObjects(context.Background(), bucketName, objects, minio.RemoveObjectsOptions{}) {
+	objects := m.client.ListObjects(context.Background(), masterID, minio.ListObjectsOptions{Recursive: true})
+	for err := range m.client.RemoveObjects(context.Background(), masterID, objects, minio.RemoveObjectsOptions{}) {
 		return err.Err
 	}
-	if err := m.client.RemoveBucket(context.Background(), bucketName); err != nil {
+	if err := m.client.RemoveBucket(context.Background(), masterID); err != nil {
 		return err
 	}
 
-	m.logger.Infof("Bucket deleted: %s", bucketName)
+	m.logger.Infof("Bucket deleted: %s", masterID)
 	return nil
 }
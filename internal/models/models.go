@@ -6,47 +6,139 @@ import (
 	"github.com/lib/pq"
 )
 
+// City names are unique ignoring case
 type City struct {
-	ID   string `gorm:"column:id;type:varchar(36);"`
-	Name string `gorm:"name"`
+	ID      string `gorm:"column:id;type:varchar(36);primaryKey"`
+	Name    string `gorm:"column:name;uniqueIndex:idx_cities_lower_name,expression:lower(name)"`
+	Version int64  `gorm:"column:version;not null;default:1"`
 }
 
+// ServiceCategory names are unique ignoring case
 type ServiceCategory struct {
-	ID   string `gorm:"column:id;type:varchar(36);"`
-	Name string `gorm:"name"`
+	ID      string `gorm:"column:id;type:varchar(36);primaryKey"`
+	Name    string `gorm:"column:name;uniqueIndex:idx_service_categories_lower_name,expression:lower(name)"`
+	Version int64  `gorm:"column:version;not null;default:1"`
 }
 
+// Service names are unique within their category, ignoring case
 type Service struct {
-	ID      string `gorm:"column:id;type:varchar(36);"`
-	Name    string `gorm:"name"`
-	CatID   string `gorm:"column:cat_id;type:varchar(36);"`
-	CatName string `gorm:"cat_name"`
-}
-
-type MasterServRelation struct {
-	ID          uint   `gorm:"primaryKey;autoIncrement;notNull"`
-	MasterID    string `gorm:"column:master_id;type:varchar(36);"`
-	Name        string `gorm:"name"`
-	Description string `gorm:"description"`
-	Contact     string `gorm:"contact"`
-	CityID      string `gorm:"column:city_id;type:varchar(36);"`
-	CityName    string `gorm:"city_name"`
-	ServCatID   string `gorm:"column:serv_cat_id;type:varchar(36);"`
-	ServCatName string `gorm:"serv_cat_name"`
-	ServID      string `gorm:"column:serv_id;type:varchar(36);"`
-	ServName    string `gorm:"serv_name"`
+	ID       string           `gorm:"column:id;type:varchar(36);primaryKey"`
+	Name     string           `gorm:"column:name;uniqueIndex:idx_services_cat_id_lower_name,priority:2,expression:lower(name)"`
+	CatID    string           `gorm:"column:cat_id;type:varchar(36);index;uniqueIndex:idx_services_cat_id_lower_name,priority:1"`
+	Version  int64            `gorm:"column:version;not null;default:1"`
+	Category *ServiceCategory `gorm:"foreignKey:CatID;constraint:OnDelete:CASCADE"`
+	// read only, selected from the categories table
+	CatName string `gorm:"column:cat_name;->;-:migration"`
 }
 
+// Master references the city and the category, deleting either of them
+// unlists the master instead of deleting it
 type Master struct {
-	ID          string         `gorm:"column:id;type:varchar(36);"`
+	ID          string           `gorm:"column:id;type:varchar(36);primaryKey"`
+	CreatedAt   time.Time        `gorm:"created_at"`
+	Name        string           `gorm:"name"`
+	Description string           `gorm:"description"`
+	Contact     string           `gorm:"contact"`
+	CityID      string           `gorm:"column:city_id;type:varchar(36);index"`
+	City        *City            `gorm:"foreignKey:CityID;constraint:OnDelete:SET NULL"`
+	ServCatID   string           `gorm:"column:serv_cat_id;type:varchar(36);index"`
+	ServCat     *ServiceCategory `gorm:"foreignKey:ServCatID;constraint:OnDelete:SET NULL"`
+	Status      uint             `gorm:"status"`
+	TelegramID  int64            `gorm:"column:telegram_id;index"`
+	ApprovedAt  *time.Time       `gorm:"approved_at"`
+	Version     int64            `gorm:"column:version;not null;default:1"`
+	// read only, selected from the related tables
+	CityName    string         `gorm:"column:city_name;->;-:migration"`
+	ServCatName string         `gorm:"column:serv_cat_name;->;-:migration"`
+	ServIDs     pq.StringArray `gorm:"column:serv_ids;type:text[];->;-:migration"`
+}
+
+// MasterService is the service selection of a master, in the master's order
+type MasterService struct {
+	MasterID  string   `gorm:"column:master_id;type:varchar(36);primaryKey"`
+	Master    *Master  `gorm:"foreignKey:MasterID;constraint:OnDelete:CASCADE"`
+	ServiceID string   `gorm:"column:service_id;type:varchar(36);primaryKey;index"`
+	Service   *Service `gorm:"foreignKey:ServiceID;constraint:OnDelete:CASCADE"`
+	Position  int      `gorm:"position"`
+}
+
+// Image changes of an approved master made by the master wait for the review of a revision:
+// a new image is pending, a replacement is pending with the name of the image it replaces,
+// a new caption of a live image is kept aside. The images are deleted with the master
+type Image struct {
+	ID          string    `gorm:"column:id;type:varchar(36);primaryKey"`
+	MasterID    string    `gorm:"column:master_id;type:varchar(36);index"`
+	Master      *Master   `gorm:"foreignKey:MasterID;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time `gorm:"created_at"`
+	ObjectKey   string    `gorm:"object_key"`
+	ContentType string    `gorm:"content_type"`
+	Size        int64     `gorm:"size"`
+	Width       int       `gorm:"width"`
+	Height      int       `gorm:"height"`
+	Position    int       `gorm:"position"`
+	Caption     string    `gorm:"caption"`
+	IsPrimary   bool      `gorm:"is_primary"`
+	Pending     bool      `gorm:"column:pending;not null;default:false"`
+	Replaces    string    `gorm:"column:replaces;type:varchar(36)"`
+	NewCaption  *string   `gorm:"column:new_caption"`
+}
+
+// Client has no default city once the city is deleted
+type Client struct {
+	TelegramID    int64     `gorm:"column:telegram_id;primaryKey;autoIncrement:false"`
+	Language      string    `gorm:"language"`
+	DefaultCityID *string   `gorm:"column:default_city_id;type:varchar(36);"`
+	DefaultCity   *City     `gorm:"foreignKey:DefaultCityID;constraint:OnDelete:SET NULL"`
+	CreatedAt     time.Time `gorm:"created_at"`
+	LastSeenAt    time.Time `gorm:"last_seen_at"`
+}
+
+// MasterRevision is deleted with the master
+type MasterRevision struct {
+	ID          string         `gorm:"column:id;type:varchar(36);primaryKey"`
+	MasterID    string         `gorm:"column:master_id;type:varchar(36);index"`
+	Master      *Master        `gorm:"foreignKey:MasterID;constraint:OnDelete:CASCADE"`
 	CreatedAt   time.Time      `gorm:"created_at"`
+	ReviewedAt  *time.Time     `gorm:"reviewed_at"`
 	Name        string         `gorm:"name"`
 	Description string         `gorm:"description"`
 	Contact     string         `gorm:"contact"`
 	CityID      string         `gorm:"column:city_id;type:varchar(36);"`
-	CityName    string         `gorm:"city_name"`
 	ServCatID   string         `gorm:"column:serv_cat_id;type:varchar(36);"`
-	ServCatName string         `gorm:"serv_cat_name"`
 	ServIDs     pq.StringArray `gorm:"column:serv_ids;type:text[];"`
 	Status      uint           `gorm:"status"`
+	// the version of the master the revision is based on, the approval fails once the master changed
+	Version int64 `gorm:"column:version"`
+	// the revision only carries the image changes, its approval keeps the profile
+	ImagesOnly bool `gorm:"column:images_only"`
+}
+
+// Favorite is deleted with the master
+type Favorite struct {
+	ClientID  int64     `gorm:"column:client_id;primaryKey;autoIncrement:false"`
+	MasterID  string    `gorm:"column:master_id;type:varchar(36);primaryKey;index"`
+	Master    *Master   `gorm:"foreignKey:MasterID;constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time `gorm:"created_at"`
+}
+
+type Event struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement;notNull"`
+	CreatedAt  time.Time `gorm:"created_at;index"`
+	Type       string    `gorm:"type"`
+	TelegramID int64     `gorm:"column:telegram_id"`
+	CityID     string    `gorm:"column:city_id;type:varchar(36);"`
+	CategoryID string    `gorm:"column:category_id;type:varchar(36);"`
+	ServiceID  string    `gorm:"column:service_id;type:varchar(36);"`
+	MasterID   string    `gorm:"column:master_id;type:varchar(36);"`
+}
+
+// EventRollup is the number of events of one type per day, city and subject
+type EventRollup struct {
+	Day        time.Time `gorm:"column:day;type:date;primaryKey"`
+	Type       string    `gorm:"column:type;primaryKey"`
+	CityID     string    `gorm:"column:city_id;type:varchar(36);primaryKey"`
+	CategoryID string    `gorm:"column:category_id;type:varchar(36);primaryKey"`
+	ServiceID  string    `gorm:"column:service_id;type:varchar(36);primaryKey"`
+	MasterID   string    `gorm:"column:master_id;type:varchar(36);primaryKey;index"`
+	Count      int64     `gorm:"count"`
 }
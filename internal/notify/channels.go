@@ -0,0 +1,122 @@
+package notify
+
+import (
+	"bot/internal/entities"
+	"bot/internal/telegram"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	Subject string                      `json:"subject"`
+	Text    string                      `json:"text"`
+	Events  []*entities.ModerationEvent `json:"events"`
+}
+
+// Channel delivers the messages to the admins, a failed send is retried by the notifier
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, message *Message) error
+}
+
+// TelegramChannel posts to the admin chat through the Bot API of the config
+type TelegramChannel struct {
+	api    *telegram.API
+	chatID int64
+}
+
+func NewTelegramChannel(api *telegram.API, chatID int64) *TelegramChannel {
+	return &TelegramChannel{api: api, chatID: chatID}
+}
+
+func (c *TelegramChannel) Name() string {
+	return "telegram"
+}
+
+func (c *TelegramChannel) Send(ctx context.Context, message *Message) error {
+	return c.api.SendMessage(c.chatID, message.Text, nil)
+}
+
+type SMTPChannel struct {
+	addr string
+	auth smtp.Auth
+	from string
+	to   []string
+}
+
+// NewSMTPChannel sends the mail without authentication if the user is empty
+func NewSMTPChannel(host string, port int64, user, password, from string, to []string) *SMTPChannel {
+
+	channel := &SMTPChannel{addr: net.JoinHostPort(host, strconv.FormatInt(port, 10)), from: from, to: to}
+	if len(user) != 0 {
+		channel.auth = smtp.PlainAuth("", user, password, host)
+	}
+	return channel
+}
+
+func (c *SMTPChannel) Name() string {
+	return "smtp"
+}
+
+func (c *SMTPChannel) Send(ctx context.Context, message *Message) error {
+
+	mail := &bytes.Buffer{}
+	fmt.Fprintf(mail, "From: %s\r\n", c.from)
+	fmt.Fprintf(mail, "To: %s\r\n", strings.Join(c.to, ", "))
+	fmt.Fprintf(mail, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", message.Subject))
+	fmt.Fprintf(mail, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprint(mail, "MIME-Version: 1.0\r\n")
+	fmt.Fprint(mail, "Content-Type: text/plain; charset=UTF-8\r\n")
+	fmt.Fprint(mail, "Content-Transfer-Encoding: 8bit\r\n\r\n")
+	fmt.Fprint(mail, strings.ReplaceAll(message.Text, "\n", "\r\n"))
+
+	return smtp.SendMail(c.addr, c.auth, c.from, c.to, mail.Bytes())
+}
+
+// WebhookChannel posts the message as JSON, any status but 2xx is a failure
+type WebhookChannel struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookChannel(url string) *WebhookChannel {
+	return &WebhookChannel{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (c *WebhookChannel) Name() string {
+	return "webhook"
+}
+
+func (c *WebhookChannel) Send(ctx context.Context, message *Message) error {
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package notify
+
+import (
+	"bot/internal/entities"
+	"bot/internal/telegram"
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var testMessage = &Message{
+	Subject: "Новый мастер",
+	Text:    "New master registered: Anna\nNew master registered: Olga",
+	Events: []*entities.ModerationEvent{
+		{ID: 1, Type: entities.MasterCreated, MasterID: "m1", MasterName: "Anna"},
+		{ID: 2, Type: entities.MasterCreated, MasterID: "m2", MasterName: "Olga"},
+	},
+}
+
+func TestTelegramChannel(t *testing.T) {
+
+	var params map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/bot123:token/sendMessage" {
+			http.NotFound(rw, req)
+			return
+		}
+		json.NewDecoder(req.Body).Decode(&params)
+		rw.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	channel := NewTelegramChannel(telegram.NewAPI(srv.URL, "123:token"), -100)
+	if err := channel.Send(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+	if params["chat_id"] != float64(-100) || params["text"] != testMessage.Text {
+		t.Errorf("got %v", params)
+	}
+}
+
+func TestWebhookChannel(t *testing.T) {
+
+	status := http.StatusNoContent
+	var received *Message
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got content type %s", req.Header.Get("Content-Type"))
+		}
+		received = &Message{}
+		json.NewDecoder(req.Body).Decode(received)
+		rw.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	channel := NewWebhookChannel(srv.URL)
+	if err := channel.Send(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+	if received == nil || received.Subject != testMessage.Subject || len(received.Events) != 2 || received.Events[1].MasterID != "m2" {
+		t.Errorf("got %+v", received)
+	}
+
+	status = http.StatusBadGateway
+	if err := channel.Send(context.Background(), testMessage); err == nil {
+		t.Error("a 502 response is not a failure")
+	}
+}
+
+// smtpSession is what the fake SMTP server was told
+type smtpSession struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// serveSMTP answers one session with the commands net/smtp sends, AUTH PLAIN is offered
+func serveSMTP(t *testing.T, listener net.Listener, session chan<- *smtpSession) {
+
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(lines ...string) {
+		io.WriteString(conn, strings.Join(lines, "\r\n")+"\r\n")
+	}
+
+	received := &smtpSession{}
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Errorf("SMTP: %s", err)
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch command {
+		case "EHLO":
+			reply("250-localhost", "250-8BITMIME", "250 AUTH PLAIN")
+		case "AUTH":
+			received.auth = line
+			reply("235 2.7.0 Authentication successful")
+		case "MAIL":
+			received.from = line
+			reply("250 OK")
+		case "RCPT":
+			received.to = append(received.to, line)
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			data := &strings.Builder{}
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					t.Errorf("SMTP DATA: %s", err)
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			received.data = data.String()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			session <- received
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestSMTPChannel(t *testing.T) {
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	session := make(chan *smtpSession, 1)
+	go serveSMTP(t, listener, session)
+
+	host, portText, _ := net.SplitHostPort(listener.Addr().String())
+	port, _ := strconv.ParseInt(portText, 10, 64)
+	channel := NewSMTPChannel(host, port, "bot", "secret", "bot@example.com", []string{"admin@example.com", "moderator@example.com"})
+	if err := channel.Send(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+	received := <-session
+
+	credentials, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(received.auth, "AUTH PLAIN "))
+	if string(credentials) != "\x00bot\x00secret" {
+		t.Errorf("got the credentials %q", credentials)
+	}
+	if !strings.HasPrefix(received.from, "MAIL FROM:<bot@example.com>") || len(received.to) != 2 {
+		t.Errorf("got the envelope %q %q", received.from, received.to)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(received.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := (&mime.WordDecoder{}).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != testMessage.Subject {
+		t.Errorf("got the subject %q, %v", subject, err)
+	}
+	if parsed.Header.Get("To") != "admin@example.com, moderator@example.com" {
+		t.Errorf("got the recipients %q", parsed.Header.Get("To"))
+	}
+	body, _ := io.ReadAll(parsed.Body)
+	if string(body) != strings.ReplaceAll(testMessage.Text, "\n", "\r\n")+"\r\n" {
+		t.Errorf("got the body %q", body)
+	}
+}
@@ -0,0 +1,212 @@
+// Package notify tells the admins about the new registrations and the status changes of the masters.
+// It follows the moderation events of DBAdapter and delivers the messages over the configured channels,
+// each channel retries on its own, so a failing one doesn't hold the others back
+package notify
+
+import (
+	"bot/internal/config"
+	"bot/internal/dbadapter"
+	"bot/internal/entities"
+	"bot/internal/logger"
+	"bot/internal/telegram"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// the messages a channel may lag behind before the new ones are dropped
+const queueSize = 100
+
+var statusNames = map[uint]string{
+	entities.PENDING:  "pending",
+	entities.APPROVED: "approved",
+	entities.DECLINED: "declined",
+}
+
+// subscribeFunc follows the moderation events after lastID, see DBAdapter.SubscribeUpdates
+type subscribeFunc func(lastID int64) ([]*entities.ModerationEvent, <-chan *entities.ModerationEvent, func())
+
+type Notifier struct {
+	logger     logger.Logger
+	cfg        *config.Config
+	subscribe  subscribeFunc
+	channels   []Channel
+	templates  map[string]*template.Template
+	retryDelay time.Duration
+	digest     time.Duration
+}
+
+// NewNotifier builds the channels that have a destination in the config, it fails on a broken template
+func NewNotifier(logger logger.Logger, cfg *config.Config, DBAdapter *dbadapter.DBAdapter) (*Notifier, error) {
+
+	templates := make(map[string]*template.Template)
+	funcs := template.FuncMap{"status": func(status uint) string { return statusNames[status] }}
+	for eventType, text := range map[string]string{
+		entities.MasterCreated:       cfg.CreatedTemplate,
+		entities.MasterStatusChanged: cfg.StatusTemplate,
+	} {
+		parsed, err := template.New(eventType).Funcs(funcs).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("template of %s: %w", eventType, err)
+		}
+		templates[eventType] = parsed
+	}
+
+	channels := make([]Channel, 0)
+	if cfg.NotifyChatID != 0 && len(cfg.TelegramToken) != 0 {
+		channels = append(channels, NewTelegramChannel(telegram.NewAPI(cfg.TelegramAPIURL, cfg.TelegramToken), cfg.NotifyChatID))
+	}
+	if len(cfg.SMTPHost) != 0 && len(cfg.SMTPTo) != 0 {
+		channels = append(channels, NewSMTPChannel(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPass, cfg.SMTPFrom, cfg.SMTPTo))
+	}
+	if len(cfg.NotifyWebhookURL) != 0 {
+		channels = append(channels, NewWebhookChannel(cfg.NotifyWebhookURL))
+	}
+
+	return &Notifier{
+		logger:     logger,
+		cfg:        cfg,
+		subscribe:  DBAdapter.SubscribeUpdates,
+		channels:   channels,
+		templates:  templates,
+		retryDelay: time.Duration(cfg.NotifyRetryDelay) * time.Second,
+		digest:     time.Duration(cfg.DigestInterval) * time.Second,
+	}, nil
+}
+
+// Run delivers the notifications until the context is done. With a digest interval the events
+// are collected and sent as one message per interval
+func (n *Notifier) Run(ctx context.Context) {
+
+	if len(n.channels) == 0 {
+		return
+	}
+
+	queues := make([]chan *Message, 0)
+	for _, channel := range n.channels {
+		queue := make(chan *Message, queueSize)
+		queues = append(queues, queue)
+		go n.deliver(ctx, channel, queue)
+	}
+
+	var digest <-chan time.Time
+	if n.digest > 0 {
+		ticker := time.NewTicker(n.digest)
+		defer ticker.Stop()
+		digest = ticker.C
+	}
+
+	pending := make([]*entities.ModerationEvent, 0)
+	send := func(events []*entities.ModerationEvent) {
+		message := n.message(events)
+		for index, queue := range queues {
+			select {
+			case queue <- message:
+			default:
+				n.logger.Errorf("notify::Run: the %s queue is full, the message is dropped", n.channels[index].Name())
+			}
+		}
+	}
+
+	var lastID int64
+	for {
+		// the subscription is dropped when the notifier falls behind, it resumes after the last event
+		missed, events, cancel := n.subscribe(lastID)
+		for _, event := range missed {
+			lastID = event.ID
+			if n.notifies(event) {
+				pending = append(pending, event)
+			}
+		}
+
+		for open := true; open; {
+			if digest == nil && len(pending) != 0 {
+				send(pending)
+				pending = make([]*entities.ModerationEvent, 0)
+			}
+
+			select {
+			case <-ctx.Done():
+				cancel()
+				return
+			case event, ok := <-events:
+				if !ok {
+					open = false
+					break
+				}
+				lastID = event.ID
+				if n.notifies(event) {
+					pending = append(pending, event)
+				}
+			case <-digest:
+				if len(pending) != 0 {
+					send(pending)
+					pending = make([]*entities.ModerationEvent, 0)
+				}
+			}
+		}
+		cancel()
+	}
+}
+
+func (n *Notifier) notifies(event *entities.ModerationEvent) bool {
+	_, ok := n.templates[event.Type]
+	return ok
+}
+
+// message renders the events, a failed template leaves the event type in the text
+func (n *Notifier) message(events []*entities.ModerationEvent) *Message {
+
+	lines := make([]string, 0)
+	for _, event := range events {
+		text := &strings.Builder{}
+		if err := n.templates[event.Type].Execute(text, event); err != nil {
+			n.logger.Error("notify::message::Execute", err)
+			text.Reset()
+			fmt.Fprintf(text, "%s: %s", event.Type, event.MasterName)
+		}
+		lines = append(lines, text.String())
+	}
+
+	message := &Message{Text: strings.Join(lines, "\n"), Events: events}
+	switch {
+	case len(events) > 1:
+		message.Subject = fmt.Sprintf("%d moderation updates", len(events))
+	case events[0].Type == entities.MasterCreated:
+		message.Subject = "New master registration"
+	default:
+		message.Subject = "Master status changed"
+	}
+	return message
+}
+
+// deliver sends the messages of the channel one by one, the delay doubles with every retry
+func (n *Notifier) deliver(ctx context.Context, channel Channel, queue <-chan *Message) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message := <-queue:
+			delay := n.retryDelay
+			for attempt := 0; ; attempt++ {
+				err := channel.Send(ctx, message)
+				if err == nil {
+					break
+				}
+				n.logger.Errorf("notify::deliver::%s: attempt %d: %s", channel.Name(), attempt+1, err)
+				if attempt >= int(n.cfg.NotifyRetries) {
+					break
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(delay):
+				}
+				delay *= 2
+			}
+		}
+	}
+}
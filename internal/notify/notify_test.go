@@ -0,0 +1,200 @@
+package notify
+
+import (
+	"bot/internal/broker"
+	"bot/internal/config"
+	"bot/internal/entities"
+	"bot/internal/logger"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeChannel fails the messages that fail returns true for and reports every attempt
+type fakeChannel struct {
+	name     string
+	fail     func(message *Message, attempt int) bool
+	mu       sync.Mutex
+	attempts map[*Message][]time.Time
+	sent     chan *Message
+}
+
+func newFakeChannel(name string, fail func(message *Message, attempt int) bool) *fakeChannel {
+	return &fakeChannel{name: name, fail: fail, attempts: make(map[*Message][]time.Time), sent: make(chan *Message, 10)}
+}
+
+func (c *fakeChannel) Name() string {
+	return c.name
+}
+
+func (c *fakeChannel) Send(ctx context.Context, message *Message) error {
+
+	c.mu.Lock()
+	c.attempts[message] = append(c.attempts[message], time.Now())
+	attempt := len(c.attempts[message])
+	c.mu.Unlock()
+
+	if c.fail != nil && c.fail(message, attempt) {
+		return errors.New("unavailable")
+	}
+	c.sent <- message
+	return nil
+}
+
+func (c *fakeChannel) attemptTimes(message *Message) []time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.attempts[message]
+}
+
+func (c *fakeChannel) next(t *testing.T) *Message {
+	t.Helper()
+	select {
+	case message := <-c.sent:
+		return message
+	case <-time.After(2 * time.Second):
+		t.Fatalf("%s got no message", c.name)
+		return nil
+	}
+}
+
+func (c *fakeChannel) none(t *testing.T, wait time.Duration) {
+	t.Helper()
+	select {
+	case message := <-c.sent:
+		t.Fatalf("%s got an unexpected message %q", c.name, message.Text)
+	case <-time.After(wait):
+	}
+}
+
+// startNotifier runs the notifier over a broker instead of the database, it returns once the notifier is subscribed
+func startNotifier(t *testing.T, cfg *config.Config, digest time.Duration, channels ...Channel) *broker.Broker {
+
+	cfg.CreatedTemplate = "created {{.MasterName}}"
+	cfg.StatusTemplate = "{{.MasterName}} is {{status .Status}}"
+	notifier, err := NewNotifier(logger.NewLogger(), cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := broker.New(10)
+	subscribed := make(chan struct{}, 1)
+	notifier.subscribe = func(lastID int64) ([]*entities.ModerationEvent, <-chan *entities.ModerationEvent, func()) {
+		defer func() { subscribed <- struct{}{} }()
+		return events.Subscribe(lastID)
+	}
+	notifier.channels = channels
+	notifier.retryDelay = 20 * time.Millisecond
+	notifier.digest = digest
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go notifier.Run(ctx)
+	<-subscribed
+	return events
+}
+
+func TestNotifierSendsEachEvent(t *testing.T) {
+
+	channel := newFakeChannel("fake", nil)
+	events := startNotifier(t, &config.Config{}, 0, channel)
+
+	events.Publish(&entities.ModerationEvent{Type: entities.MasterCreated, MasterID: "m1", MasterName: "Anna", Status: entities.PENDING})
+	events.Publish(&entities.ModerationEvent{Type: entities.ImageUploaded, MasterID: "m1"})
+	events.Publish(&entities.ModerationEvent{Type: entities.MasterStatusChanged, MasterID: "m2", MasterName: "Olga", Status: entities.APPROVED})
+
+	first := channel.next(t)
+	if first.Text != "created Anna" || first.Subject != "New master registration" {
+		t.Errorf("got %q %q", first.Subject, first.Text)
+	}
+	second := channel.next(t)
+	if second.Text != "Olga is approved" || second.Subject != "Master status changed" {
+		t.Errorf("got %q %q", second.Subject, second.Text)
+	}
+	// the image is not a moderation decision, it is not sent
+	channel.none(t, 100*time.Millisecond)
+}
+
+func TestNotifierRetries(t *testing.T) {
+
+	// the first message fails twice, the third attempt goes through
+	channel := newFakeChannel("fake", func(message *Message, attempt int) bool { return attempt < 3 })
+	events := startNotifier(t, &config.Config{NotifyRetries: 3}, 0, channel)
+
+	events.Publish(&entities.ModerationEvent{Type: entities.MasterCreated, MasterID: "m1", MasterName: "Anna"})
+	message := channel.next(t)
+
+	attempts := channel.attemptTimes(message)
+	if len(attempts) != 3 {
+		t.Fatalf("got %d attempts, want 3", len(attempts))
+	}
+	// the delay doubles with every retry
+	if first := attempts[1].Sub(attempts[0]); first < 20*time.Millisecond {
+		t.Errorf("the first retry came after %s", first)
+	}
+	if second := attempts[2].Sub(attempts[1]); second < 40*time.Millisecond {
+		t.Errorf("the second retry came after %s", second)
+	}
+}
+
+func TestNotifierGivesUp(t *testing.T) {
+
+	channel := newFakeChannel("fake", func(message *Message, attempt int) bool { return message.Events[0].MasterName == "Anna" })
+	events := startNotifier(t, &config.Config{NotifyRetries: 2}, 0, channel)
+
+	events.Publish(&entities.ModerationEvent{Type: entities.MasterCreated, MasterID: "m1", MasterName: "Anna"})
+	events.Publish(&entities.ModerationEvent{Type: entities.MasterCreated, MasterID: "m2", MasterName: "Olga"})
+
+	// the failed message is dropped after the retries and the next one is delivered
+	if message := channel.next(t); message.Text != "created Olga" {
+		t.Fatalf("got %q", message.Text)
+	}
+
+	channel.mu.Lock()
+	defer channel.mu.Unlock()
+	for message, attempts := range channel.attempts {
+		if message.Events[0].MasterName == "Anna" && len(attempts) != 3 {
+			t.Errorf("got %d attempts, want 3", len(attempts))
+		}
+	}
+}
+
+func TestNotifierFailingChannelDoesNotBlock(t *testing.T) {
+
+	broken := newFakeChannel("broken", func(message *Message, attempt int) bool { return true })
+	working := newFakeChannel("working", nil)
+	events := startNotifier(t, &config.Config{NotifyRetries: 100}, 0, broken, working)
+
+	events.Publish(&entities.ModerationEvent{Type: entities.MasterCreated, MasterID: "m1", MasterName: "Anna"})
+	events.Publish(&entities.ModerationEvent{Type: entities.MasterCreated, MasterID: "m2", MasterName: "Olga"})
+
+	if message := working.next(t); message.Text != "created Anna" {
+		t.Errorf("got %q", message.Text)
+	}
+	if message := working.next(t); message.Text != "created Olga" {
+		t.Errorf("got %q", message.Text)
+	}
+}
+
+func TestNotifierDigest(t *testing.T) {
+
+	channel := newFakeChannel("fake", nil)
+	events := startNotifier(t, &config.Config{}, 200*time.Millisecond, channel)
+
+	events.Publish(&entities.ModerationEvent{Type: entities.MasterCreated, MasterID: "m1", MasterName: "Anna"})
+	events.Publish(&entities.ModerationEvent{Type: entities.RevisionCreated, MasterID: "m1"})
+	events.Publish(&entities.ModerationEvent{Type: entities.MasterStatusChanged, MasterID: "m2", MasterName: "Olga", Status: entities.DECLINED})
+
+	message := channel.next(t)
+	if len(message.Events) != 2 || message.Subject != "2 moderation updates" {
+		t.Fatalf("got %d events %q", len(message.Events), message.Subject)
+	}
+	if message.Text != "created Anna\nOlga is declined" {
+		t.Errorf("got %q", message.Text)
+	}
+
+	// an interval without events sends nothing
+	channel.none(t, 300*time.Millisecond)
+}
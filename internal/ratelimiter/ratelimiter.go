@@ -0,0 +1,83 @@
+package ratelimiter
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type Limit struct {
+	Rate  float64 // tokens added per second
+	Burst int64   // bucket capacity
+}
+
+// Store keeps token buckets by key. MemoryStore is used by default,
+// a shared backend (e.g. Redis) can be plugged in by implementing it.
+type Store interface {
+	Take(key string, limit Limit) (allowed bool, retryAfter time.Duration, err error)
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	ttl       time.Duration
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		buckets:   make(map[string]*bucket),
+		ttl:       ttl,
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), lastSeen: now}
+		s.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.lastSeen).Seconds()
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+	b.lastSeen = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+
+	if limit.Rate <= 0 {
+		return false, s.ttl, nil
+	}
+	wait := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	return false, wait, nil
+}
+
+// sweep drops buckets that were not touched for ttl, a bucket idle for
+// that long would be refilled anyway
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.ttl {
+		return
+	}
+	for key, b := range s.buckets {
+		if now.Sub(b.lastSeen) >= s.ttl {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
+	"gorm.io/gorm"
 )
 
 // @Summary Delete city
@@ -14,6 +17,7 @@ import (
 // @Produce json
 // @Success 200
 // @Failure 400 {string} string "Error message"
+// @Failure 404 {string} string "Error message"
 // @Failure 500 {string} string "Error message"
 // @Router /cities/{city_id} [delete]
 func (h *Handler) DeleteCity(rw http.ResponseWriter, req *http.Request) {
@@ -23,6 +27,10 @@ func (h *Handler) DeleteCity(rw http.ResponseWriter, req *http.Request) {
 
 	if err := h.DBAdapter.DeleteCity(params["city_id"]); err != nil {
 		h.logger.Errorf("server::DeleteCity::DeleteCity: %s", err.Error())
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -38,6 +46,7 @@ func (h *Handler) DeleteCity(rw http.ResponseWriter, req *http.Request) {
 // @Produce json
 // @Success 200
 // @Failure 400 {string} string "Error message"
+// @Failure 404 {string} string "Error message"
 // @Failure 500 {string} string "Error message"
 // @Router /services/categories/{category_id} [delete]
 func (h *Handler) DeleteServCategory(rw http.ResponseWriter, req *http.Request) {
@@ -47,6 +56,10 @@ func (h *Handler) DeleteServCategory(rw http.ResponseWriter, req *http.Request)
 
 	if err := h.DBAdapter.DeleteServCategory(params["category_id"]); err != nil {
 		h.logger.Errorf("server::DeleteServCategory::DeleteServCategory: %s", err.Error())
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -62,6 +75,7 @@ func (h *Handler) DeleteServCategory(rw http.ResponseWriter, req *http.Request)
 // @Produce json
 // @Success 200
 // @Failure 400 {string} string "Error message"
+// @Failure 404 {string} string "Error message"
 // @Failure 500 {string} string "Error message"
 // @Router /services/{service_id} [delete]
 func (h *Handler) DeleteService(rw http.ResponseWriter, req *http.Request) {
@@ -71,6 +85,10 @@ func (h *Handler) DeleteService(rw http.ResponseWriter, req *http.Request) {
 
 	if err := h.DBAdapter.DeleteService(params["service_id"]); err != nil {
 		h.logger.Errorf("server::DeleteService::DeleteService: %s", err.Error())
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -86,6 +104,7 @@ func (h *Handler) DeleteService(rw http.ResponseWriter, req *http.Request) {
 // @Produce json
 // @Success 200
 // @Failure 400 {string} string "Error message"
+// @Failure 404 {string} string "Error message"
 // @Failure 500 {string} string "Error message"
 // @Router /masters/{master_id} [delete]
 func (h *Handler) DeleteMaster(rw http.ResponseWriter, req *http.Request) {
@@ -96,12 +115,16 @@ func (h *Handler) DeleteMaster(rw http.ResponseWriter, req *http.Request) {
 
 	if err := h.DBAdapter.DeleteMaster(masterID); err != nil {
 		h.logger.Errorf("server::DeleteMaster::DeleteMaster: %s", err.Error())
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	if err := h.MinIOAdapter.DeleteMasterImages(masterID); err != nil {
-		h.logger.Errorf("server::DeleteMaster::DeleteBucket: %s", err.Error())
+	if err := h.ImageStorage.DeleteMasterImages(masterID); err != nil {
+		h.logger.Errorf("server::DeleteMaster::DeleteMasterImages: %s", err.Error())
 	}
 
 	rw.WriteHeader(http.StatusOK)
@@ -113,11 +136,14 @@ func (h *Handler) DeleteMaster(rw http.ResponseWriter, req *http.Request) {
 // @Tags Master
 // @Param master_id path string true "ID of the master"
 // @Param image_name path string true "Name of the image"
+// @Param X-Telegram-User-ID header int false "Telegram user ID, selects the master on the /masters/self routes"
 // @Accept json
 // @Produce json
 // @Success 200
+// @Failure 404 {string} string "Error message"
 // @Failure 500 {string} string "Error message"
 // @Router /masters/{master_id}/images/{image_name} [delete]
+// @Router /masters/self/images/{image_name} [delete]
 func (h *Handler) DeleteMasterImage(rw http.ResponseWriter, req *http.Request) {
 	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
 
@@ -125,11 +151,63 @@ func (h *Handler) DeleteMasterImage(rw http.ResponseWriter, req *http.Request) {
 	masterID := params["master_id"]
 	imageName := params["image_name"]
 
-	if err := h.MinIOAdapter.DeleteMasterImage(masterID, imageName); err != nil {
+	objectKey, err := h.DBAdapter.DeleteMasterImage(masterID, imageName)
+	if err != nil {
 		h.logger.Errorf("server::DeleteMasterImage::DeleteMasterImage: %s", err.Error())
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.ImageStorage.DeleteMasterImage(objectKey); err != nil {
+		h.logger.Errorf("server::DeleteMasterImage::DeleteMasterImage: %s", err.Error())
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	rw.WriteHeader(http.StatusOK)
 	h.logger.Info("Response sent")
 }
+
+// @Summary Remove master from favorites
+// @Description Remove the master from the client favorites. Used by bot.
+// @Tags Client
+// @Param telegram_id path int true "Telegram user ID"
+// @Param X-Telegram-User-ID header int true "Telegram user ID the bot acts for, must match telegram_id"
+// @Param master_id path string true "ID of the master"
+// @Accept json
+// @Produce json
+// @Success 204
+// @Failure 400 {string} string "Error message"
+// @Failure 401 {string} string "Error message"
+// @Failure 403 {string} string "Error message"
+// @Failure 404 {string} string "Error message"
+// @Failure 500 {string} string "Error message"
+// @Router /clients/{telegram_id}/favorites/{master_id} [delete]
+func (h *Handler) DeleteFavorite(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	params := mux.Vars(req)
+	telegramID, err := strconv.ParseInt(params["telegram_id"], 10, 64)
+	if err != nil {
+		h.logger.Error("server::DeleteFavorite::ParseInt", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DBAdapter.DeleteFavorite(telegramID, params["master_id"]); err != nil {
+		h.logger.Error("server::DeleteFavorite::DeleteFavorite", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+	h.logger.Info("Response sent")
+}
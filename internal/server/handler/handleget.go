@@ -1,10 +1,19 @@
 package handler
 
 import (
+	"bot/internal/catalog"
+	"bot/internal/dbadapter"
+	"bot/internal/entities"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"gorm.io/gorm"
 )
 
 // @Summary Get cities
@@ -12,9 +21,12 @@ import (
 // @Tags City
 // @Param page query int false "Page number for pagination"
 // @Param limit query int false "Limit of items for pagination"
+// @Param If-None-Match header string false "ETag of a previously received response"
 // @Accept json
 // @Produce json
 // @Success 200 {array} entities.City
+// @Success 304 "Not modified"
+// @Header 200 {string} ETag "Hash of the list, for caching only, If-Match doesn't take it"
 // @Failure 500 {string} string "Error message"
 // @Router /cities [get]
 func (h *Handler) GetCities(rw http.ResponseWriter, req *http.Request) {
@@ -49,9 +61,7 @@ func (h *Handler) GetCities(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	rw.Header().Set("Content-Type", "application/json")
-	rw.WriteHeader(http.StatusOK)
-	if _, err := rw.Write(cityList); err != nil {
+	if err := h.writeCached(rw, req, cityList); err != nil {
 		h.logger.Error("server::GetCities::Write", err)
 		return
 	}
@@ -63,9 +73,12 @@ func (h *Handler) GetCities(rw http.ResponseWriter, req *http.Request) {
 // @Tags Service
 // @Param page query int false "Page number for pagination"
 // @Param limit query int false "Limit of items for pagination"
-// @Acept json
+// @Param If-None-Match header string false "ETag of a previously received response"
+// @Accept json
 // @Produce json
 // @Success 200 {array} entities.ServiceCategory
+// @Success 304 "Not modified"
+// @Header 200 {string} ETag "Hash of the list, for caching only, If-Match doesn't take it"
 // @Failure 500 {string} string "Error message"
 // @Router /services/categories [get]
 func (h *Handler) GetServiceCategories(rw http.ResponseWriter, req *http.Request) {
@@ -100,9 +113,7 @@ func (h *Handler) GetServiceCategories(rw http.ResponseWriter, req *http.Request
 		return
 	}
 
-	rw.Header().Set("Content-Type", "application/json")
-	rw.WriteHeader(http.StatusOK)
-	if _, err := rw.Write(categoryList); err != nil {
+	if err := h.writeCached(rw, req, categoryList); err != nil {
 		h.logger.Error("server::GetServiceCategories::Write", err)
 		return
 	}
@@ -115,9 +126,12 @@ func (h *Handler) GetServiceCategories(rw http.ResponseWriter, req *http.Request
 // @Param page query int false "Page number for pagination"
 // @Param limit query int false "Limit of items for pagination"
 // @Param category_id query string false "ID of the service category"
+// @Param If-None-Match header string false "ETag of a previously received response"
 // @Accept json
 // @Produce json
 // @Success 200 {array} entities.Service
+// @Success 304 "Not modified"
+// @Header 200 {string} ETag "Hash of the list, for caching only, If-Match doesn't take it"
 // @Failure 500 {string} string "Error message"
 // @Router /services [get]
 func (h *Handler) GetServices(rw http.ResponseWriter, req *http.Request) {
@@ -152,9 +166,7 @@ func (h *Handler) GetServices(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	rw.Header().Set("Content-Type", "application/json")
-	rw.WriteHeader(http.StatusOK)
-	if _, err := rw.Write(serviceList); err != nil {
+	if err := h.writeCached(rw, req, serviceList); err != nil {
 		h.logger.Error("server::GetServices::Write", err)
 		return
 	}
@@ -168,9 +180,13 @@ func (h *Handler) GetServices(rw http.ResponseWriter, req *http.Request) {
 // @Param limit query int false "Limit of items for pagination"
 // @Param city_id query string false "ID of the selected city"
 // @Param service_id query string false "ID of the seleted service"
+// @Param order query string false "popular - the most viewed in the city first"
+// @Param If-None-Match header string false "ETag of a previously received response"
 // @Accept json
 // @Produce json
 // @Success 200 {array} entities.MasterShort
+// @Success 304 "Not modified"
+// @Header 200 {string} ETag "Hash of the list, for caching only, If-Match doesn't take it"
 // @Failure 400 {string} string "Error message"
 // @Failure 500 {string} string "Error message"
 // @Router /masters/bot [get]
@@ -192,15 +208,23 @@ func (h *Handler) GetMastersBot(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	masters, err := h.DBAdapter.GetMastersBot(query.Get("city_id"), "", query.Get("service_id"), page, limit)
+	order := query.Get("order")
+	if len(order) != 0 && order != dbadapter.PopularOrder {
+		http.Error(rw, "unknown order: "+order, http.StatusBadRequest)
+		return
+	}
+
+	masters, err := h.DBAdapter.GetMastersBot(query.Get("city_id"), "", query.Get("service_id"), order, page, limit)
 	if err != nil {
 		h.logger.Error("server::GetMastersBot::GetMastersBot", err)
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	for index, _ := range masters {
-		masters[index].Images = h.MinIOAdapter.GetMasterImagesURLs(masters[index].ID)
+	if err := h.fillMasters(masters); err != nil {
+		h.logger.Error("server::GetMastersBot::fillMasters", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	mastersResp, err := json.Marshal(masters)
@@ -210,8 +234,7 @@ func (h *Handler) GetMastersBot(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	rw.WriteHeader(http.StatusOK)
-	if _, err := rw.Write(mastersResp); err != nil {
+	if err := h.writeCached(rw, req, mastersResp); err != nil {
 		h.logger.Error("server::GetMasters::Write", err)
 		return
 	}
@@ -223,6 +246,13 @@ func (h *Handler) GetMastersBot(rw http.ResponseWriter, req *http.Request) {
 // @Tags Master
 // @Param page query int false "Page number for pagination"
 // @Param limit query int false "Limit of items for pagination"
+// @Param status query int false "Master status: 1 - pending, 2 - approved, 3 - declined"
+// @Param city_id query string false "ID of the city"
+// @Param category_id query string false "ID of the service category"
+// @Param from query string false "Registered on or after the date, YYYY-MM-DD"
+// @Param to query string false "Registered on or before the date, YYYY-MM-DD"
+// @Param q query string false "Text to search in the name and the contact"
+// @Param sort query string false "date, -date, name or -name, date by default"
 // @Accept json
 // @Produce json
 // @Success 200 {array} entities.MasterShort
@@ -247,13 +277,59 @@ func (h *Handler) GetMastersAdmin(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	masters, err := h.DBAdapter.GetMastersAdmin(page, limit)
+	status, err := getParam[uint](query.Get("status"), 0)
+	if err != nil {
+		h.logger.Error("server::GetMastersAdmin::getParam[uint]", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := &entities.MastersFilter{
+		Status:    status,
+		CityID:    query.Get("city_id"),
+		ServCatID: query.Get("category_id"),
+		Search:    strings.TrimSpace(query.Get("q")),
+		Sort:      query.Get("sort"),
+	}
+
+	switch filter.Sort {
+	case "", entities.SortByDate, entities.SortByDateDesc, entities.SortByName, entities.SortByNameDesc:
+	default:
+		http.Error(rw, "unknown sort: "+filter.Sort, http.StatusBadRequest)
+		return
+	}
+
+	if from := query.Get("from"); len(from) != 0 {
+		if filter.From, err = time.ParseInLocation("2006-01-02", from, time.Local); err != nil {
+			h.logger.Error("server::GetMastersAdmin::ParseInLocation", err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// the end date is inclusive
+	if to := query.Get("to"); len(to) != 0 {
+		if filter.To, err = time.ParseInLocation("2006-01-02", to, time.Local); err != nil {
+			h.logger.Error("server::GetMastersAdmin::ParseInLocation", err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.To = filter.To.AddDate(0, 0, 1)
+	}
+
+	masters, err := h.DBAdapter.GetMastersAdmin(filter, page, limit)
 	if err != nil {
 		h.logger.Error("server::GetMastersAdmin::GetMastersAdmin", err)
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if err := h.fillMasters(masters); err != nil {
+		h.logger.Error("server::GetMastersAdmin::fillMasters", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	mastersResp, err := json.Marshal(masters)
 	if err != nil {
 		h.logger.Error("server::GetMastersAdmin::Marshal", err)
@@ -269,16 +345,148 @@ func (h *Handler) GetMastersAdmin(rw http.ResponseWriter, req *http.Request) {
 	h.logger.Info("Response sent")
 }
 
+// @Summary Get city
+// @Description Get the city by the given ID
+// @Tags City
+// @Param city_id path string true "ID of the city"
+// @Param If-None-Match header string false "ETag of a previously received response"
+// @Accept json
+// @Produce json
+// @Success 200 {object} entities.City
+// @Success 304 "Not modified"
+// @Header 200 {string} ETag "Version of the city"
+// @Failure 404 {string} string "Error message"
+// @Failure 500 {string} string "Error message"
+// @Router /cities/{city_id} [get]
+func (h *Handler) GetCity(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	params := mux.Vars(req)
+
+	city, err := h.DBAdapter.GetCity(params["city_id"])
+	if err != nil {
+		h.logger.Error("server::GetCity::GetCity", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	cityResp, err := json.Marshal(city)
+	if err != nil {
+		h.logger.Error("server::GetCity::Marshal", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := writeVersioned(rw, req, cityResp, city.Version); err != nil {
+		h.logger.Error("server::GetCity::Write", err)
+		return
+	}
+	h.logger.Info("Response sent")
+}
+
+// @Summary Get service category
+// @Description Get the service category by the given ID
+// @Tags Service
+// @Param category_id path string true "ID of the service category"
+// @Param If-None-Match header string false "ETag of a previously received response"
+// @Accept json
+// @Produce json
+// @Success 200 {object} entities.ServiceCategory
+// @Success 304 "Not modified"
+// @Header 200 {string} ETag "Version of the service category"
+// @Failure 404 {string} string "Error message"
+// @Failure 500 {string} string "Error message"
+// @Router /services/categories/{category_id} [get]
+func (h *Handler) GetServCategory(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	params := mux.Vars(req)
+
+	category, err := h.DBAdapter.GetServCategory(params["category_id"])
+	if err != nil {
+		h.logger.Error("server::GetServCategory::GetServCategory", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	categoryResp, err := json.Marshal(category)
+	if err != nil {
+		h.logger.Error("server::GetServCategory::Marshal", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := writeVersioned(rw, req, categoryResp, category.Version); err != nil {
+		h.logger.Error("server::GetServCategory::Write", err)
+		return
+	}
+	h.logger.Info("Response sent")
+}
+
+// @Summary Get service
+// @Description Get the service by the given ID
+// @Tags Service
+// @Param service_id path string true "ID of the service"
+// @Param If-None-Match header string false "ETag of a previously received response"
+// @Accept json
+// @Produce json
+// @Success 200 {object} entities.Service
+// @Success 304 "Not modified"
+// @Header 200 {string} ETag "Version of the service"
+// @Failure 404 {string} string "Error message"
+// @Failure 500 {string} string "Error message"
+// @Router /services/{service_id} [get]
+func (h *Handler) GetService(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	params := mux.Vars(req)
+
+	service, err := h.DBAdapter.GetService(params["service_id"])
+	if err != nil {
+		h.logger.Error("server::GetService::GetService", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	serviceResp, err := json.Marshal(service)
+	if err != nil {
+		h.logger.Error("server::GetService::Marshal", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := writeVersioned(rw, req, serviceResp, service.Version); err != nil {
+		h.logger.Error("server::GetService::Write", err)
+		return
+	}
+	h.logger.Info("Response sent")
+}
+
 // @Summary Get master
 // @Description Get the master by the given ID
 // @Tags Master
 // @Param master_id path string true "ID of the master"
+// @Param X-Telegram-User-ID header int false "Telegram user ID, selects the master on the /masters/self routes"
 // @Accept json
 // @Produce json
 // @Success 200 {object} entities.MasterLong
+// @Header 200 {string} ETag "Version of the master"
 // @Failure 400 {string} string "Error message"
 // @Failure 500 {string} string "Error message"
 // @Router /masters/{master_id} [get]
+// @Router /masters/self [get]
 func (h *Handler) GetMaster(rw http.ResponseWriter, req *http.Request) {
 	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
 
@@ -299,8 +507,7 @@ func (h *Handler) GetMaster(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	rw.WriteHeader(http.StatusOK)
-	if _, err := rw.Write(masterResp); err != nil {
+	if err := writeVersioned(rw, req, masterResp, master.Version); err != nil {
 		h.logger.Error("server::GetMaster::Write", err)
 		return
 	}
@@ -308,23 +515,43 @@ func (h *Handler) GetMaster(rw http.ResponseWriter, req *http.Request) {
 }
 
 // @Summary Get master images
-// @Description Gat all the images provided by master
+// @Description Gat all the images provided by master, the cover image goes first. At /masters/self/images the changes of an approved listing waiting for review are listed too, marked pending.
 // @Tags Master
 // @Param master_id path string true "ID of the master"
+// @Param X-Telegram-User-ID header int false "Telegram user ID, selects the master on the /masters/self routes"
 // @Accept json
 // @Produce json
 // @Success 200 {array} entities.Image
 // @Failure 500 {string} string "Error message"
 // @Router /masters/{master_id}/images [get]
+// @Router /masters/self/images [get]
 func (h *Handler) GetMasterImages(rw http.ResponseWriter, req *http.Request) {
 	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
 
 	params := mux.Vars(req)
 	masterID := params["master_id"]
 
-	images := h.MinIOAdapter.GetMasterImages(masterID)
+	// the master sees its changes waiting for review too
+	images, err := h.DBAdapter.GetMasterImages(masterID, isSelf(req))
+	if err != nil {
+		h.logger.Error("server::GetMasterImages::GetMasterImages", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// images are shared with the cache, so the URLs are set on copies
+	result := make([]*entities.Image, 0)
+	for _, image := range images {
+		withURL := *image
+		if withURL.URL, err = h.ImageStorage.GetImageURL(image.ObjectKey); err != nil {
+			h.logger.Error("server::GetMasterImages::GetImageURL", err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result = append(result, &withURL)
+	}
 
-	imagesResp, err := json.Marshal(images)
+	imagesResp, err := json.Marshal(result)
 	if err != nil {
 		h.logger.Error("server::GetMasterImages::Marshal", err)
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
@@ -339,3 +566,353 @@ func (h *Handler) GetMasterImages(rw http.ResponseWriter, req *http.Request) {
 
 	h.logger.Info("Response sent")
 }
+
+// @Summary Get client
+// @Description Get the profile of the bot user
+// @Tags Client
+// @Param telegram_id path int true "Telegram user ID"
+// @Param X-Telegram-User-ID header int true "Telegram user ID the bot acts for, must match telegram_id"
+// @Accept json
+// @Produce json
+// @Success 200 {object} entities.Client
+// @Failure 400 {string} string "Error message"
+// @Failure 401 {string} string "Error message"
+// @Failure 403 {string} string "Error message"
+// @Failure 404 {string} string "Error message"
+// @Failure 500 {string} string "Error message"
+// @Router /clients/{telegram_id} [get]
+func (h *Handler) GetClient(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	params := mux.Vars(req)
+	telegramID, err := strconv.ParseInt(params["telegram_id"], 10, 64)
+	if err != nil {
+		h.logger.Error("server::GetClient::ParseInt", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	client, err := h.DBAdapter.GetClient(telegramID)
+	if err != nil {
+		h.logger.Error("server::GetClient::GetClient", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	clientResp, err := json.Marshal(client)
+	if err != nil {
+		h.logger.Error("server::GetClient::Marshal", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	if _, err := rw.Write(clientResp); err != nil {
+		h.logger.Error("server::GetClient::Write", err)
+		return
+	}
+	h.logger.Info("Response sent")
+}
+
+// @Summary Get master revisions
+// @Description Get the profile changes submitted by masters. Used by control panel, or by the master for own revisions at /masters/self/revisions. A pending revision lists the image changes of the master published with it: new images, replacements and new captions.
+// @Tags Master
+// @Param page query int false "Page number for pagination"
+// @Param limit query int false "Limit of items for pagination"
+// @Param status query int false "Revision status: 1 - pending, 2 - approved, 3 - declined"
+// @Param X-Telegram-User-ID header int false "Telegram user ID, selects the master on the /masters/self routes"
+// @Accept json
+// @Produce json
+// @Success 200 {array} entities.MasterRevision
+// @Failure 400 {string} string "Error message"
+// @Failure 401 {string} string "Error message"
+// @Failure 500 {string} string "Error message"
+// @Router /masters/revisions [get]
+// @Router /masters/self/revisions [get]
+func (h *Handler) GetMasterRevisions(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	params := mux.Vars(req)
+	query := req.URL.Query()
+	page, err := getParam[int](query.Get("page"), 0)
+	if err != nil {
+		h.logger.Error("server::GetMasterRevisions::getParam[int]", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit, err := getParam[int](query.Get("limit"), -1)
+	if err != nil {
+		h.logger.Error("server::GetMasterRevisions::getParam[int]", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status, err := getParam[uint](query.Get("status"), 0)
+	if err != nil {
+		h.logger.Error("server::GetMasterRevisions::getParam[uint]", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := h.DBAdapter.GetMasterRevisions(params["master_id"], status, page, limit)
+	if err != nil {
+		h.logger.Error("server::GetMasterRevisions::GetMasterRevisions", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.attachPendingImages(revisions); err != nil {
+		h.logger.Error("server::GetMasterRevisions::attachPendingImages", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	revisionsResp, err := json.Marshal(revisions)
+	if err != nil {
+		h.logger.Error("server::GetMasterRevisions::Marshal", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	if _, err := rw.Write(revisionsResp); err != nil {
+		h.logger.Error("server::GetMasterRevisions::Write", err)
+		return
+	}
+	h.logger.Info("Response sent")
+}
+
+// @Summary Get favorite masters
+// @Description Get the approved masters the client added to favorites, the latest added first. Used by bot.
+// @Tags Client
+// @Param telegram_id path int true "Telegram user ID"
+// @Param X-Telegram-User-ID header int true "Telegram user ID the bot acts for, must match telegram_id"
+// @Param page query int false "Page number for pagination"
+// @Param limit query int false "Limit of items for pagination"
+// @Accept json
+// @Produce json
+// @Success 200 {array} entities.MasterShort
+// @Failure 400 {string} string "Error message"
+// @Failure 401 {string} string "Error message"
+// @Failure 403 {string} string "Error message"
+// @Failure 500 {string} string "Error message"
+// @Router /clients/{telegram_id}/favorites [get]
+func (h *Handler) GetFavorites(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	params := mux.Vars(req)
+	telegramID, err := strconv.ParseInt(params["telegram_id"], 10, 64)
+	if err != nil {
+		h.logger.Error("server::GetFavorites::ParseInt", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := req.URL.Query()
+	page, err := getParam[int](query.Get("page"), 0)
+	if err != nil {
+		h.logger.Error("server::GetFavorites::getParam[int]", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit, err := getParam[int](query.Get("limit"), -1)
+	if err != nil {
+		h.logger.Error("server::GetFavorites::getParam[int]", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	masters, err := h.DBAdapter.GetFavorites(telegramID, page, limit)
+	if err != nil {
+		h.logger.Error("server::GetFavorites::GetFavorites", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.fillMasters(masters); err != nil {
+		h.logger.Error("server::GetFavorites::fillMasters", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	mastersResp, err := json.Marshal(masters)
+	if err != nil {
+		h.logger.Error("server::GetFavorites::Marshal", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	if _, err := rw.Write(mastersResp); err != nil {
+		h.logger.Error("server::GetFavorites::Write", err)
+		return
+	}
+	h.logger.Info("Response sent")
+}
+
+// @Summary Export catalog
+// @Description Stream all cities, service categories, services and masters in the format accepted by the import.
+// @Tags Catalog
+// @Param format query string false "csv or json, json by default"
+// @Produce json
+// @Produce text/csv
+// @Success 200 {object} entities.Catalog
+// @Failure 400 {string} string "Error message"
+// @Failure 401 {string} string "Error message"
+// @Router /catalog/export [get]
+func (h *Handler) ExportCatalog(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	format, err := catalog.Format(req.URL.Query().Get("format"), "")
+	if err != nil {
+		h.logger.Error("server::ExportCatalog::Format", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rw.Header().Set("Content-Type", catalog.ContentType(format))
+	rw.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="catalog.%s"`, format))
+	rw.WriteHeader(http.StatusOK)
+
+	// the status is already sent, a failure can only cut the body short
+	writer := catalog.NewWriter(rw, format)
+	if err := h.DBAdapter.ExportCatalog(writer.Write); err != nil {
+		h.logger.Error("server::ExportCatalog::ExportCatalog", err)
+		return
+	}
+	if err := writer.Close(); err != nil {
+		h.logger.Error("server::ExportCatalog::Close", err)
+		return
+	}
+	h.logger.Info("Response sent")
+}
+
+// @Summary Get statistics
+// @Description Get the numbers for the control panel dashboard: masters by status, city, category and service, registrations per day, the moderation backlog of masters and revisions and the median time to approve a revision.
+// @Tags Stats
+// @Param days query int false "Number of days of the registrations report, 30 by default"
+// @Accept json
+// @Produce json
+// @Success 200 {object} entities.Stats
+// @Failure 400 {string} string "Error message"
+// @Failure 401 {string} string "Error message"
+// @Failure 500 {string} string "Error message"
+// @Router /stats [get]
+func (h *Handler) GetStats(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	days, err := getParam[int](req.URL.Query().Get("days"), 30)
+	if err != nil {
+		h.logger.Error("server::GetStats::getParam[int]", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if days < 1 || days > 366 {
+		http.Error(rw, "days must be between 1 and 366", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := h.DBAdapter.GetStats(days)
+	if err != nil {
+		h.logger.Error("server::GetStats::GetStats", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	statsResp, err := json.Marshal(stats)
+	if err != nil {
+		h.logger.Error("server::GetStats::Marshal", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	if _, err := rw.Write(statsResp); err != nil {
+		h.logger.Error("server::GetStats::Write", err)
+		return
+	}
+	h.logger.Info("Response sent")
+}
+
+// @Summary Stream moderation events
+// @Description Push the changes of the masters as server-sent events as they happen: master.created, master.updated, master.status_changed, image.uploaded, revision.created and revision.reviewed. The event name is the type, the data is the event in JSON. A client that reconnects with Last-Event-ID gets the events it missed while they are still buffered, the connection is closed when the client falls behind. Used by admin panel.
+// @Tags Master
+// @Param Last-Event-ID header int false "ID of the last received event"
+// @Param api_key query string false "Admin API key for the clients that can't set X-API-Key, e.g. EventSource"
+// @Produce text/event-stream
+// @Success 200 {object} entities.ModerationEvent
+// @Failure 400 {string} string "Error message"
+// @Failure 401 {string} string "Error message"
+// @Failure 500 {string} string "Error message"
+// @Router /events/stream [get]
+func (h *Handler) StreamEvents(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		http.Error(rw, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	lastID, err := getParam[int64](req.Header.Get("Last-Event-ID"), 0)
+	if err != nil {
+		h.logger.Error("server::StreamEvents::getParam[int64]", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	missed, events, cancel := h.DBAdapter.SubscribeUpdates(lastID)
+	defer cancel()
+
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	// the proxies must not buffer the stream
+	rw.Header().Set("X-Accel-Buffering", "no")
+	rw.WriteHeader(http.StatusOK)
+
+	for _, event := range missed {
+		if err := writeEvent(rw, event); err != nil {
+			h.logger.Error("server::StreamEvents::writeEvent", err)
+			return
+		}
+	}
+	flusher.Flush()
+
+	ping := time.NewTicker(streamPing)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			h.logger.Info("Stream closed")
+			return
+		case event, ok := <-events:
+			if !ok {
+				h.logger.Info("Stream closed, the client fell behind")
+				return
+			}
+			if err := writeEvent(rw, event); err != nil {
+				h.logger.Error("server::StreamEvents::writeEvent", err)
+				return
+			}
+			flusher.Flush()
+		case <-ping.C:
+			// a comment keeps the idle connection open through the proxies
+			if _, err := fmt.Fprint(rw, ": ping\n\n"); err != nil {
+				h.logger.Error("server::StreamEvents::Fprint", err)
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
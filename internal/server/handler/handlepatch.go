@@ -0,0 +1,351 @@
+package handler
+
+import (
+	"bot/internal/dbadapter"
+	"bot/internal/entities"
+	"bot/internal/mergepatch"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// @Summary Patch city
+// @Description Change the city with a JSON merge patch (RFC 7396), the fields set to null are reset
+// @Tags City
+// @Param city_id path string true "ID of the city"
+// @Param If-Match header string false "Version of the city the patch is based on"
+// @Param patch body entities.City true "Changed fields"
+// @Accept json
+// @Produce json
+// @Success 204
+// @Failure 400 {string} string "Error message"
+// @Failure 404 {string} string "Error message"
+// @Failure 409 {string} string "Error message"
+// @Failure 412 {string} string "The version is stale"
+// @Failure 415 {string} string "Error message"
+// @Failure 500 {string} string "Error message"
+// @Router /cities/{city_id} [patch]
+func (h *Handler) PatchCity(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	params := mux.Vars(req)
+	cityID := params["city_id"]
+
+	if contentType := req.Header.Get("Content-Type"); !isMergePatch(contentType) {
+		h.logger.Errorf("server::PatchCity::isMergePatch: %s", contentType)
+		http.Error(rw, "unsupported content type, use "+mergepatch.ContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	patch, err := io.ReadAll(req.Body)
+	if err != nil {
+		h.logger.Error("server::PatchCity::ReadAll", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the If-Match header is optional, the patch may carry the version as well
+	version, err := expectedVersion(req, 0)
+	if err != nil && !errors.Is(err, errVersionRequired) {
+		h.logger.Error("server::PatchCity::expectedVersion", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	validator := validator.New()
+	err = h.DBAdapter.PatchCity(cityID, func(city *entities.City) error {
+		if err := applyPatch(city, patch); err != nil {
+			return err
+		}
+		city.ID = cityID
+		city.Name = strings.TrimSpace(city.Name)
+		if version != 0 {
+			city.Version = version
+		}
+		if err := validator.Struct(city); err != nil {
+			return fmt.Errorf("%w: %s", mergepatch.ErrInvalidPatch, err)
+		}
+		return nil
+	})
+	if err != nil {
+		h.logger.Error("server::PatchCity::PatchCity", err)
+		if errors.Is(err, mergepatch.ErrInvalidPatch) {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			http.Error(rw, err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, dbadapter.ErrVersionMismatch) {
+			http.Error(rw, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+	h.logger.Info("Response sent")
+}
+
+// @Summary Patch service category
+// @Description Change the service category with a JSON merge patch (RFC 7396), the fields set to null are reset
+// @Tags Service
+// @Param category_id path string true "ID of the service category"
+// @Param If-Match header string false "Version of the service category the patch is based on"
+// @Param patch body entities.ServiceCategory true "Changed fields"
+// @Accept json
+// @Produce json
+// @Success 204
+// @Failure 400 {string} string "Error message"
+// @Failure 404 {string} string "Error message"
+// @Failure 409 {string} string "Error message"
+// @Failure 412 {string} string "The version is stale"
+// @Failure 415 {string} string "Error message"
+// @Failure 500 {string} string "Error message"
+// @Router /services/categories/{category_id} [patch]
+func (h *Handler) PatchServCategory(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	params := mux.Vars(req)
+	categoryID := params["category_id"]
+
+	if contentType := req.Header.Get("Content-Type"); !isMergePatch(contentType) {
+		h.logger.Errorf("server::PatchServCategory::isMergePatch: %s", contentType)
+		http.Error(rw, "unsupported content type, use "+mergepatch.ContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	patch, err := io.ReadAll(req.Body)
+	if err != nil {
+		h.logger.Error("server::PatchServCategory::ReadAll", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the If-Match header is optional, the patch may carry the version as well
+	version, err := expectedVersion(req, 0)
+	if err != nil && !errors.Is(err, errVersionRequired) {
+		h.logger.Error("server::PatchServCategory::expectedVersion", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	validator := validator.New()
+	err = h.DBAdapter.PatchServCategory(categoryID, func(category *entities.ServiceCategory) error {
+		if err := applyPatch(category, patch); err != nil {
+			return err
+		}
+		category.ID = categoryID
+		category.Name = strings.TrimSpace(category.Name)
+		if version != 0 {
+			category.Version = version
+		}
+		if err := validator.Struct(category); err != nil {
+			return fmt.Errorf("%w: %s", mergepatch.ErrInvalidPatch, err)
+		}
+		return nil
+	})
+	if err != nil {
+		h.logger.Error("server::PatchServCategory::PatchServCategory", err)
+		if errors.Is(err, mergepatch.ErrInvalidPatch) {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			http.Error(rw, err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, dbadapter.ErrVersionMismatch) {
+			http.Error(rw, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+	h.logger.Info("Response sent")
+}
+
+// @Summary Patch service
+// @Description Change the service name or category with a JSON merge patch (RFC 7396), the fields set to null are reset
+// @Tags Service
+// @Param service_id path string true "ID of the service"
+// @Param If-Match header string false "Version of the service the patch is based on"
+// @Param patch body entities.Service true "Changed fields"
+// @Accept json
+// @Produce json
+// @Success 204
+// @Failure 400 {string} string "Error message"
+// @Failure 404 {string} string "Error message"
+// @Failure 409 {string} string "Error message"
+// @Failure 412 {string} string "The version is stale"
+// @Failure 415 {string} string "Error message"
+// @Failure 500 {string} string "Error message"
+// @Router /services/{service_id} [patch]
+func (h *Handler) PatchService(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	params := mux.Vars(req)
+	serviceID := params["service_id"]
+
+	if contentType := req.Header.Get("Content-Type"); !isMergePatch(contentType) {
+		h.logger.Errorf("server::PatchService::isMergePatch: %s", contentType)
+		http.Error(rw, "unsupported content type, use "+mergepatch.ContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	patch, err := io.ReadAll(req.Body)
+	if err != nil {
+		h.logger.Error("server::PatchService::ReadAll", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the If-Match header is optional, the patch may carry the version as well
+	version, err := expectedVersion(req, 0)
+	if err != nil && !errors.Is(err, errVersionRequired) {
+		h.logger.Error("server::PatchService::expectedVersion", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	validator := validator.New()
+	err = h.DBAdapter.PatchService(serviceID, func(service *entities.Service) error {
+		if err := applyPatch(service, patch); err != nil {
+			return err
+		}
+		service.ID = serviceID
+		service.Name = strings.TrimSpace(service.Name)
+		if version != 0 {
+			service.Version = version
+		}
+		if err := validator.Struct(service); err != nil {
+			return fmt.Errorf("%w: %s", mergepatch.ErrInvalidPatch, err)
+		}
+		return nil
+	})
+	if err != nil {
+		h.logger.Error("server::PatchService::PatchService", err)
+		if errors.Is(err, mergepatch.ErrInvalidPatch) {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			http.Error(rw, err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, dbadapter.ErrVersionMismatch) {
+			http.Error(rw, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+	h.logger.Info("Response sent")
+}
+
+// @Summary Patch master
+// @Description Change the master data with a JSON merge patch (RFC 7396), the fields set to null are reset
+// @Tags Master
+// @Param master_id path string true "ID of the master"
+// @Param If-Match header string false "Version of the master the patch is based on"
+// @Param patch body entities.MasterLong true "Changed fields"
+// @Accept json
+// @Produce json
+// @Success 204
+// @Failure 400 {string} string "Error message"
+// @Failure 404 {string} string "Error message"
+// @Failure 409 {string} string "Error message"
+// @Failure 412 {string} string "The version is stale"
+// @Failure 415 {string} string "Error message"
+// @Failure 500 {string} string "Error message"
+// @Router /masters/{master_id} [patch]
+func (h *Handler) PatchMaster(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	params := mux.Vars(req)
+	masterID := params["master_id"]
+
+	if contentType := req.Header.Get("Content-Type"); !isMergePatch(contentType) {
+		h.logger.Errorf("server::PatchMaster::isMergePatch: %s", contentType)
+		http.Error(rw, "unsupported content type, use "+mergepatch.ContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	patch, err := io.ReadAll(req.Body)
+	if err != nil {
+		h.logger.Error("server::PatchMaster::ReadAll", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the If-Match header is optional, the patch may carry the version as well
+	version, err := expectedVersion(req, 0)
+	if err != nil && !errors.Is(err, errVersionRequired) {
+		h.logger.Error("server::PatchMaster::expectedVersion", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	validator := validator.New()
+	err = h.DBAdapter.PatchMaster(masterID, func(master *entities.MasterLong) error {
+		if err := applyPatch(master, patch); err != nil {
+			return err
+		}
+		master.ID = masterID
+		if version != 0 {
+			master.Version = version
+		}
+		if err := validator.Struct(master); err != nil {
+			return fmt.Errorf("%w: %s", mergepatch.ErrInvalidPatch, err)
+		}
+		return nil
+	})
+	if err != nil {
+		h.logger.Error("server::PatchMaster::PatchMaster", err)
+		if errors.Is(err, mergepatch.ErrInvalidPatch) {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			http.Error(rw, err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, dbadapter.ErrVersionMismatch) {
+			http.Error(rw, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+	h.logger.Info("Response sent")
+}
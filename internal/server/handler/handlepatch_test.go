@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"bot/internal/dbadapter"
+	"bot/internal/dbtest"
+	"bot/internal/logger"
+	"bot/internal/mergepatch"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// null resets a field, the patched entity must still be valid
+func TestPatchNullRequired(t *testing.T) {
+
+	cfg := dbtest.Config(t)
+	adapter, err := dbadapter.NewDbAdapter(logger.NewLogger(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(logger.NewLogger(), cfg, adapter, nil)
+
+	cityID, err := adapter.SaveCity("city " + uuid.NewString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { adapter.DeleteCity(cityID) })
+	categoryID, err := adapter.SaveServiceCategory("category " + uuid.NewString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { adapter.DeleteServCategory(categoryID) })
+	serviceID, err := adapter.SaveService("service "+uuid.NewString(), categoryID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { adapter.DeleteService(serviceID) })
+
+	tests := map[string]struct {
+		handler http.HandlerFunc
+		vars    map[string]string
+		patch   string
+	}{
+		"city name":           {h.PatchCity, map[string]string{"city_id": cityID}, `{"name": null}`},
+		"category name":       {h.PatchServCategory, map[string]string{"category_id": categoryID}, `{"name": null}`},
+		"service name":        {h.PatchService, map[string]string{"service_id": serviceID}, `{"name": null}`},
+		"category of service": {h.PatchService, map[string]string{"service_id": serviceID}, `{"catID": null}`},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(test.patch))
+			req.Header.Set("Content-Type", mergepatch.ContentType)
+			rw := httptest.NewRecorder()
+			test.handler(rw, mux.SetURLVars(req, test.vars))
+			if rw.Code != http.StatusBadRequest {
+				t.Errorf("got %d: %s, want %d", rw.Code, rw.Body.String(), http.StatusBadRequest)
+			}
+		})
+	}
+
+	city, err := adapter.GetCity(cityID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(city.Name) == 0 {
+		t.Error("the rejected patch reset the name of the city")
+	}
+}
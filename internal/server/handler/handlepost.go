@@ -1,15 +1,24 @@
 package handler
 
 import (
+	"bot/internal/catalog"
+	"bot/internal/dbadapter"
 	"bot/internal/entities"
+	middleware "bot/internal/server/middleware"
+	"bot/internal/storage"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"gorm.io/gorm"
 )
 
 // @Summary Save city
@@ -20,6 +29,7 @@ import (
 // @Produce json
 // @Success 201 {object} ID "ID of the new city"
 // @Failure 400 {string} string "Error message"
+// @Failure 409 {string} string "Error message"
 // @Failure 500 {string} string "Error message"
 // @Router /cities [post]
 func (h *Handler) SaveCity(rw http.ResponseWriter, req *http.Request) {
@@ -32,16 +42,28 @@ func (h *Handler) SaveCity(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	city := &entities.City{}
+	city := &Name{}
 	if err := json.Unmarshal(body, city); err != nil {
 		h.logger.Error("server::SaveCity::Unmarshal")
 		http.Error(rw, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	city.Name = strings.TrimSpace(city.Name)
+	validator := validator.New()
+	if err := validator.Struct(city); err != nil {
+		h.logger.Error("server::SaveCity::Struct", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	id, err := h.DBAdapter.SaveCity(city.Name)
 	if err != nil {
 		h.logger.Error("server::SaveCity::SaveCity", err)
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			http.Error(rw, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -63,6 +85,7 @@ func (h *Handler) SaveCity(rw http.ResponseWriter, req *http.Request) {
 // @Produce json
 // @Success 201 {object} ID "ID of the new service category"
 // @Failure 400 {string} string "Error message"
+// @Failure 409 {string} string "Error message"
 // @Failure 500 {string} string "Error message"
 // @Router /services/categories [post]
 func (h *Handler) SaveServiceCategory(rw http.ResponseWriter, req *http.Request) {
@@ -75,16 +98,28 @@ func (h *Handler) SaveServiceCategory(rw http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	serviceCategory := &entities.ServiceCategory{}
+	serviceCategory := &Name{}
 	if err := json.Unmarshal(body, serviceCategory); err != nil {
 		h.logger.Error("server::SaveServiceCategory::Unmarshal", err)
 		http.Error(rw, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	serviceCategory.Name = strings.TrimSpace(serviceCategory.Name)
+	validator := validator.New()
+	if err := validator.Struct(serviceCategory); err != nil {
+		h.logger.Error("server::SaveServiceCategory::Struct", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	id, err := h.DBAdapter.SaveServiceCategory(serviceCategory.Name)
 	if err != nil {
 		h.logger.Error("server::SaveServiceCategory::SaveServiceCategory", err)
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			http.Error(rw, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -106,6 +141,8 @@ func (h *Handler) SaveServiceCategory(rw http.ResponseWriter, req *http.Request)
 // @Produce json
 // @Success 201 {object} ID "ID of the new service"
 // @Failure 400 {string} string "Error message"
+// @Failure 404 {string} string "Error message"
+// @Failure 409 {string} string "Error message"
 // @Failure 500 {string} string "Error message"
 // @Router /services [post]
 func (h *Handler) SaveService(rw http.ResponseWriter, req *http.Request) {
@@ -125,9 +162,25 @@ func (h *Handler) SaveService(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	service.Name = strings.TrimSpace(service.Name)
+	validator := validator.New()
+	if err := validator.Struct(service); err != nil {
+		h.logger.Error("server::SaveService::Struct", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	id, err := h.DBAdapter.SaveService(service.Name, service.CatID)
 	if err != nil {
 		h.logger.Error("server::SaveService::SaveService", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			http.Error(rw, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -142,13 +195,16 @@ func (h *Handler) SaveService(rw http.ResponseWriter, req *http.Request) {
 }
 
 // @Summary Save master
-// @Description Save new master in the system
+// @Description Save new master in the system. The master is linked to the Telegram user that submitted the form only when the bot sends it with its API key, telegramID and the header are ignored otherwise.
 // @Tags Master
+// @Param X-Telegram-User-ID header int false "Telegram user ID, overrides telegramID from the body"
 // @Param form body entities.Master true "Master data"
 // @Accept json
 // @Produce json
 // @Success 201 {object} ID "ID of the new master"
 // @Failure 400 {string} string "Error message"
+// @Failure 429 {string} string "Too many requests"
+// @Failure 404 {string} string "Error message"
 // @Failure 500 {string} string "Error message"
 // @Router /masters [post]
 func (h *Handler) SaveMaster(rw http.ResponseWriter, req *http.Request) {
@@ -168,6 +224,22 @@ func (h *Handler) SaveMaster(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// the master is linked to a Telegram user only by the bot, anybody else
+	// could link the listing to somebody else and take over their /masters/self
+	if !h.auth.IsUserKey(req.Header.Get(middleware.APIKeyHeader)) {
+		master.TelegramID = 0
+	} else {
+		telegramID, err := getTelegramID(req)
+		if err != nil {
+			h.logger.Error("server::SaveMaster::getTelegramID", err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if telegramID != 0 {
+			master.TelegramID = telegramID
+		}
+	}
+
 	validator := validator.New()
 	if err := validator.Struct(master); err != nil {
 		h.logger.Error("server::SaveMaster::Struct", err)
@@ -175,22 +247,14 @@ func (h *Handler) SaveMaster(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	id, err := h.DBAdapter.SaveMaster(master)
+	// approved right away, temporary, while the approvement mechanism is not integrated
+	id, err := h.DBAdapter.SaveApprovedMaster(master)
 	if err != nil {
-		h.logger.Error("server::SaveMaster::SaveMaster", err)
-		http.Error(rw, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	if err := h.MinIOAdapter.MakeBucket(id); err != nil {
-		h.logger.Error("server::SaveMaster::MakeBucket", err)
-		http.Error(rw, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// temporary, while the approvement mechanism is not integrated
-	if err := h.DBAdapter.ApproveMaster(id); err != nil {
-		h.logger.Error("server::SaveMaster::SaveMaster", err)
+		h.logger.Error("server::SaveMaster::SaveApprovedMaster", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -205,16 +269,20 @@ func (h *Handler) SaveMaster(rw http.ResponseWriter, req *http.Request) {
 }
 
 // @Summary Save master's image
-// @Description Save the image that was attached to the registration form
+// @Description Save the image that was attached to the registration form, without the admin key the image of an approved master waits for a revision
 // @Tags Master
 // @Param master_id path string true "ID of a master, whose picture is uploaded"
 // @Param file formData file true "Image to upload"
+// @Param caption formData string false "Image caption"
+// @Param X-Telegram-User-ID header int false "Telegram user ID, selects the master on the /masters/self routes"
 // @Accept multipart/form-data
 // @Produce json
 // @Success 201 {object} URL "URL of the saved picture"
 // @Failure 400 {string} string "Error message"
+// @Failure 429 {string} string "Too many requests"
 // @Failure 500 {string} string "Error message"
 // @Router /masters/{master_id}/images [post]
+// @Router /masters/self/images [post]
 func (h *Handler) SaveMasterImage(rw http.ResponseWriter, req *http.Request) {
 	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
 
@@ -235,13 +303,33 @@ func (h *Handler) SaveMasterImage(rw http.ResponseWriter, req *http.Request) {
 	}
 	defer formFile.Close()
 
+	image, err := readImageMeta(formFile, meta)
+	if err != nil {
+		h.logger.Error("server::SaveMasterImage::readImageMeta", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	newImageName := uuid.NewString()
-	if err := h.MinIOAdapter.PutMasterImage(masterID, newImageName, formFile, meta.Size, meta.Header.Get("Content-Type")); err != nil {
+	objectKey := storage.MasterImageKey(masterID, newImageName)
+	image.Name = newImageName
+	image.Caption = req.FormValue("caption")
+
+	if err := h.ImageStorage.PutMasterImage(objectKey, formFile, image.Size, image.ContentType); err != nil {
 		h.logger.Error("server::SaveMasterImage::PutMasterImage", err)
 		http.Error(rw, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	if err := h.DBAdapter.SaveMasterImage(masterID, objectKey, image, h.byMaster(req)); err != nil {
+		h.logger.Error("server::SaveMasterImage::SaveMasterImage", err)
+		if err := h.ImageStorage.DeleteMasterImage(objectKey); err != nil {
+			h.logger.Error("server::SaveMasterImage::DeleteMasterImage", err)
+		}
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	rw.Header().Set("Content-Type", "application/json")
 	rw.WriteHeader(http.StatusCreated)
 	if _, err := rw.Write([]byte(fmt.Sprintf(`{ "url" : "%s" }`, newImageName))); err != nil {
@@ -259,8 +347,9 @@ func (h *Handler) SaveMasterImage(rw http.ResponseWriter, req *http.Request) {
 // @Produce json
 // @Success 201 {object} ID "ID of the approved master"
 // @Failure 400 {string} string "Error message"
+// @Failure 404 {string} string "Error message"
 // @Failure 500 {string} string "Error message"
-// @Router /masters/approve/{maser_id} [post]
+// @Router /masters/{master_id}:approve [post]
 func (h *Handler) ApproveMaster(rw http.ResponseWriter, req *http.Request) {
 	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
 
@@ -269,6 +358,10 @@ func (h *Handler) ApproveMaster(rw http.ResponseWriter, req *http.Request) {
 
 	if err := h.DBAdapter.ApproveMaster(masterID); err != nil {
 		h.logger.Error("server::ApproveMaster::SaveMaster", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -281,3 +374,454 @@ func (h *Handler) ApproveMaster(rw http.ResponseWriter, req *http.Request) {
 	}
 	h.logger.Info("Response sent")
 }
+
+// @Summary Set master cover
+// @Description Make the image the cover of the master, the bot shows it first
+// @Tags Master
+// @Param master_id path string true "ID of the master"
+// @Param image_name path string true "Name of the image"
+// @Param X-Telegram-User-ID header int false "Telegram user ID, selects the master on the /masters/self routes"
+// @Accept json
+// @Produce json
+// @Success 204
+// @Failure 404 {string} string "Error message"
+// @Failure 500 {string} string "Error message"
+// @Router /masters/{master_id}/images/{image_name}/cover [post]
+// @Router /masters/self/images/{image_name}/cover [post]
+func (h *Handler) SetMasterCover(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	params := mux.Vars(req)
+	masterID := params["master_id"]
+	imageName := params["image_name"]
+
+	if err := h.DBAdapter.SetMasterCover(masterID, imageName); err != nil {
+		h.logger.Error("server::SetMasterCover::SetMasterCover", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+	h.logger.Info("Response sent")
+}
+
+// @Summary Request image upload URL
+// @Description Get a short-lived presigned URL to upload a master image directly to the storage. The upload must be confirmed afterwards.
+// @Tags Master
+// @Param master_id path string true "ID of the master"
+// @Param upload body UploadRequest true "Content type and size of the image"
+// @Param X-Telegram-User-ID header int false "Telegram user ID, selects the master on the /masters/self routes"
+// @Accept json
+// @Produce json
+// @Success 201 {object} UploadURL "Presigned upload URL and the headers to send with it"
+// @Failure 400 {string} string "Error message"
+// @Failure 404 {string} string "Error message"
+// @Failure 429 {string} string "Too many requests"
+// @Failure 500 {string} string "Error message"
+// @Router /masters/{master_id}/images/uploads [post]
+// @Router /masters/self/images/uploads [post]
+func (h *Handler) PresignMasterImageUpload(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	params := mux.Vars(req)
+	masterID := params["master_id"]
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		h.logger.Error("server::PresignMasterImageUpload::ReadAll", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	upload := &UploadRequest{}
+	if err := json.Unmarshal(body, upload); err != nil {
+		h.logger.Error("server::PresignMasterImageUpload::Unmarshal", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(upload); err != nil {
+		h.logger.Error("server::PresignMasterImageUpload::Struct", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.checkImage(upload.ContentType, upload.Size); err != nil {
+		h.logger.Error("server::PresignMasterImageUpload::checkImage", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.DBAdapter.GetMaster(masterID); err != nil {
+		h.logger.Error("server::PresignMasterImageUpload::GetMaster", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	name := uuid.NewString()
+	url, headers, err := h.ImageStorage.PresignUpload(storage.MasterImageKey(masterID, name), upload.ContentType, upload.Size)
+	if err != nil {
+		h.logger.Error("server::PresignMasterImageUpload::PresignUpload", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	uploadURL := &UploadURL{
+		Name:      name,
+		URL:       url,
+		Method:    http.MethodPut,
+		Headers:   make(map[string]string),
+		ExpiresAt: time.Now().Add(time.Duration(h.cfg.UploadURLExpiry) * time.Second),
+	}
+	for key := range headers {
+		uploadURL.Headers[key] = headers.Get(key)
+	}
+
+	uploadResp, err := json.Marshal(uploadURL)
+	if err != nil {
+		h.logger.Error("server::PresignMasterImageUpload::Marshal", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusCreated)
+	if _, err := rw.Write(uploadResp); err != nil {
+		h.logger.Error("server::PresignMasterImageUpload::Write", err)
+		return
+	}
+	h.logger.Info("Response sent")
+}
+
+// @Summary Confirm image upload
+// @Description Register an image uploaded through a presigned URL. Uploads of a wrong type or size are removed. Without the admin key the image of an approved master waits for a revision.
+// @Tags Master
+// @Param master_id path string true "ID of the master"
+// @Param image_name path string true "Name of the image returned with the upload URL"
+// @Param caption body Caption false "Image caption"
+// @Param X-Telegram-User-ID header int false "Telegram user ID, selects the master on the /masters/self routes"
+// @Accept json
+// @Produce json
+// @Success 201 {object} entities.Image
+// @Failure 400 {string} string "Error message"
+// @Failure 404 {string} string "Error message"
+// @Failure 409 {string} string "Error message"
+// @Failure 500 {string} string "Error message"
+// @Router /masters/{master_id}/images/{image_name}/confirm [post]
+// @Router /masters/self/images/{image_name}/confirm [post]
+func (h *Handler) ConfirmMasterImageUpload(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	params := mux.Vars(req)
+	masterID := params["master_id"]
+	imageName := params["image_name"]
+
+	if _, err := uuid.Parse(imageName); err != nil {
+		h.logger.Error("server::ConfirmMasterImageUpload::Parse", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	caption := &Caption{}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		h.logger.Error("server::ConfirmMasterImageUpload::ReadAll", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(body) != 0 {
+		if err := json.Unmarshal(body, caption); err != nil {
+			h.logger.Error("server::ConfirmMasterImageUpload::Unmarshal", err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	objectKey := storage.MasterImageKey(masterID, imageName)
+	image, err := h.ImageStorage.StatMasterImage(objectKey)
+	if err != nil {
+		h.logger.Error("server::ConfirmMasterImageUpload::StatMasterImage", err)
+		http.Error(rw, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if err := h.checkImage(image.ContentType, image.Size); err != nil {
+		h.logger.Error("server::ConfirmMasterImageUpload::checkImage", err)
+		if err := h.ImageStorage.DeleteMasterImage(objectKey); err != nil {
+			h.logger.Error("server::ConfirmMasterImageUpload::DeleteMasterImage", err)
+		}
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	image.Name = imageName
+	image.Caption = caption.Caption
+	if err := h.DBAdapter.SaveMasterImage(masterID, objectKey, image, h.byMaster(req)); err != nil {
+		h.logger.Error("server::ConfirmMasterImageUpload::SaveMasterImage", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			http.Error(rw, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if image.URL, err = h.ImageStorage.GetImageURL(objectKey); err != nil {
+		h.logger.Error("server::ConfirmMasterImageUpload::GetImageURL", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	imageResp, err := json.Marshal(image)
+	if err != nil {
+		h.logger.Error("server::ConfirmMasterImageUpload::Marshal", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusCreated)
+	if _, err := rw.Write(imageResp); err != nil {
+		h.logger.Error("server::ConfirmMasterImageUpload::Write", err)
+		return
+	}
+	h.logger.Info("Response sent")
+}
+
+// @Summary Approve master revision
+// @Description Apply the profile changes and the image changes to the live listing, a revision of only image changes keeps the profile
+// @Tags Master
+// @Param revision_id path string true "ID of the revision"
+// @Accept json
+// @Produce json
+// @Success 204
+// @Failure 400 {string} string "Error message"
+// @Failure 401 {string} string "Error message"
+// @Failure 404 {string} string "Error message"
+// @Failure 412 {string} string "The master changed after the revision was submitted"
+// @Failure 500 {string} string "Error message"
+// @Router /masters/revisions/{revision_id}:approve [post]
+func (h *Handler) ApproveMasterRevision(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	params := mux.Vars(req)
+	revisionID := params["revision_id"]
+
+	objectKeys, err := h.DBAdapter.ApproveMasterRevision(revisionID)
+	if err != nil {
+		h.logger.Error("server::ApproveMasterRevision::ApproveMasterRevision", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, dbadapter.ErrVersionMismatch) {
+			http.Error(rw, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the revision is approved already, a leftover object doesn't fail the request
+	for _, objectKey := range objectKeys {
+		if err := h.ImageStorage.DeleteMasterImage(objectKey); err != nil {
+			h.logger.Error("server::ApproveMasterRevision::DeleteMasterImage", err)
+		}
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+	h.logger.Info("Response sent")
+}
+
+// @Summary Reject master revision
+// @Description Decline the profile changes and drop the image changes, the live listing stays unchanged
+// @Tags Master
+// @Param revision_id path string true "ID of the revision"
+// @Accept json
+// @Produce json
+// @Success 204
+// @Failure 400 {string} string "Error message"
+// @Failure 401 {string} string "Error message"
+// @Failure 404 {string} string "Error message"
+// @Failure 500 {string} string "Error message"
+// @Router /masters/revisions/{revision_id}:reject [post]
+func (h *Handler) RejectMasterRevision(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	params := mux.Vars(req)
+	revisionID := params["revision_id"]
+
+	objectKeys, err := h.DBAdapter.RejectMasterRevision(revisionID)
+	if err != nil {
+		h.logger.Error("server::RejectMasterRevision::RejectMasterRevision", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the revision is rejected already, a leftover object doesn't fail the request
+	for _, objectKey := range objectKeys {
+		if err := h.ImageStorage.DeleteMasterImage(objectKey); err != nil {
+			h.logger.Error("server::RejectMasterRevision::DeleteMasterImage", err)
+		}
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+	h.logger.Info("Response sent")
+}
+
+// @Summary Import catalog
+// @Description Create or update cities, service categories, services and masters in one transaction. Records are matched by ID, then by name; references may be given by ID or name. CSV has one row per record with the type column set to city, category, service or master.
+// @Tags Catalog
+// @Param format query string false "csv or json, taken from Content-Type if omitted"
+// @Param dry_run query bool false "Only report the changes, don't save them"
+// @Param catalog body entities.Catalog true "Catalog"
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Success 200 {object} entities.ImportResult
+// @Failure 400 {string} string "Error message"
+// @Failure 401 {string} string "Error message"
+// @Failure 409 {string} string "Error message"
+// @Failure 500 {string} string "Error message"
+// @Router /catalog/import [post]
+func (h *Handler) ImportCatalog(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	query := req.URL.Query()
+	format, err := catalog.Format(query.Get("format"), req.Header.Get("Content-Type"))
+	if err != nil {
+		h.logger.Error("server::ImportCatalog::Format", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dryRun := false
+	if len(query.Get("dry_run")) != 0 {
+		if dryRun, err = strconv.ParseBool(query.Get("dry_run")); err != nil {
+			h.logger.Error("server::ImportCatalog::ParseBool", err)
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	records, err := catalog.Read(req.Body, format)
+	if err != nil {
+		h.logger.Error("server::ImportCatalog::Read", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.DBAdapter.ImportCatalog(records, dryRun)
+	if err != nil {
+		h.logger.Error("server::ImportCatalog::ImportCatalog", err)
+		if errors.Is(err, dbadapter.ErrInvalidCatalog) {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			http.Error(rw, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resultResp, err := json.Marshal(result)
+	if err != nil {
+		h.logger.Error("server::ImportCatalog::Marshal", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	if _, err := rw.Write(resultResp); err != nil {
+		h.logger.Error("server::ImportCatalog::Write", err)
+		return
+	}
+	h.logger.Info("Response sent")
+}
+
+// @Summary Save bot events
+// @Description Save a batch of bot user interactions: viewed_category needs categoryID, viewed_master and clicked_contact need masterID. The events are counted per day, the counts drive the master views and the popular order. Used by bot.
+// @Tags Event
+// @Param events body []entities.Event true "Events, the time defaults to the time of the request"
+// @Accept json
+// @Produce json
+// @Success 202
+// @Failure 400 {string} string "Error message"
+// @Failure 401 {string} string "Error message"
+// @Failure 500 {string} string "Error message"
+// @Router /events [post]
+func (h *Handler) SaveEvents(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		h.logger.Error("server::SaveEvents::ReadAll", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	events := make([]*entities.Event, 0)
+	if err := json.Unmarshal(body, &events); err != nil {
+		h.logger.Error("server::SaveEvents::Unmarshal", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if int64(len(events)) > h.cfg.EventBatchSize {
+		http.Error(rw, fmt.Sprintf("at most %d events per request", h.cfg.EventBatchSize), http.StatusBadRequest)
+		return
+	}
+
+	telegramID, err := getTelegramID(req)
+	if err != nil {
+		h.logger.Error("server::SaveEvents::getTelegramID", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	validator := validator.New()
+	for index, event := range events {
+		if err := validator.Struct(event); err != nil {
+			h.logger.Error("server::SaveEvents::Struct", err)
+			http.Error(rw, fmt.Sprintf("event %d: %s", index+1, err), http.StatusBadRequest)
+			return
+		}
+		// the bot may queue events for a while, but not send them from the future
+		if event.Time.IsZero() || event.Time.After(now) {
+			event.Time = now
+		}
+		if event.TelegramID == 0 {
+			event.TelegramID = telegramID
+		}
+	}
+
+	if err := h.DBAdapter.SaveEvents(events); err != nil {
+		h.logger.Error("server::SaveEvents::SaveEvents", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusAccepted)
+	h.logger.Info("Response sent")
+}
@@ -1,28 +1,39 @@
 package handler
 
 import (
+	"bot/internal/dbadapter"
 	"bot/internal/entities"
+	"bot/internal/storage"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"gorm.io/gorm"
 )
 
 // @Summary Update city
 // @Description Change the city name
 // @Tags City
-// @Param city body entities.City true "City id and name"
+// @Param city_id path string true "ID of the city"
+// @Param city body entities.City true "City name"
+// @Param If-Match header string false "Version of the city the update is based on, required unless the body has it"
 // @Accept json
 // @Produce json
 // @Success 204
 // @Failure 400 {string} string "Error message"
 // @Failure 404 {string} string "Error message"
+// @Failure 409 {string} string "Error message"
+// @Failure 412 {string} string "The version is stale"
+// @Failure 428 {string} string "The version is missing"
 // @Failure 500 {string} string "Error message"
-// @Router /cities [put]
+// @Router /cities/{city_id} [put]
 func (h *Handler) UpdateCity(rw http.ResponseWriter, req *http.Request) {
 	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
 
@@ -40,8 +51,45 @@ func (h *Handler) UpdateCity(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// the versioned route takes the ID from the path
+	if id, ok := mux.Vars(req)["city_id"]; ok {
+		city.ID = id
+	}
+
+	city.Name = strings.TrimSpace(city.Name)
+	validator := validator.New()
+	if err := validator.Struct(city); err != nil {
+		h.logger.Error("server::UpdateCity::Struct", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	version, err := expectedVersion(req, city.Version)
+	if err != nil {
+		h.logger.Error("server::UpdateCity::expectedVersion", err)
+		if errors.Is(err, errVersionRequired) {
+			http.Error(rw, err.Error(), http.StatusPreconditionRequired)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	city.Version = version
+
 	if err := h.DBAdapter.UpdateCity(city); err != nil {
 		h.logger.Error("server::UpdateCity::UpdateCity")
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			http.Error(rw, err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, dbadapter.ErrVersionMismatch) {
+			http.Error(rw, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -53,14 +101,19 @@ func (h *Handler) UpdateCity(rw http.ResponseWriter, req *http.Request) {
 // @Summary Update service category
 // @Description Change the service categiry name
 // @Tags Service
-// @Param service body entities.ServiceCategory true "Service category id and name"
+// @Param category_id path string true "ID of the service category"
+// @Param service body entities.ServiceCategory true "Service category name"
+// @Param If-Match header string false "Version of the service category the update is based on, required unless the body has it"
 // @Accept json
 // @Produce json
 // @Success 204
 // @Failure 400 {string} string "Error message"
 // @Failure 404 {string} string "Error message"
+// @Failure 409 {string} string "Error message"
+// @Failure 412 {string} string "The version is stale"
+// @Failure 428 {string} string "The version is missing"
 // @Failure 500 {string} string "Error message"
-// @Router /services/categories [put]
+// @Router /services/categories/{category_id} [put]
 func (h *Handler) UpdateServCategory(rw http.ResponseWriter, req *http.Request) {
 	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
 
@@ -78,8 +131,45 @@ func (h *Handler) UpdateServCategory(rw http.ResponseWriter, req *http.Request)
 		return
 	}
 
+	// the versioned route takes the ID from the path
+	if id, ok := mux.Vars(req)["category_id"]; ok {
+		category.ID = id
+	}
+
+	category.Name = strings.TrimSpace(category.Name)
+	validator := validator.New()
+	if err := validator.Struct(category); err != nil {
+		h.logger.Error("server::UpdateServCategory::Struct", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	version, err := expectedVersion(req, category.Version)
+	if err != nil {
+		h.logger.Error("server::UpdateServCategory::expectedVersion", err)
+		if errors.Is(err, errVersionRequired) {
+			http.Error(rw, err.Error(), http.StatusPreconditionRequired)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	category.Version = version
+
 	if err := h.DBAdapter.UpdateServCategory(category); err != nil {
 		h.logger.Error("server::UpdateServCategory::UpdateServCategory")
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			http.Error(rw, err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, dbadapter.ErrVersionMismatch) {
+			http.Error(rw, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -91,14 +181,19 @@ func (h *Handler) UpdateServCategory(rw http.ResponseWriter, req *http.Request)
 // @Summary Update service
 // @Description Change the service name or category
 // @Tags Service
-// @Param service body entities.Service true "Service category id, category name, id and name"
+// @Param service_id path string true "ID of the service"
+// @Param service body entities.Service true "Service name and category id"
+// @Param If-Match header string false "Version of the service the update is based on, required unless the body has it"
 // @Accept json
 // @Produce json
 // @Success 204
 // @Failure 400 {string} string "Error message"
 // @Failure 404 {string} string "Error message"
+// @Failure 409 {string} string "Error message"
+// @Failure 412 {string} string "The version is stale"
+// @Failure 428 {string} string "The version is missing"
 // @Failure 500 {string} string "Error message"
-// @Router /services [put]
+// @Router /services/{service_id} [put]
 func (h *Handler) UpdateService(rw http.ResponseWriter, req *http.Request) {
 	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
 
@@ -116,8 +211,50 @@ func (h *Handler) UpdateService(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// the versioned route takes the ID from the path
+	if id, ok := mux.Vars(req)["service_id"]; ok {
+		service.ID = id
+	}
+
+	service.Name = strings.TrimSpace(service.Name)
+	validator := validator.New()
+	if err := validator.Struct(service); err != nil {
+		h.logger.Error("server::UpdateService::Struct", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validator.Var(service.ID, "required"); err != nil {
+		h.logger.Error("server::UpdateService::Var", err)
+		http.Error(rw, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	version, err := expectedVersion(req, service.Version)
+	if err != nil {
+		h.logger.Error("server::UpdateService::expectedVersion", err)
+		if errors.Is(err, errVersionRequired) {
+			http.Error(rw, err.Error(), http.StatusPreconditionRequired)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	service.Version = version
+
 	if err := h.DBAdapter.UpdateService(service); err != nil {
 		h.logger.Error("server::UpdateService::UpdateService")
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			http.Error(rw, err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, dbadapter.ErrVersionMismatch) {
+			http.Error(rw, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -129,14 +266,18 @@ func (h *Handler) UpdateService(rw http.ResponseWriter, req *http.Request) {
 // @Summary Update master
 // @Description Update master data in the system
 // @Tags Master
+// @Param master_id path string true "ID of the master"
 // @Param service body entities.MasterLong true "Master data"
+// @Param If-Match header string false "Version of the master the update is based on, required unless the body has it"
 // @Accept json
 // @Produce json
 // @Success 200 {object} ID "ID of the updated master"
 // @Failure 400 {string} string "Error message"
 // @Failure 404 {string} string "Error message"
+// @Failure 412 {string} string "The version is stale"
+// @Failure 428 {string} string "The version is missing"
 // @Failure 500 {string} string "Error message"
-// @Router /masters [put]
+// @Router /masters/{master_id} [put]
 func (h *Handler) UpdateMaster(rw http.ResponseWriter, req *http.Request) {
 	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
 
@@ -154,6 +295,11 @@ func (h *Handler) UpdateMaster(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// the versioned route takes the ID from the path
+	if id, ok := mux.Vars(req)["master_id"]; ok {
+		master.ID = id
+	}
+
 	validator := validator.New()
 	if err := validator.Struct(master); err != nil {
 		h.logger.Error("server::UpdateMaster::Struct", err)
@@ -161,8 +307,28 @@ func (h *Handler) UpdateMaster(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	version, err := expectedVersion(req, master.Version)
+	if err != nil {
+		h.logger.Error("server::UpdateMaster::expectedVersion", err)
+		if errors.Is(err, errVersionRequired) {
+			http.Error(rw, err.Error(), http.StatusPreconditionRequired)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	master.Version = version
+
 	if err := h.DBAdapter.UpdateMaster(master); err != nil {
 		h.logger.Error("server::UpdateMaster::UpdateMaster")
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, dbadapter.ErrVersionMismatch) {
+			http.Error(rw, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -170,18 +336,19 @@ func (h *Handler) UpdateMaster(rw http.ResponseWriter, req *http.Request) {
 	rw.Header().Set("Content-Type", "application/json")
 	rw.WriteHeader(http.StatusOK)
 	if _, err := rw.Write([]byte(fmt.Sprintf(`{ "id" : "%s" }`, master.ID))); err != nil {
-		h.logger.Errorf("server::ApproveMaster::Write: %s", err.Error())
+		h.logger.Errorf("server::UpdateMaster::Write: %s", err.Error())
 		return
 	}
 	h.logger.Info("Response sent")
 }
 
 // @Summary Update master image
-// @Description Update an image of a master in the system
+// @Description Update an image of a master in the system. The master replacing an image of an approved listing at /masters/self sends the replacement for review, the old image stays until the revision is approved
 // @Tags Master
 // @Param master_id path string true "ID of the master"
 // @Param image_name path string true "Name of the image"
 // @Param file formData file true "Updated image"
+// @Param X-Telegram-User-ID header int false "Telegram user ID, selects the master on the /masters/self routes"
 // @Accept multipart/form-data
 // @Produce json
 // @Success 204
@@ -189,6 +356,7 @@ func (h *Handler) UpdateMaster(rw http.ResponseWriter, req *http.Request) {
 // @Failure 404 {string} string "Error message"
 // @Failure 500 {string} string "Error message"
 // @Router /masters/{master_id}/images/{image_name} [put]
+// @Router /masters/self/images/{image_name} [put]
 func (h *Handler) UpdateMasterImage(rw http.ResponseWriter, req *http.Request) {
 	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
 
@@ -210,14 +378,379 @@ func (h *Handler) UpdateMasterImage(rw http.ResponseWriter, req *http.Request) {
 	}
 	defer formFile.Close()
 
-	if err := h.MinIOAdapter.PutMasterImage(masterID, uuid.NewString(), formFile, meta.Size, meta.Header.Get("Content-Type")); err != nil {
+	image, err := readImageMeta(formFile, meta)
+	if err != nil {
+		h.logger.Error("server::UpdateMasterImage::readImageMeta", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	image.Name = uuid.NewString()
+	objectKey := storage.MasterImageKey(masterID, image.Name)
+
+	if err := h.ImageStorage.PutMasterImage(objectKey, formFile, image.Size, image.ContentType); err != nil {
 		h.logger.Error("server::UpdateMasterImage::PutMasterImage", err)
 		http.Error(rw, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	if err := h.MinIOAdapter.DeleteMasterImage(masterID, imageName); err != nil {
-		h.logger.Error("server::UpdateMasterImage::DeleteMasterImage", err)
+	oldObjectKey, err := h.DBAdapter.ReplaceMasterImage(masterID, imageName, objectKey, image, h.byMaster(req))
+	if err != nil {
+		h.logger.Error("server::UpdateMasterImage::ReplaceMasterImage", err)
+		if err := h.ImageStorage.DeleteMasterImage(objectKey); err != nil {
+			h.logger.Error("server::UpdateMasterImage::DeleteMasterImage", err)
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// a replacement waiting for review keeps the old object until it is approved
+	if len(oldObjectKey) != 0 {
+		if err := h.ImageStorage.DeleteMasterImage(oldObjectKey); err != nil {
+			h.logger.Error("server::UpdateMasterImage::DeleteMasterImage", err)
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+	h.logger.Info("Response sent")
+}
+
+// @Summary Reorder master images
+// @Description Change the order of the master images, all image names must be listed
+// @Tags Master
+// @Param master_id path string true "ID of the master"
+// @Param order body ImageOrder true "Image names in the new order"
+// @Param X-Telegram-User-ID header int false "Telegram user ID, selects the master on the /masters/self routes"
+// @Accept json
+// @Produce json
+// @Success 204
+// @Failure 400 {string} string "Error message"
+// @Failure 404 {string} string "Error message"
+// @Failure 500 {string} string "Error message"
+// @Router /masters/{master_id}/images/order [put]
+// @Router /masters/self/images/order [put]
+func (h *Handler) ReorderMasterImages(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	params := mux.Vars(req)
+	masterID := params["master_id"]
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		h.logger.Error("server::ReorderMasterImages::ReadAll", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order := &ImageOrder{}
+	if err := json.Unmarshal(body, order); err != nil {
+		h.logger.Error("server::ReorderMasterImages::Unmarshal", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	validator := validator.New()
+	if err := validator.Struct(order); err != nil {
+		h.logger.Error("server::ReorderMasterImages::Struct", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DBAdapter.ReorderMasterImages(masterID, order.Names); err != nil {
+		h.logger.Error("server::ReorderMasterImages::ReorderMasterImages", err)
+		if errors.Is(err, dbadapter.ErrInvalidOrder) {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+	h.logger.Info("Response sent")
+}
+
+// @Summary Update master image caption
+// @Description Change the caption of the master image. At /masters/self the new caption of an approved listing waits for the review of a revision
+// @Tags Master
+// @Param master_id path string true "ID of the master"
+// @Param image_name path string true "Name of the image"
+// @Param caption body Caption true "New caption"
+// @Param X-Telegram-User-ID header int false "Telegram user ID, selects the master on the /masters/self routes"
+// @Accept json
+// @Produce json
+// @Success 204
+// @Failure 400 {string} string "Error message"
+// @Failure 404 {string} string "Error message"
+// @Failure 500 {string} string "Error message"
+// @Router /masters/{master_id}/images/{image_name}/caption [put]
+// @Router /masters/self/images/{image_name}/caption [put]
+func (h *Handler) UpdateMasterImageCaption(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	params := mux.Vars(req)
+	masterID := params["master_id"]
+	imageName := params["image_name"]
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		h.logger.Error("server::UpdateMasterImageCaption::ReadAll", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	caption := &Caption{}
+	if err := json.Unmarshal(body, caption); err != nil {
+		h.logger.Error("server::UpdateMasterImageCaption::Unmarshal", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DBAdapter.UpdateMasterImageCaption(masterID, imageName, caption.Caption, h.byMaster(req)); err != nil {
+		h.logger.Error("server::UpdateMasterImageCaption::UpdateMasterImageCaption", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+	h.logger.Info("Response sent")
+}
+
+// @Summary Save client
+// @Description Create the bot user profile or update its language and default city, the fields missing from the body keep their values
+// @Tags Client
+// @Param telegram_id path int true "Telegram user ID"
+// @Param X-Telegram-User-ID header int true "Telegram user ID the bot acts for, must match telegram_id"
+// @Param client body entities.Client true "Client preferences"
+// @Accept json
+// @Produce json
+// @Success 200 {object} entities.Client
+// @Failure 400 {string} string "Error message"
+// @Failure 401 {string} string "Error message"
+// @Failure 403 {string} string "Error message"
+// @Failure 404 {string} string "Error message"
+// @Failure 500 {string} string "Error message"
+// @Router /clients/{telegram_id} [put]
+func (h *Handler) SaveClient(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	params := mux.Vars(req)
+	telegramID, err := strconv.ParseInt(params["telegram_id"], 10, 64)
+	if err != nil {
+		h.logger.Error("server::SaveClient::ParseInt", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		h.logger.Error("server::SaveClient::ReadAll", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	client := &entities.Client{}
+	if err := json.Unmarshal(body, client); err != nil {
+		h.logger.Error("server::SaveClient::Unmarshal", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	client.TelegramID = telegramID
+
+	// the fields missing from the body keep their values
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(body, &fields); err != nil {
+		h.logger.Error("server::SaveClient::Unmarshal", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	columns := make([]string, 0)
+	if _, ok := fields["language"]; ok {
+		columns = append(columns, dbadapter.ClientLanguage)
+	}
+	if _, ok := fields["defaultCityID"]; ok {
+		columns = append(columns, dbadapter.ClientDefaultCity)
+	}
+
+	saved, err := h.DBAdapter.SaveClient(client, columns)
+	if err != nil {
+		h.logger.Error("server::SaveClient::SaveClient", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, "unknown default city", http.StatusNotFound)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	clientResp, err := json.Marshal(saved)
+	if err != nil {
+		h.logger.Error("server::SaveClient::Marshal", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	if _, err := rw.Write(clientResp); err != nil {
+		h.logger.Error("server::SaveClient::Write", err)
+		return
+	}
+	h.logger.Info("Response sent")
+}
+
+// @Summary Update own master profile
+// @Description Update the profile of the master linked to the Telegram user. Changes of an approved profile are saved as a revision and go live after an admin approves them.
+// @Tags Master
+// @Param X-Telegram-User-ID header int true "Telegram user ID"
+// @Param form body entities.Master true "Master data, the status is ignored"
+// @Param If-Match header string false "Version of the master the update is based on, required unless the body has it"
+// @Accept json
+// @Produce json
+// @Success 200 {object} ID "ID of the updated master"
+// @Success 202 {object} ID "ID of the revision waiting for approval"
+// @Failure 400 {string} string "Error message"
+// @Failure 401 {string} string "Error message"
+// @Failure 404 {string} string "Error message"
+// @Failure 412 {string} string "The version is stale"
+// @Failure 428 {string} string "The version is missing"
+// @Failure 500 {string} string "Error message"
+// @Router /masters/self [put]
+func (h *Handler) UpdateSelfMaster(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	params := mux.Vars(req)
+	masterID := params["master_id"]
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		h.logger.Error("server::UpdateSelfMaster::ReadAll", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	update := &entities.Master{}
+	if err := json.Unmarshal(body, update); err != nil {
+		h.logger.Error("server::UpdateSelfMaster::Unmarshal", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	update.Status = entities.PENDING
+
+	validator := validator.New()
+	if err := validator.Struct(update); err != nil {
+		h.logger.Error("server::UpdateSelfMaster::Struct", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	version, err := expectedVersion(req, update.Version)
+	if err != nil {
+		h.logger.Error("server::UpdateSelfMaster::expectedVersion", err)
+		if errors.Is(err, errVersionRequired) {
+			http.Error(rw, err.Error(), http.StatusPreconditionRequired)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	update.Version = version
+
+	master, err := h.DBAdapter.GetMaster(masterID)
+	if err != nil {
+		h.logger.Error("server::UpdateSelfMaster::GetMaster", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	id := masterID
+	if master.Status == entities.APPROVED {
+		// the live listing stays unchanged until the revision is approved
+		if id, err = h.DBAdapter.SaveMasterRevision(masterID, update); err != nil {
+			h.logger.Error("server::UpdateSelfMaster::SaveMasterRevision", err)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				http.Error(rw, err.Error(), http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, dbadapter.ErrVersionMismatch) {
+				http.Error(rw, err.Error(), http.StatusPreconditionFailed)
+				return
+			}
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		status = http.StatusAccepted
+	} else {
+		if err := h.DBAdapter.UpdateMaster(&entities.MasterLong{ID: masterID, Master: *update}); err != nil {
+			h.logger.Error("server::UpdateSelfMaster::UpdateMaster", err)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				http.Error(rw, err.Error(), http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, dbadapter.ErrVersionMismatch) {
+				http.Error(rw, err.Error(), http.StatusPreconditionFailed)
+				return
+			}
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	if _, err := rw.Write([]byte(fmt.Sprintf(`{ "id" : "%s" }`, id))); err != nil {
+		h.logger.Error("server::UpdateSelfMaster::Write", err)
+		return
+	}
+	h.logger.Info("Response sent")
+}
+
+// @Summary Add master to favorites
+// @Description Add the approved master to the client favorites, adding the same master again does nothing. Used by bot.
+// @Tags Client
+// @Param telegram_id path int true "Telegram user ID"
+// @Param X-Telegram-User-ID header int true "Telegram user ID the bot acts for, must match telegram_id"
+// @Param master_id path string true "ID of the master"
+// @Accept json
+// @Produce json
+// @Success 204
+// @Failure 400 {string} string "Error message"
+// @Failure 401 {string} string "Error message"
+// @Failure 403 {string} string "Error message"
+// @Failure 404 {string} string "Error message"
+// @Failure 500 {string} string "Error message"
+// @Router /clients/{telegram_id}/favorites/{master_id} [put]
+func (h *Handler) SaveFavorite(rw http.ResponseWriter, req *http.Request) {
+	h.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	params := mux.Vars(req)
+	telegramID, err := strconv.ParseInt(params["telegram_id"], 10, 64)
+	if err != nil {
+		h.logger.Error("server::SaveFavorite::ParseInt", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.DBAdapter.SaveFavorite(telegramID, params["master_id"]); err != nil {
+		h.logger.Error("server::SaveFavorite::SaveFavorite", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(rw, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(rw, err.Error(), http.StatusInternalServerError)
 		return
 	}
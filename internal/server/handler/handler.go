@@ -1,24 +1,86 @@
+// @title Bot API
+// @version 1.0
+// @description The unversioned routes are deprecated aliases of the /api/v1 routes, only cities, service categories, services and masters are versioned
+// @BasePath /api/v1
 package handler
 
 import (
 	"bot/internal/config"
 	"bot/internal/dbadapter"
 	"bot/internal/logger"
-	"bot/internal/minioadapter"
+	middleware "bot/internal/server/middleware"
+	"bot/internal/storage"
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
 )
 
 type Handler struct {
 	logger       logger.Logger
 	cfg          *config.Config
 	DBAdapter    *dbadapter.DBAdapter
-	MinIOAdapter *minioadapter.MinIOAdapter
+	ImageStorage storage.ImageStorage
+	auth         *middleware.Auth
 }
 
-func NewHandler(logger logger.Logger, cfg *config.Config, DBAdapter *dbadapter.DBAdapter, MinIOAdapter *minioadapter.MinIOAdapter) *Handler {
+func NewHandler(logger logger.Logger, cfg *config.Config, DBAdapter *dbadapter.DBAdapter, ImageStorage storage.ImageStorage) *Handler {
 	return &Handler{
 		logger:       logger,
 		cfg:          cfg,
 		DBAdapter:    DBAdapter,
-		MinIOAdapter: MinIOAdapter,
+		ImageStorage: ImageStorage,
+		auth:         middleware.NewAuth(cfg),
 	}
 }
+
+// Self resolves the master linked to the Telegram user from the request
+// and passes its ID to the handler as the master_id path variable
+func (h *Handler) Self(next http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+
+		telegramID, err := getTelegramID(req)
+		if err != nil || telegramID == 0 {
+			h.logger.Error("server::Self::getTelegramID", err)
+			http.Error(rw, "invalid Telegram user ID", http.StatusBadRequest)
+			return
+		}
+
+		master, err := h.DBAdapter.GetMasterByTelegramID(telegramID)
+		if err != nil {
+			h.logger.Error("server::Self::GetMasterByTelegramID", err)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				http.Error(rw, "no master is linked to this Telegram user", http.StatusNotFound)
+				return
+			}
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		vars := make(map[string]string)
+		for key, value := range mux.Vars(req) {
+			vars[key] = value
+		}
+		vars["master_id"] = master.ID
+
+		ctx := context.WithValue(req.Context(), selfKey{}, true)
+		next(rw, mux.SetURLVars(req.WithContext(ctx), vars))
+	}
+}
+
+type selfKey struct{}
+
+// isSelf tells that the master acts on its own listing, the image changes
+// of an approved listing wait for a review then
+func isSelf(req *http.Request) bool {
+	self, _ := req.Context().Value(selfKey{}).(bool)
+	return self
+}
+
+// byMaster tells that the image change doesn't come from the control panel. The uploads without
+// a key are the ones of the registration form, they go live only until the listing is approved
+func (h *Handler) byMaster(req *http.Request) bool {
+	return isSelf(req) || !h.auth.IsAdmin(req)
+}
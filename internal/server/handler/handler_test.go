@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"bot/internal/config"
+	"bot/internal/logger"
+	middleware "bot/internal/server/middleware"
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+// the images of an approved master change without a review only through the control panel
+func TestImageChangesByMaster(t *testing.T) {
+
+	h := NewHandler(logger.NewLogger(), &config.Config{AdminKeys: []string{"admin"}, BotKeys: []string{"bot"}}, nil, nil)
+
+	tests := map[string]struct {
+		key  string
+		self bool
+		want bool
+	}{
+		"anonymous":   {want: true},
+		"bot":         {key: "bot", want: true},
+		"admin":       {key: "admin", want: false},
+		"self":        {key: "bot", self: true, want: true},
+		"admin, self": {key: "admin", self: true, want: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/masters/1/images", nil)
+			req.Header.Set(middleware.APIKeyHeader, test.key)
+			if test.self {
+				req = req.WithContext(context.WithValue(req.Context(), selfKey{}, true))
+			}
+			if got := h.byMaster(req); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -1,11 +1,35 @@
 package handler
 
 import (
+	"bot/internal/config"
+	"bot/internal/dbadapter"
+	"bot/internal/entities"
+	"bot/internal/mergepatch"
+	middleware "bot/internal/server/middleware"
+	"bot/internal/storage"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"golang.org/x/exp/constraints"
 )
 
+// streamPing is the interval of the keep-alive comments of the event stream
+const streamPing = 30 * time.Second
+
 func getParam[T constraints.Integer](param string, defaultValue T) (T, error) {
 	if len(param) == 0 {
 		return defaultValue, nil
@@ -24,9 +48,280 @@ type ID struct {
 }
 
 type Name struct {
-	Name string `json:"name"`
+	Name string `json:"name" validate:"required,max=100"`
 }
 
 type URL struct {
 	URL string `json:"url"`
 }
+
+type Caption struct {
+	Caption string `json:"caption"`
+}
+
+type UploadRequest struct {
+	ContentType string `json:"contentType" validate:"required"`
+	Size        int64  `json:"size" validate:"required,gt=0"`
+}
+
+type UploadURL struct {
+	Name      string            `json:"name"`
+	URL       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+}
+
+type ImageOrder struct {
+	Names []string `json:"names" validate:"required"`
+}
+
+func (h *Handler) fillMasters(masters []*entities.MasterShort) error {
+	return FillMasters(h.DBAdapter, h.ImageStorage, masters)
+}
+
+// FillMasters sets the image links, cover first, and the view counts of the masters
+func FillMasters(DBAdapter *dbadapter.DBAdapter, ImageStorage storage.ImageStorage, masters []*entities.MasterShort) error {
+
+	masterIDs := make([]string, 0)
+	for _, master := range masters {
+		masterIDs = append(masterIDs, master.ID)
+	}
+
+	images, err := DBAdapter.GetMastersImages(masterIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, master := range masters {
+		master.Images = make([]string, 0)
+		for _, image := range images[master.ID] {
+			url, err := ImageStorage.GetImageURL(image.ObjectKey)
+			if err != nil {
+				return err
+			}
+			master.Images = append(master.Images, url)
+		}
+	}
+
+	views, err := DBAdapter.GetMastersViews(masterIDs)
+	if err != nil {
+		return err
+	}
+	for _, master := range masters {
+		master.Views = views[master.ID]
+	}
+	return nil
+}
+
+// attachPendingImages lists the image changes waiting for review with the pending revisions
+func (h *Handler) attachPendingImages(revisions []*entities.MasterRevision) error {
+
+	masterIDs := make([]string, 0)
+	for _, revision := range revisions {
+		if revision.Status == entities.PENDING {
+			masterIDs = append(masterIDs, revision.MasterID)
+		}
+	}
+
+	images, err := h.DBAdapter.GetPendingImages(masterIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, revision := range revisions {
+		if revision.Status != entities.PENDING {
+			continue
+		}
+		for _, image := range images[revision.MasterID] {
+			if image.URL, err = h.ImageStorage.GetImageURL(image.ObjectKey); err != nil {
+				return err
+			}
+			revision.Images = append(revision.Images, image)
+		}
+	}
+	return nil
+}
+
+// getTelegramID reads the ID of the Telegram user the bot acts for, 0 if the header is absent
+func getTelegramID(req *http.Request) (int64, error) {
+	header := req.Header.Get(middleware.TelegramUserIDHeader)
+	if len(header) == 0 {
+		return 0, nil
+	}
+	return strconv.ParseInt(header, 10, 64)
+}
+
+// readImageMeta detects the content type and dimensions of the uploaded file,
+// the dimensions are left empty for formats the decoder doesn't know
+func readImageMeta(file multipart.File, header *multipart.FileHeader) (*entities.Image, error) {
+
+	meta := &entities.Image{
+		ContentType: header.Header.Get("Content-Type"),
+		Size:        header.Size,
+	}
+
+	if len(meta.ContentType) == 0 || meta.ContentType == "application/octet-stream" {
+		sniff := make([]byte, 512)
+		n, err := io.ReadFull(file, sniff)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+		meta.ContentType = http.DetectContentType(sniff[:n])
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+
+	if config, _, err := image.DecodeConfig(file); err == nil {
+		meta.Width = config.Width
+		meta.Height = config.Height
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return meta, nil
+}
+
+func etag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return fmt.Sprintf(`"%s"`, hex.EncodeToString(sum[:16]))
+}
+
+func etagMatches(ifNoneMatch, tag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+var errVersionRequired = errors.New("the If-Match header or the version field is required")
+
+func versionTag(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// expectedVersion returns the version the update is based on, the If-Match header
+// takes precedence over the version in the body. "If-Match: *" skips the check.
+func expectedVersion(req *http.Request, version int64) (int64, error) {
+	ifMatch := strings.TrimSpace(req.Header.Get("If-Match"))
+	if len(ifMatch) == 0 {
+		if version <= 0 {
+			return 0, errVersionRequired
+		}
+		return version, nil
+	}
+
+	if ifMatch == "*" {
+		return 0, nil
+	}
+
+	tag := strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`)
+	expected, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || expected <= 0 {
+		return 0, fmt.Errorf("invalid If-Match header: %s", ifMatch)
+	}
+	return expected, nil
+}
+
+func isMergePatch(contentType string) bool {
+	if len(contentType) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == mergepatch.ContentType || mediaType == "application/json")
+}
+
+// applyPatch replaces the entity with the result of the JSON merge patch,
+// the errors of the patch and of the patched entity wrap mergepatch.ErrInvalidPatch
+func applyPatch[T any](entity *T, patch []byte) error {
+
+	doc, err := json.Marshal(entity)
+	if err != nil {
+		return err
+	}
+
+	patched, err := mergepatch.Apply(doc, patch)
+	if err != nil {
+		return err
+	}
+
+	result := new(T)
+	if err := json.Unmarshal(patched, result); err != nil {
+		return fmt.Errorf("%w: %s", mergepatch.ErrInvalidPatch, err)
+	}
+
+	*entity = *result
+	return nil
+}
+
+// writeVersioned writes a JSON body of a single record with its version as the ETag,
+// answers 304 if the client already has this version
+func writeVersioned(rw http.ResponseWriter, req *http.Request, body []byte, version int64) error {
+	tag := versionTag(version)
+	rw.Header().Set("ETag", tag)
+
+	if ifNoneMatch := req.Header.Get("If-None-Match"); len(ifNoneMatch) != 0 && etagMatches(ifNoneMatch, tag) {
+		rw.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	_, err := rw.Write(body)
+	return err
+}
+
+// writeCached writes a JSON body with ETag and Cache-Control headers,
+// answers 304 if the client already has the same representation
+func (h *Handler) writeCached(rw http.ResponseWriter, req *http.Request, body []byte) error {
+	tag := etag(body)
+	rw.Header().Set("ETag", tag)
+	rw.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", h.cfg.CacheMaxAge))
+
+	if ifNoneMatch := req.Header.Get("If-None-Match"); len(ifNoneMatch) != 0 && etagMatches(ifNoneMatch, tag) {
+		rw.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	_, err := rw.Write(body)
+	return err
+}
+
+func (h *Handler) checkImage(contentType string, size int64) error {
+	return CheckImage(h.cfg, contentType, size)
+}
+
+// CheckImage checks the image against the size limit and the content types of the config
+func CheckImage(cfg *config.Config, contentType string, size int64) error {
+
+	if size > cfg.MaxImageSize {
+		return fmt.Errorf("image is too large: %d bytes, the limit is %d", size, cfg.MaxImageSize)
+	}
+
+	for _, allowed := range cfg.ImageTypes {
+		if contentType == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported content type: %s", contentType)
+}
+
+// writeEvent writes the moderation event in the server-sent events format
+func writeEvent(rw http.ResponseWriter, event *entities.ModerationEvent) error {
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(rw, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
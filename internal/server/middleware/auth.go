@@ -0,0 +1,128 @@
+package server
+
+import (
+	"bot/internal/config"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// Auth checks the API keys from the config. With no keys configured
+// every request is let through, as before the keys were introduced,
+// except for the requests on behalf of a Telegram user.
+type Auth struct {
+	adminKeys map[string]bool
+	botKeys   map[string]bool
+}
+
+func NewAuth(cfg *config.Config) *Auth {
+
+	auth := &Auth{adminKeys: make(map[string]bool), botKeys: make(map[string]bool)}
+	for _, key := range cfg.AdminKeys {
+		auth.adminKeys[key] = true
+	}
+	for _, key := range cfg.BotKeys {
+		auth.botKeys[key] = true
+	}
+	return auth
+}
+
+func (a *Auth) Enabled() bool {
+	return len(a.adminKeys) != 0 || len(a.botKeys) != 0
+}
+
+func (a *Auth) IsAdmin(req *http.Request) bool {
+	return a.IsAdminKey(req.Header.Get(APIKeyHeader))
+}
+
+func (a *Auth) IsBot(req *http.Request) bool {
+	return a.IsBotKey(req.Header.Get(APIKeyHeader))
+}
+
+// IsAdminKey checks the key sent outside of an HTTP request, e.g. in the gRPC metadata
+func (a *Auth) IsAdminKey(key string) bool {
+	return !a.Enabled() || a.adminKeys[key]
+}
+
+func (a *Auth) IsBotKey(key string) bool {
+	return !a.Enabled() || a.botKeys[key] || a.adminKeys[key]
+}
+
+// IsUserKey checks that the key may act for a Telegram user. Without configured keys
+// nobody may, anybody could send the user ID of somebody else
+func (a *Auth) IsUserKey(key string) bool {
+	return a.Enabled() && (a.botKeys[key] || a.adminKeys[key])
+}
+
+// Admin lets through the control panel only
+func (a *Auth) Admin(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if !a.IsAdmin(req) {
+			http.Error(rw, "admin API key required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(rw, req)
+	})
+}
+
+// AdminStream lets through the control panel like Admin, the key may also come in APIKeyParam
+// since a browser EventSource can't set headers. The key is removed from the URL before it is logged
+func (a *Auth) AdminStream(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		key := req.Header.Get(APIKeyHeader)
+		if len(key) == 0 {
+			key = query.Get(APIKeyParam)
+		}
+		if !a.IsAdminKey(key) {
+			http.Error(rw, "admin API key required", http.StatusUnauthorized)
+			return
+		}
+
+		query.Del(APIKeyParam)
+		withoutKey := *req.URL
+		withoutKey.RawQuery = query.Encode()
+		req = req.Clone(req.Context())
+		req.URL = &withoutKey
+		next.ServeHTTP(rw, req)
+	})
+}
+
+// Bot lets through the bot acting on behalf of a Telegram user,
+// the user is identified by TelegramUserIDHeader which only the bot may set
+func (a *Auth) Bot(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if !a.IsUserKey(req.Header.Get(APIKeyHeader)) {
+			http.Error(rw, "bot API key required", http.StatusUnauthorized)
+			return
+		}
+		if len(req.Header.Get(TelegramUserIDHeader)) == 0 {
+			http.Error(rw, TelegramUserIDHeader+" header required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(rw, req)
+	})
+}
+
+// Client lets through the bot acting on behalf of the Telegram user of the telegram_id path variable,
+// a user may only read and change their own profile and favorites
+func (a *Auth) Client(next http.HandlerFunc) http.Handler {
+	return a.Bot(func(rw http.ResponseWriter, req *http.Request) {
+		if mux.Vars(req)["telegram_id"] != req.Header.Get(TelegramUserIDHeader) {
+			http.Error(rw, "the Telegram user ID doesn't match "+TelegramUserIDHeader, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(rw, req)
+	})
+}
+
+// BotKey lets through the bot acting on its own behalf
+func (a *Auth) BotKey(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if !a.IsBot(req) {
+			http.Error(rw, "bot API key required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(rw, req)
+	})
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"bot/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestBotRequiresConfiguredKey(t *testing.T) {
+
+	withKeys := NewAuth(&config.Config{BotKeys: []string{"bot"}})
+	withoutKeys := NewAuth(&config.Config{})
+
+	tests := []struct {
+		name string
+		auth *Auth
+		key  string
+		want int
+	}{
+		{"bot key", withKeys, "bot", http.StatusOK},
+		{"unknown key", withKeys, "guess", http.StatusUnauthorized},
+		// anybody could send the user ID of somebody else
+		{"no keys configured", withoutKeys, "", http.StatusUnauthorized},
+	}
+
+	ok := func(rw http.ResponseWriter, req *http.Request) {}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/masters/self", nil)
+			req.Header.Set(APIKeyHeader, test.key)
+			req.Header.Set(TelegramUserIDHeader, "42")
+
+			rec := httptest.NewRecorder()
+			test.auth.Bot(ok).ServeHTTP(rec, req)
+			if rec.Code != test.want {
+				t.Errorf("got %d, want %d", rec.Code, test.want)
+			}
+		})
+	}
+}
+
+func TestClientMatchesUser(t *testing.T) {
+
+	auth := NewAuth(&config.Config{BotKeys: []string{"bot"}})
+	router := mux.NewRouter()
+	router.Handle("/clients/{telegram_id}", auth.Client(func(rw http.ResponseWriter, req *http.Request) {}))
+
+	tests := []struct {
+		name   string
+		key    string
+		userID string
+		want   int
+	}{
+		{"own profile", "bot", "42", http.StatusOK},
+		{"profile of another user", "bot", "43", http.StatusForbidden},
+		{"no bot key", "", "42", http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/clients/42", nil)
+			req.Header.Set(APIKeyHeader, test.key)
+			req.Header.Set(TelegramUserIDHeader, test.userID)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != test.want {
+				t.Errorf("got %d, want %d", rec.Code, test.want)
+			}
+		})
+	}
+}
+
+// a browser EventSource can't set headers, the key comes in the query and doesn't reach the logs
+func TestAdminStreamKeyInQuery(t *testing.T) {
+
+	auth := NewAuth(&config.Config{AdminKeys: []string{"admin"}})
+
+	tests := []struct {
+		name   string
+		target string
+		header string
+		want   int
+	}{
+		{"key in the query", "/api/v1/events/stream?api_key=admin&x=1", "", http.StatusOK},
+		{"key in the header", "/api/v1/events/stream", "admin", http.StatusOK},
+		{"wrong key in the query", "/api/v1/events/stream?api_key=guess", "", http.StatusUnauthorized},
+		{"no key", "/api/v1/events/stream", "", http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", test.target, nil)
+			if len(test.header) != 0 {
+				req.Header.Set(APIKeyHeader, test.header)
+			}
+
+			var query string
+			rec := httptest.NewRecorder()
+			auth.AdminStream(func(rw http.ResponseWriter, req *http.Request) {
+				query = req.URL.RawQuery
+			}).ServeHTTP(rec, req)
+
+			if rec.Code != test.want {
+				t.Errorf("got %d, want %d", rec.Code, test.want)
+			}
+			if strings.Contains(query, APIKeyParam) {
+				t.Errorf("the handler got the key in %q", query)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package server
+
+import "net/http"
+
+// APIPrefix is the path prefix of the current API version
+const APIPrefix = "/api/v1"
+
+// Deprecated marks the responses of the unversioned routes, they stay until the clients move to APIPrefix
+func Deprecated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Deprecation", "true")
+		rw.Header().Set("Link", "<"+APIPrefix+">; rel=\"successor-version\"")
+		next.ServeHTTP(rw, req)
+	})
+}
@@ -6,8 +6,9 @@ func CorsMiddlware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
 
 		rw.Header().Set("Access-Control-Allow-Origin", "*")
-		rw.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		rw.Header().Set("Access-Control-Allow-Headers", "Origin, Authorization, Content-Type, Accept")
+		rw.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		rw.Header().Set("Access-Control-Allow-Headers", "Origin, Authorization, Content-Type, Accept, If-None-Match, If-Match, "+APIKeyHeader+", "+TelegramUserIDHeader)
+		rw.Header().Set("Access-Control-Expose-Headers", "ETag, Retry-After, Deprecation, Link")
 
 		if req.Method == http.MethodOptions {
 			return
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"bot/internal/config"
+	"bot/internal/logger"
+	"bot/internal/ratelimiter"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	APIKeyHeader         = "X-API-Key"
+	TelegramUserIDHeader = "X-Telegram-User-ID"
+	// APIKeyParam carries the key of the clients that can't set headers, e.g. EventSource
+	APIKeyParam = "api_key"
+)
+
+func RateLimitMiddleware(logger logger.Logger, store ratelimiter.Store, limits []config.RateLimit, auth *Auth) mux.MiddlewareFunc {
+
+	routeLimits := make(map[string]ratelimiter.Limit)
+	for _, limit := range limits {
+		routeLimits[limit.Method+" "+limit.Path] = ratelimiter.Limit{Rate: limit.Rate, Burst: limit.Burst}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+
+			route := mux.CurrentRoute(req)
+			if route == nil {
+				next.ServeHTTP(rw, req)
+				return
+			}
+
+			template, err := route.GetPathTemplate()
+			if err != nil {
+				next.ServeHTTP(rw, req)
+				return
+			}
+
+			// the limits are configured by the unversioned paths, they cover both routers
+			routeKey := req.Method + " " + strings.TrimPrefix(template, APIPrefix)
+			limit, ok := routeLimits[routeKey]
+			if !ok {
+				next.ServeHTTP(rw, req)
+				return
+			}
+
+			client := clientKey(req, auth)
+			allowed, retryAfter, err := store.Take(routeKey+" "+client, limit)
+			if err != nil {
+				// the limiter must not take the API down with it
+				logger.Error("server::RateLimitMiddleware::Take", err)
+				next.ServeHTTP(rw, req)
+				return
+			}
+
+			if !allowed {
+				logger.Infof("Rate limit exceeded: %s %s", routeKey, client)
+				rw.Header().Set("Retry-After", fmt.Sprintf("%d", int64(math.Ceil(retryAfter.Seconds()))))
+				http.Error(rw, "too many requests", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(rw, req)
+		})
+	}
+}
+
+// clientKey identifies the bot by the Telegram user it acts for, then the API key, the others by IP.
+// The headers are only trusted with a configured key, anybody could send them to get a fresh limit
+func clientKey(req *http.Request, auth *Auth) string {
+	if key := req.Header.Get(APIKeyHeader); auth.IsUserKey(key) {
+		if userID := req.Header.Get(TelegramUserIDHeader); len(userID) != 0 {
+			return "tg:" + userID
+		}
+		return "key:" + key
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	return "ip:" + strings.TrimSpace(host)
+}
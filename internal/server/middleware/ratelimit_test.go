@@ -0,0 +1,46 @@
+package server
+
+import (
+	"bot/internal/config"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientKey(t *testing.T) {
+
+	withKeys := NewAuth(&config.Config{AdminKeys: []string{"admin"}, BotKeys: []string{"bot"}})
+	withoutKeys := NewAuth(&config.Config{})
+
+	tests := []struct {
+		name   string
+		auth   *Auth
+		key    string
+		userID string
+		want   string
+	}{
+		{"bot acting for a user", withKeys, "bot", "42", "tg:42"},
+		{"bot on its own", withKeys, "bot", "", "key:bot"},
+		{"admin", withKeys, "admin", "", "key:admin"},
+		{"unknown key", withKeys, "guess", "", "ip:192.0.2.1"},
+		{"user ID without a key", withKeys, "", "42", "ip:192.0.2.1"},
+		{"user ID with an unknown key", withKeys, "guess", "42", "ip:192.0.2.1"},
+		{"no keys configured", withoutKeys, "any", "42", "ip:192.0.2.1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/masters", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			if len(test.key) != 0 {
+				req.Header.Set(APIKeyHeader, test.key)
+			}
+			if len(test.userID) != 0 {
+				req.Header.Set(TelegramUserIDHeader, test.userID)
+			}
+
+			if got := clientKey(req, test.auth); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"bot/internal/logger"
+	corsMiddleware "bot/internal/server/middleware"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type openAPI struct {
+	BasePath string                                `json:"basePath"`
+	Paths    map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+// CheckRoutes compares the versioned routes of the router with the operations of the OpenAPI document,
+// it returns the routes missing from the document and the operations the router doesn't serve
+func CheckRoutes(router *mux.Router, spec []byte) ([]string, error) {
+
+	doc := &openAPI{}
+	if err := json.Unmarshal(spec, doc); err != nil {
+		return nil, err
+	}
+
+	documented := make(map[string]bool)
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			documented[strings.ToUpper(method)+" "+doc.BasePath+path] = true
+		}
+	}
+
+	served := make(map[string]bool)
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil
+		}
+		path, err := route.GetPathTemplate()
+		if err != nil || !strings.HasPrefix(path, corsMiddleware.APIPrefix+"/") {
+			return nil
+		}
+		for _, methodRoute := range append(ancestors, route) {
+			methods, err := methodRoute.GetMethods()
+			if err != nil {
+				continue
+			}
+			for _, method := range methods {
+				served[method+" "+path] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	problems := make([]string, 0)
+	for operation := range served {
+		if !documented[operation] {
+			problems = append(problems, fmt.Sprintf("%s is not documented", operation))
+		}
+	}
+	for operation := range documented {
+		if !served[operation] {
+			problems = append(problems, fmt.Sprintf("%s is documented but not served", operation))
+		}
+	}
+	sort.Strings(problems)
+	return problems, nil
+}
+
+func serveSpec(logger logger.Logger, spec []byte, contentType string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("Content-Type", contentType)
+		rw.WriteHeader(http.StatusOK)
+		if _, err := rw.Write(spec); err != nil {
+			logger.Error("server::serveSpec::Write", err)
+		}
+	}
+}
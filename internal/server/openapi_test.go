@@ -0,0 +1,61 @@
+package server
+
+import (
+	"bot/docs"
+	"bot/internal/config"
+	"bot/internal/logger"
+	corsMiddleware "bot/internal/server/middleware"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestDocumentMatchesRoutes(t *testing.T) {
+
+	router, err := NewRouter(logger.NewLogger(), &config.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := CheckRoutes(router, docs.JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, problem := range problems {
+		t.Error(problem)
+	}
+}
+
+func TestCheckRoutesReportsDifferences(t *testing.T) {
+
+	noop := func(rw http.ResponseWriter, req *http.Request) {}
+	router := mux.NewRouter()
+	apiRouter := router.PathPrefix(corsMiddleware.APIPrefix).Subrouter()
+	apiRouter.Methods(http.MethodGet).Path("/cities").HandlerFunc(noop)
+	apiRouter.Methods(http.MethodPost).Path("/cities").HandlerFunc(noop)
+	// the unversioned routes are not checked
+	router.Methods(http.MethodGet).Path("/masters").HandlerFunc(noop)
+
+	spec := []byte(`{
+		"basePath": "/api/v1",
+		"paths": {
+			"/cities": {"get": {}},
+			"/masters": {"get": {}}
+		}
+	}`)
+
+	problems, err := CheckRoutes(router, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"GET /api/v1/masters is documented but not served",
+		"POST /api/v1/cities is not documented",
+	}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("got %q, want %q", problems, want)
+	}
+}
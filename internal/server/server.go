@@ -1,60 +1,181 @@
 package server
 
 import (
+	"bot/docs"
 	"bot/internal/config"
 	"bot/internal/dbadapter"
+	"bot/internal/graphqlserver"
 	"bot/internal/logger"
-	"bot/internal/minioadapter"
+	"bot/internal/ratelimiter"
 	"bot/internal/server/handler"
 	corsMiddleware "bot/internal/server/middleware"
+	"bot/internal/storage"
+	"bot/internal/telegram"
 	"fmt"
 	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/go-openapi/runtime/middleware"
 	"github.com/gorilla/mux"
 )
 
-func NewServer(logger logger.Logger, cfg *config.Config, DBAdapter *dbadapter.DBAdapter, MinIOAdapter *minioadapter.MinIOAdapter) (*http.Server, error) {
+func NewServer(logger logger.Logger, cfg *config.Config, DBAdapter *dbadapter.DBAdapter, ImageStorage storage.ImageStorage) (*http.Server, error) {
 
-	handler := handler.NewHandler(logger, cfg, DBAdapter, MinIOAdapter)
-	docHandler := middleware.Redoc(middleware.RedocOpts{SpecURL: "swagger.yaml"}, nil)
+	router, err := NewRouter(logger, cfg, DBAdapter, ImageStorage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Server{
+		Handler: corsMiddleware.CorsMiddlware(router),
+		Addr:    fmt.Sprintf(":%d", cfg.Port),
+	}, nil
+}
+
+func NewRouter(logger logger.Logger, cfg *config.Config, DBAdapter *dbadapter.DBAdapter, ImageStorage storage.ImageStorage) (*mux.Router, error) {
+
+	handler := handler.NewHandler(logger, cfg, DBAdapter, ImageStorage)
+	docHandler := middleware.Redoc(middleware.RedocOpts{SpecURL: "/openapi.json"}, nil)
+
+	auth := corsMiddleware.NewAuth(cfg)
 
 	router := mux.NewRouter()
+	router.Use(corsMiddleware.RateLimitMiddleware(logger, ratelimiter.NewMemoryStore(time.Hour), cfg.RateLimits, auth))
+
+	router.Methods(http.MethodGet).Path("/docs").Handler(docHandler)
+	router.Methods(http.MethodGet).Path("/openapi.json").Handler(serveSpec(logger, docs.JSON, "application/json"))
+	router.Methods(http.MethodGet).Path("/swagger.yaml").Handler(serveSpec(logger, docs.YAML, "application/yaml"))
+
+	apiRouter := router.PathPrefix(corsMiddleware.APIPrefix).Subrouter()
+	commonRoutes(apiRouter, handler, auth)
+	apiRoutes(apiRouter, handler, auth)
+
+	graphqlHandler, err := graphqlserver.NewHandler(logger, cfg, DBAdapter, ImageStorage)
+	if err != nil {
+		return nil, err
+	}
+	apiRouter.Methods(http.MethodPost).Path("/graphql").Handler(auth.Admin(graphqlHandler.ServeHTTP))
+
+	// Telegram calls the webhook of the built-in bot, it is not a part of the API
+	if len(cfg.TelegramToken) != 0 {
+		router.Methods(http.MethodPost).Path(telegram.WebhookPath).Handler(telegram.NewBot(logger, cfg, DBAdapter))
+	}
+
+	// the unversioned routes are kept for the clients that didn't move to the versioned API yet
+	legacyRouter := router.NewRoute().Subrouter()
+	legacyRouter.Use(corsMiddleware.Deprecated)
+	commonRoutes(legacyRouter, handler, auth)
+	legacyRoutes(legacyRouter, handler, auth)
+
+	// backends without their own file server serve the images through the API
+	if fileServer, ok := ImageStorage.(http.Handler); ok {
+		prefix, err := url.Parse(cfg.ImagePrefix)
+		if err != nil {
+			return nil, err
+		}
+		router.PathPrefix(prefix.Path + "/").Handler(http.StripPrefix(prefix.Path, fileServer))
+	}
+
+	return router, nil
+}
+
+// apiRoutes registers the routes that only the versioned API has, the resources are addressed by the path.
+// They go after the common routes, so that /services/categories and /masters/self are matched first
+func apiRoutes(router *mux.Router, handler *handler.Handler, auth *corsMiddleware.Auth) {
+
+	getRouter := router.Methods(http.MethodGet).Subrouter()
+	getRouter.HandleFunc("/cities/{city_id}", handler.GetCity)
+	getRouter.HandleFunc("/services/categories/{category_id}", handler.GetServCategory)
+	getRouter.HandleFunc("/services/{service_id}", handler.GetService)
+	getRouter.Handle("/events/stream", auth.AdminStream(handler.StreamEvents))
+
+	postRouter := router.Methods(http.MethodPost).Subrouter()
+	postRouter.Handle("/masters/revisions/{revision_id}:approve", auth.Admin(handler.ApproveMasterRevision))
+	postRouter.Handle("/masters/revisions/{revision_id}:reject", auth.Admin(handler.RejectMasterRevision))
+	postRouter.Handle("/masters/{master_id}:approve", auth.Admin(handler.ApproveMaster))
+
+	putHandler := router.Methods(http.MethodPut).Subrouter()
+	putHandler.Handle("/cities/{city_id}", auth.Admin(handler.UpdateCity))
+	putHandler.Handle("/services/categories/{category_id}", auth.Admin(handler.UpdateServCategory))
+	putHandler.Handle("/services/{service_id}", auth.Admin(handler.UpdateService))
+	putHandler.Handle("/masters/{master_id}", auth.Admin(handler.UpdateMaster))
+
+	patchHandler := router.Methods(http.MethodPatch).Subrouter()
+	patchHandler.Handle("/cities/{city_id}", auth.Admin(handler.PatchCity))
+	patchHandler.Handle("/services/categories/{category_id}", auth.Admin(handler.PatchServCategory))
+	patchHandler.Handle("/services/{service_id}", auth.Admin(handler.PatchService))
+	patchHandler.Handle("/masters/{master_id}", auth.Admin(handler.PatchMaster))
+}
+
+// legacyRoutes registers the deprecated routes that the versioned API replaced
+func legacyRoutes(router *mux.Router, handler *handler.Handler, auth *corsMiddleware.Auth) {
+
+	postRouter := router.Methods(http.MethodPost).Subrouter()
+	postRouter.Handle("/masters/revisions/{revision_id}/approve", auth.Admin(handler.ApproveMasterRevision))
+	postRouter.Handle("/masters/revisions/{revision_id}/reject", auth.Admin(handler.RejectMasterRevision))
+	postRouter.Handle("/masters/approve/{master_id}", auth.Admin(handler.ApproveMaster))
+
+	putHandler := router.Methods(http.MethodPut).Subrouter()
+	putHandler.Handle("/cities", auth.Admin(handler.UpdateCity))
+	putHandler.Handle("/services/categories", auth.Admin(handler.UpdateServCategory))
+	putHandler.Handle("/services", auth.Admin(handler.UpdateService))
+	putHandler.Handle("/masters", auth.Admin(handler.UpdateMaster))
+}
+
+// commonRoutes registers the routes that are the same in the versioned API and in the deprecated one
+func commonRoutes(router *mux.Router, handler *handler.Handler, auth *corsMiddleware.Auth) {
+
 	getRouter := router.Methods(http.MethodGet).Subrouter()
 	getRouter.HandleFunc("/cities", handler.GetCities)
 	getRouter.HandleFunc("/services/categories", handler.GetServiceCategories)
 	getRouter.HandleFunc("/services", handler.GetServices)
 	getRouter.HandleFunc("/masters/bot", handler.GetMastersBot)
-	getRouter.HandleFunc("/masters/admin", handler.GetMastersAdmin)
+	getRouter.Handle("/masters/admin", auth.Admin(handler.GetMastersAdmin))
+	getRouter.Handle("/masters/revisions", auth.Admin(handler.GetMasterRevisions))
+	getRouter.Handle("/masters/self", auth.Bot(handler.Self(handler.GetMaster)))
+	getRouter.Handle("/masters/self/images", auth.Bot(handler.Self(handler.GetMasterImages)))
+	getRouter.Handle("/masters/self/revisions", auth.Bot(handler.Self(handler.GetMasterRevisions)))
 	getRouter.HandleFunc("/masters/{master_id}", handler.GetMaster)
 	getRouter.HandleFunc("/masters/{master_id}/images", handler.GetMasterImages)
-	getRouter.Handle("/docs", docHandler)
-	getRouter.Handle("/swagger.yaml", http.FileServer(http.Dir("/bot-server/docs")))
+	getRouter.Handle("/clients/{telegram_id}", auth.Client(handler.GetClient))
+	getRouter.Handle("/catalog/export", auth.Admin(handler.ExportCatalog))
+	getRouter.Handle("/stats", auth.Admin(handler.GetStats))
+	getRouter.Handle("/clients/{telegram_id}/favorites", auth.Client(handler.GetFavorites))
 
 	postRouter := router.Methods(http.MethodPost).Subrouter()
-	postRouter.HandleFunc("/cities", handler.SaveCity)
-	postRouter.HandleFunc("/services/categories", handler.SaveServiceCategory)
-	postRouter.HandleFunc("/services", handler.SaveService)
+	postRouter.Handle("/cities", auth.Admin(handler.SaveCity))
+	postRouter.Handle("/services/categories", auth.Admin(handler.SaveServiceCategory))
+	postRouter.Handle("/services", auth.Admin(handler.SaveService))
+	postRouter.Handle("/catalog/import", auth.Admin(handler.ImportCatalog))
+	postRouter.Handle("/events", auth.BotKey(handler.SaveEvents))
 	postRouter.HandleFunc("/masters", handler.SaveMaster)
+	postRouter.Handle("/masters/self/images", auth.Bot(handler.Self(handler.SaveMasterImage)))
+	postRouter.Handle("/masters/self/images/uploads", auth.Bot(handler.Self(handler.PresignMasterImageUpload)))
+	postRouter.Handle("/masters/self/images/{image_name}/confirm", auth.Bot(handler.Self(handler.ConfirmMasterImageUpload)))
+	postRouter.Handle("/masters/self/images/{image_name}/cover", auth.Bot(handler.Self(handler.SetMasterCover)))
 	postRouter.HandleFunc("/masters/{master_id}/images", handler.SaveMasterImage)
-	postRouter.HandleFunc("/masters/approve/{master_id}", handler.ApproveMaster)
+	postRouter.HandleFunc("/masters/{master_id}/images/uploads", handler.PresignMasterImageUpload)
+	postRouter.HandleFunc("/masters/{master_id}/images/{image_name}/confirm", handler.ConfirmMasterImageUpload)
+	postRouter.Handle("/masters/{master_id}/images/{image_name}/cover", auth.Admin(handler.SetMasterCover))
 
 	putHandler := router.Methods(http.MethodPut).Subrouter()
-	putHandler.HandleFunc("/cities", handler.UpdateCity)
-	putHandler.HandleFunc("/services/categories", handler.UpdateServCategory)
-	putHandler.HandleFunc("/services", handler.UpdateService)
-	putHandler.HandleFunc("/masters", handler.UpdateMaster)
-	putHandler.HandleFunc("/masters/{master_id}/images/{image_name}", handler.UpdateMasterImage)
+	putHandler.Handle("/masters/self", auth.Bot(handler.Self(handler.UpdateSelfMaster)))
+	putHandler.Handle("/masters/self/images/order", auth.Bot(handler.Self(handler.ReorderMasterImages)))
+	putHandler.Handle("/masters/self/images/{image_name}", auth.Bot(handler.Self(handler.UpdateMasterImage)))
+	putHandler.Handle("/masters/self/images/{image_name}/caption", auth.Bot(handler.Self(handler.UpdateMasterImageCaption)))
+	putHandler.Handle("/clients/{telegram_id}", auth.Client(handler.SaveClient))
+	putHandler.Handle("/clients/{telegram_id}/favorites/{master_id}", auth.Client(handler.SaveFavorite))
+	putHandler.Handle("/masters/{master_id}/images/order", auth.Admin(handler.ReorderMasterImages))
+	putHandler.Handle("/masters/{master_id}/images/{image_name}", auth.Admin(handler.UpdateMasterImage))
+	putHandler.Handle("/masters/{master_id}/images/{image_name}/caption", auth.Admin(handler.UpdateMasterImageCaption))
 
 	deleteHandler := router.Methods(http.MethodDelete).Subrouter()
-	deleteHandler.HandleFunc("/cities/{city_id}", handler.DeleteCity)
-	deleteHandler.HandleFunc("/services/categories/{category_id}", handler.DeleteServCategory)
-	deleteHandler.HandleFunc("/services/{service_id}", handler.DeleteService)
-	deleteHandler.HandleFunc("/masters/{master_id}", handler.DeleteMaster)
-	deleteHandler.HandleFunc("/masters/{master_id}/images/{image_name}", handler.DeleteMasterImage)
-
-	return &http.Server{
-		Handler: corsMiddleware.CorsMiddlware(router),
-		Addr:    fmt.Sprintf(":%d", cfg.Port),
-	}, nil
+	deleteHandler.Handle("/cities/{city_id}", auth.Admin(handler.DeleteCity))
+	deleteHandler.Handle("/services/categories/{category_id}", auth.Admin(handler.DeleteServCategory))
+	deleteHandler.Handle("/services/{service_id}", auth.Admin(handler.DeleteService))
+	deleteHandler.Handle("/clients/{telegram_id}/favorites/{master_id}", auth.Client(handler.DeleteFavorite))
+	deleteHandler.Handle("/masters/self/images/{image_name}", auth.Bot(handler.Self(handler.DeleteMasterImage)))
+	deleteHandler.Handle("/masters/{master_id}", auth.Admin(handler.DeleteMaster))
+	deleteHandler.Handle("/masters/{master_id}/images/{image_name}", auth.Admin(handler.DeleteMasterImage))
 }
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"bot/internal/config"
+	"bot/internal/dbadapter"
+	"bot/internal/dbtest"
+	"bot/internal/logger"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// the merge patches came with the versioned API, the deprecated routes don't have them
+func TestPatchOnlyVersioned(t *testing.T) {
+
+	router, err := NewRouter(logger.NewLogger(), &config.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/cities/1", "/services/categories/1", "/services/1", "/masters/1"} {
+		match := &mux.RouteMatch{}
+		if !router.Match(httptest.NewRequest("PATCH", "/api/v1"+path, nil), match) || match.MatchErr != nil {
+			t.Errorf("PATCH /api/v1%s is not served", path)
+		}
+		match = &mux.RouteMatch{}
+		if router.Match(httptest.NewRequest("PATCH", path, nil), match) && match.MatchErr == nil {
+			t.Errorf("PATCH %s is served", path)
+		}
+	}
+}
+
+// the control panel connects with a browser EventSource, which sends no X-API-Key
+func TestStreamWithoutHeader(t *testing.T) {
+
+	cfg := dbtest.Config(t)
+	cfg.AdminKeys = []string{"admin"}
+
+	adapter, err := dbadapter.NewDbAdapter(logger.NewLogger(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, err := NewRouter(logger.NewLogger(), cfg, adapter, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	connect := func(query string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v1/events/stream"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := connect("?api_key=admin")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("got %d %q, want the stream", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	denied := connect("")
+	denied.Body.Close()
+	if denied.StatusCode != http.StatusUnauthorized {
+		t.Errorf("got %d without a key, want %d", denied.StatusCode, http.StatusUnauthorized)
+	}
+}
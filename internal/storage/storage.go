@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"bot/internal/entities"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const MastersPrefix = "masters"
+
+// ImageStorage keeps the image files, objects are addressed by keys made with MasterImageKey.
+// A backend that also implements http.Handler is mounted by the server at the ImagePrefix path.
+type ImageStorage interface {
+	GetImageURL(objectKey string) (string, error)
+	PutMasterImage(objectKey string, file io.Reader, size int64, contentType string) error
+	DeleteMasterImage(objectKey string) error
+	DeleteMasterImages(masterID string) error
+	PresignUpload(objectKey, contentType string, size int64) (string, http.Header, error)
+	StatMasterImage(objectKey string) (*entities.Image, error)
+}
+
+func MasterImageKey(masterID, imageName string) string {
+	return fmt.Sprintf("%s/%s/%s", MastersPrefix, masterID, imageName)
+}
@@ -0,0 +1,106 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// API calls the Telegram Bot API. The base URL comes from the config,
+// so a local server may stand in for api.telegram.org
+type API struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func NewAPI(baseURL, token string) *API {
+	return &API{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+type InlineKeyboardButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+type response struct {
+	OK          bool   `json:"ok"`
+	ErrorCode   int    `json:"error_code"`
+	Description string `json:"description"`
+}
+
+func (a *API) SendMessage(chatID int64, text string, keyboard *InlineKeyboardMarkup) error {
+	params := map[string]interface{}{"chat_id": chatID, "text": text}
+	if keyboard != nil {
+		params["reply_markup"] = keyboard
+	}
+	return a.call("sendMessage", params)
+}
+
+func (a *API) EditMessageText(chatID, messageID int64, text string, keyboard *InlineKeyboardMarkup) error {
+	params := map[string]interface{}{"chat_id": chatID, "message_id": messageID, "text": text}
+	if keyboard != nil {
+		params["reply_markup"] = keyboard
+	}
+
+	// a repeated tap on the same button renders the same screen
+	err := a.call("editMessageText", params)
+	if err != nil && strings.Contains(err.Error(), "message is not modified") {
+		return nil
+	}
+	return err
+}
+
+func (a *API) AnswerCallbackQuery(id, text string) error {
+	return a.call("answerCallbackQuery", map[string]interface{}{"callback_query_id": id, "text": text})
+}
+
+// SetWebhook points Telegram to the webhook, the secret comes back in SecretTokenHeader of every update
+func (a *API) SetWebhook(webhookURL, secret string) error {
+	params := map[string]interface{}{"url": webhookURL, "allowed_updates": []string{"message", "callback_query"}}
+	if len(secret) != 0 {
+		params["secret_token"] = secret
+	}
+	return a.call("setWebhook", params)
+}
+
+func (a *API) call(method string, params interface{}) error {
+
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	resp, err := a.client.Post(a.baseURL+"/bot"+a.token+"/"+method, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// the URL of the error has the token in it
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	result := &response{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("telegram %s: %s: %w", method, resp.Status, err)
+	}
+	if !result.OK {
+		return fmt.Errorf("telegram %s: %d %s", method, result.ErrorCode, result.Description)
+	}
+	return nil
+}
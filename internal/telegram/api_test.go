@@ -0,0 +1,107 @@
+package telegram
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testToken = "123:secret-token"
+
+type apiCall struct {
+	method string
+	params map[string]interface{}
+}
+
+// fakeAPI stands in for the Bot API, it records the calls and answers with the response of the method
+type fakeAPI struct {
+	*httptest.Server
+	mu        sync.Mutex
+	calls     []apiCall
+	responses map[string]string
+}
+
+func newFakeAPI(t *testing.T) *fakeAPI {
+
+	api := &fakeAPI{responses: make(map[string]string)}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		method, ok := strings.CutPrefix(req.URL.Path, "/bot"+testToken+"/")
+		if !ok {
+			http.NotFound(rw, req)
+			return
+		}
+
+		params := make(map[string]interface{})
+		if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		api.mu.Lock()
+		api.calls = append(api.calls, apiCall{method: method, params: params})
+		response, ok := api.responses[method]
+		api.mu.Unlock()
+		if !ok {
+			response = `{"ok":true,"result":true}`
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte(response))
+	}))
+	t.Cleanup(api.Close)
+	return api
+}
+
+func (a *fakeAPI) recorded() []apiCall {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]apiCall(nil), a.calls...)
+}
+
+func TestSendMessage(t *testing.T) {
+
+	fake := newFakeAPI(t)
+	api := NewAPI(fake.URL+"/", testToken)
+
+	keyboard := &InlineKeyboardMarkup{InlineKeyboard: [][]InlineKeyboardButton{{{Text: "Berlin", CallbackData: "city:1"}}}}
+	if err := api.SendMessage(42, "Choose your city:", keyboard); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := fake.recorded()
+	if len(calls) != 1 || calls[0].method != "sendMessage" {
+		t.Fatalf("got %v, want one sendMessage", calls)
+	}
+	if calls[0].params["chat_id"] != float64(42) || calls[0].params["text"] != "Choose your city:" {
+		t.Errorf("got %v", calls[0].params)
+	}
+	if _, ok := calls[0].params["reply_markup"]; !ok {
+		t.Error("the keyboard is missing")
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+
+	fake := newFakeAPI(t)
+	fake.responses["editMessageText"] = `{"ok":false,"error_code":400,"description":"Bad Request: message is not modified"}`
+	fake.responses["sendMessage"] = `{"ok":false,"error_code":403,"description":"Forbidden: bot was blocked by the user"}`
+	api := NewAPI(fake.URL, testToken)
+
+	if err := api.EditMessageText(42, 7, "Choose a category:", nil); err != nil {
+		t.Errorf("an unchanged message is not an error, got %s", err)
+	}
+
+	err := api.SendMessage(42, "Choose your city:", nil)
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("got %v, want the error of the API", err)
+	}
+
+	// the URL of a failed request carries the token, it must not reach the logs
+	unreachable := NewAPI("http://127.0.0.1:1", testToken)
+	err = unreachable.SendMessage(42, "Choose your city:", nil)
+	if err == nil || strings.Contains(err.Error(), testToken) {
+		t.Errorf("got %v, want an error without the token", err)
+	}
+}
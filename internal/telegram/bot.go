@@ -0,0 +1,295 @@
+// Package telegram is the built-in Telegram bot. It takes the updates from the webhook and
+// walks the user through the choice of a city, a category, a service and a master,
+// reading the catalog with DBAdapter directly
+package telegram
+
+import (
+	"bot/internal/config"
+	"bot/internal/dbadapter"
+	"bot/internal/entities"
+	"bot/internal/logger"
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebhookPath is where the server takes the updates, outside of the versioned API
+const WebhookPath = "/telegram/webhook"
+
+const mastersPageSize = 5
+
+// conversationTTL is how long an idle conversation is kept, the buttons of older messages start the search over
+const conversationTTL = 24 * time.Hour
+
+type Bot struct {
+	logger    logger.Logger
+	api       *API
+	secret    string
+	DBAdapter *dbadapter.DBAdapter
+
+	mu sync.Mutex
+	// the conversations are kept in memory, a restart sends the users back to the start
+	conversations map[int64]*conversation
+	lastSweep     time.Time
+	now           func() time.Time
+}
+
+func NewBot(logger logger.Logger, cfg *config.Config, DBAdapter *dbadapter.DBAdapter) *Bot {
+	return &Bot{
+		logger:        logger,
+		api:           NewAPI(cfg.TelegramAPIURL, cfg.TelegramToken),
+		secret:        cfg.TelegramSecret,
+		DBAdapter:     DBAdapter,
+		conversations: make(map[int64]*conversation),
+		lastSweep:     time.Now(),
+		now:           time.Now,
+	}
+}
+
+// ServeHTTP takes the update from Telegram. A failure to handle it is only logged,
+// an error status would make Telegram send the same update again. The updates come
+// with the secret only, without one configured every update is rejected
+func (b *Bot) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	b.logger.Infof("Request received: %s %s", req.Method, req.URL)
+
+	if len(b.secret) == 0 || subtle.ConstantTimeCompare([]byte(req.Header.Get(SecretTokenHeader)), []byte(b.secret)) != 1 {
+		http.Error(rw, "invalid secret token", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		b.logger.Error("telegram::ServeHTTP::ReadAll", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	update, err := ParseUpdate(body)
+	if err != nil {
+		b.logger.Error("telegram::ServeHTTP::ParseUpdate", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := b.HandleUpdate(update); err != nil {
+		b.logger.Error("telegram::ServeHTTP::HandleUpdate", err)
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
+func (b *Bot) HandleUpdate(update *Update) error {
+	switch {
+	case update.Message != nil:
+		return b.handleMessage(update.Message)
+	case update.CallbackQuery != nil:
+		return b.handleCallback(update.CallbackQuery)
+	default:
+		return nil
+	}
+}
+
+// handleMessage starts the search over, from the category step if the client has a city
+func (b *Bot) handleMessage(message *Message) error {
+
+	if message.Chat.Type != "private" || message.From == nil {
+		return nil
+	}
+
+	conv, unlock := b.conversation(message.Chat.ID)
+	defer unlock()
+
+	conv.reset()
+
+	client := &entities.Client{TelegramID: message.From.ID, Language: message.From.LanguageCode}
+	saved, err := b.DBAdapter.SaveClient(client, []string{dbadapter.ClientLanguage})
+	if err != nil {
+		return err
+	}
+
+	if len(saved.DefaultCityID) != 0 {
+		conv.cityID = saved.DefaultCityID
+		conv.state = stateCategory
+	}
+	return b.render(message.Chat.ID, 0, conv)
+}
+
+func (b *Bot) handleCallback(query *CallbackQuery) error {
+
+	if query.Message == nil {
+		return b.api.AnswerCallbackQuery(query.ID, "")
+	}
+
+	chatID, messageID := query.Message.Chat.ID, query.Message.MessageID
+	conv, unlock := b.conversation(chatID)
+	defer unlock()
+
+	if !conv.apply(query.Data) {
+		if err := b.api.AnswerCallbackQuery(query.ID, "This menu is outdated"); err != nil {
+			return err
+		}
+		return b.render(chatID, messageID, conv)
+	}
+
+	if err := b.api.AnswerCallbackQuery(query.ID, ""); err != nil {
+		return err
+	}
+
+	if err := b.record(query.From.ID, query.Data, conv); err != nil {
+		return err
+	}
+	return b.render(chatID, messageID, conv)
+}
+
+// record saves the choice of the city as the default one of the client and counts the views
+// the way the external bot does
+func (b *Bot) record(telegramID int64, data string, conv *conversation) error {
+
+	event := &entities.Event{TelegramID: telegramID, CityID: conv.cityID, Time: time.Now()}
+	switch action, _, _ := strings.Cut(data, ":"); action {
+	case actionCity:
+		client := &entities.Client{TelegramID: telegramID, DefaultCityID: conv.cityID}
+		_, err := b.DBAdapter.SaveClient(client, []string{dbadapter.ClientDefaultCity})
+		return err
+	case actionCategory:
+		event.Type = entities.ViewedCategory
+		event.CategoryID = conv.categoryID
+	case actionMaster:
+		event.Type = entities.ViewedMaster
+		event.ServiceID = conv.serviceID
+		event.MasterID = conv.masterID
+	default:
+		return nil
+	}
+	return b.DBAdapter.SaveEvents([]*entities.Event{event})
+}
+
+// conversation returns the locked conversation of the chat, the updates of one chat are handled one by one
+func (b *Bot) conversation(chatID int64) (*conversation, func()) {
+
+	b.mu.Lock()
+	now := b.now()
+	b.sweep(now)
+	conv, ok := b.conversations[chatID]
+	if !ok {
+		conv = &conversation{}
+		b.conversations[chatID] = conv
+	}
+	conv.lastSeen = now
+	b.mu.Unlock()
+
+	conv.mu.Lock()
+	return conv, conv.mu.Unlock
+}
+
+// sweep drops the conversations idle for conversationTTL, the caller holds b.mu
+func (b *Bot) sweep(now time.Time) {
+	if now.Sub(b.lastSweep) < conversationTTL {
+		return
+	}
+	for chatID, conv := range b.conversations {
+		if now.Sub(conv.lastSeen) >= conversationTTL {
+			delete(b.conversations, chatID)
+		}
+	}
+	b.lastSweep = now
+}
+
+// render shows the step of the conversation, in place of the message with the buttons if there is one
+func (b *Bot) render(chatID, messageID int64, conv *conversation) error {
+
+	text, keyboard, err := b.screen(conv)
+	if err != nil {
+		return err
+	}
+
+	if messageID != 0 {
+		return b.api.EditMessageText(chatID, messageID, text, keyboard)
+	}
+	return b.api.SendMessage(chatID, text, keyboard)
+}
+
+func (b *Bot) screen(conv *conversation) (string, *InlineKeyboardMarkup, error) {
+
+	keyboard := &InlineKeyboardMarkup{InlineKeyboard: make([][]InlineKeyboardButton, 0)}
+	add := func(text, action, id string) {
+		data := action
+		if len(id) != 0 {
+			data += ":" + id
+		}
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, []InlineKeyboardButton{{Text: text, CallbackData: data}})
+	}
+
+	switch conv.state {
+	case stateCity:
+		cities, err := b.DBAdapter.GetCities("", 0, -1)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, city := range cities {
+			add(city.Name, actionCity, city.ID)
+		}
+		if len(cities) == 0 {
+			return "There are no cities yet.", nil, nil
+		}
+		return "Choose your city:", keyboard, nil
+
+	case stateCategory:
+		categories, err := b.DBAdapter.GetServCategories(conv.cityID, 0, -1)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, category := range categories {
+			add(category.Name, actionCategory, category.ID)
+		}
+		add("« Change city", actionBack, "")
+		if len(categories) == 0 {
+			return "There are no masters in this city yet.", keyboard, nil
+		}
+		return "Choose a category:", keyboard, nil
+
+	case stateService:
+		services, err := b.DBAdapter.GetServices(conv.categoryID, conv.cityID, 0, -1)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, service := range services {
+			add(service.Name, actionService, service.ID)
+		}
+		add("« Back", actionBack, "")
+		return "Choose a service:", keyboard, nil
+
+	case stateMasters:
+		masters, err := b.DBAdapter.GetMastersBot(conv.cityID, "", conv.serviceID, dbadapter.PopularOrder, conv.page, mastersPageSize)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, master := range masters {
+			add(master.Name, actionMaster, master.ID)
+		}
+		if len(masters) == mastersPageSize {
+			add("More »", actionMore, "")
+		}
+		add("« Back", actionBack, "")
+		if len(masters) == 0 {
+			return "No more masters.", keyboard, nil
+		}
+		return "Choose a master:", keyboard, nil
+
+	default:
+		master, err := b.DBAdapter.GetMaster(conv.masterID)
+		if err != nil {
+			return "", nil, err
+		}
+		add("« Back", actionBack, "")
+
+		text := master.Name
+		if len(master.Description) != 0 {
+			text += "\n\n" + master.Description
+		}
+		return fmt.Sprintf("%s\n\nContact: %s", text, master.Contact), keyboard, nil
+	}
+}
@@ -0,0 +1,117 @@
+package telegram
+
+import (
+	"bot/internal/config"
+	"bot/internal/logger"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "s3cret"
+
+func newTestBot(t *testing.T, secret string) (*Bot, *fakeAPI) {
+	fake := newFakeAPI(t)
+	cfg := &config.Config{TelegramAPIURL: fake.URL, TelegramToken: testToken, TelegramSecret: secret}
+	return NewBot(logger.NewLogger(), cfg, nil), fake
+}
+
+func TestServeHTTPSecret(t *testing.T) {
+
+	// the update has neither a message nor a callback query, it is accepted without a reply
+	const update = `{"update_id": 1}`
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		status int
+	}{
+		{"no secret configured", "", "", http.StatusUnauthorized},
+		{"matching secret", "s3cret", "s3cret", http.StatusOK},
+		{"missing secret", "s3cret", "", http.StatusUnauthorized},
+		{"wrong secret", "s3cret", "s3cre", http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bot, _ := newTestBot(t, test.secret)
+
+			req := httptest.NewRequest(http.MethodPost, WebhookPath, strings.NewReader(update))
+			if len(test.header) != 0 {
+				req.Header.Set(SecretTokenHeader, test.header)
+			}
+			rec := httptest.NewRecorder()
+			bot.ServeHTTP(rec, req)
+
+			if rec.Code != test.status {
+				t.Errorf("got %d, want %d", rec.Code, test.status)
+			}
+		})
+	}
+}
+
+func TestServeHTTPInvalidUpdate(t *testing.T) {
+
+	bot, fake := newTestBot(t, testSecret)
+
+	req := httptest.NewRequest(http.MethodPost, WebhookPath, strings.NewReader(`{"message": {"text": "hi"}}`))
+	req.Header.Set(SecretTokenHeader, testSecret)
+	rec := httptest.NewRecorder()
+	bot.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if calls := fake.recorded(); len(calls) != 0 {
+		t.Errorf("got %v, want no calls of the API", calls)
+	}
+}
+
+// a button of a message that Telegram no longer has is answered, the conversation stays where it is
+func TestCallbackWithoutMessage(t *testing.T) {
+
+	bot, fake := newTestBot(t, testSecret)
+
+	update := `{"update_id": 1, "callback_query": {"id": "q1", "from": {"id": 5}, "data": "city:1"}}`
+	req := httptest.NewRequest(http.MethodPost, WebhookPath, strings.NewReader(update))
+	req.Header.Set(SecretTokenHeader, testSecret)
+	rec := httptest.NewRecorder()
+	bot.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("got %d, want %d", rec.Code, http.StatusOK)
+	}
+	calls := fake.recorded()
+	if len(calls) != 1 || calls[0].method != "answerCallbackQuery" || calls[0].params["callback_query_id"] != "q1" {
+		t.Errorf("got %v, want the query answered", calls)
+	}
+	if len(bot.conversations) != 0 {
+		t.Errorf("got %d conversations, want none", len(bot.conversations))
+	}
+}
+
+func TestConversationsSwept(t *testing.T) {
+
+	bot, _ := newTestBot(t, testSecret)
+	now := time.Now()
+	bot.now = func() time.Time { return now }
+
+	_, unlock := bot.conversation(1)
+	unlock()
+
+	now = now.Add(conversationTTL / 2)
+	_, unlock = bot.conversation(2)
+	unlock()
+
+	// the first chat was idle for the TTL, the second one only for half of it
+	now = now.Add(conversationTTL / 2)
+	_, unlock = bot.conversation(3)
+	unlock()
+
+	if _, ok := bot.conversations[1]; ok || len(bot.conversations) != 2 {
+		t.Errorf("got %d conversations, want the idle one dropped", len(bot.conversations))
+	}
+}
@@ -0,0 +1,93 @@
+package telegram
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+type state int
+
+// The steps of the search, back goes to the previous one
+const (
+	stateCity state = iota
+	stateCategory
+	stateService
+	stateMasters
+	stateMaster
+)
+
+// the callback data of the buttons, the choices carry the ID after the colon
+const (
+	actionCity     = "city"
+	actionCategory = "category"
+	actionService  = "service"
+	actionMaster   = "master"
+	actionMore     = "more"
+	actionBack     = "back"
+)
+
+// conversation is the search of one chat, the choices of the previous steps are kept
+type conversation struct {
+	mu         sync.Mutex
+	state      state
+	cityID     string
+	categoryID string
+	serviceID  string
+	masterID   string
+	page       int
+	// set under the lock of the bot, the idle conversations are dropped
+	lastSeen time.Time
+}
+
+// apply moves the conversation by the button, false means the button belongs to another step,
+// e.g. it is on an older message
+func (c *conversation) apply(data string) bool {
+
+	action, id, _ := strings.Cut(data, ":")
+	switch {
+	case action == actionCity && c.state == stateCity && len(id) != 0:
+		c.cityID = id
+		c.state = stateCategory
+	case action == actionCategory && c.state == stateCategory && len(id) != 0:
+		c.categoryID = id
+		c.state = stateService
+	case action == actionService && c.state == stateService && len(id) != 0:
+		c.serviceID = id
+		c.page = 0
+		c.state = stateMasters
+	case action == actionMore && c.state == stateMasters:
+		c.page++
+	case action == actionMaster && c.state == stateMasters && len(id) != 0:
+		c.masterID = id
+		c.state = stateMaster
+	case action == actionBack:
+		c.back()
+	default:
+		return false
+	}
+	return true
+}
+
+func (c *conversation) reset() {
+	c.state = stateCity
+	c.cityID, c.categoryID, c.serviceID, c.masterID = "", "", "", ""
+	c.page = 0
+}
+
+func (c *conversation) back() {
+	switch c.state {
+	case stateCategory:
+		c.state = stateCity
+	case stateService:
+		c.state = stateCategory
+	case stateMasters:
+		if c.page > 0 {
+			c.page--
+			return
+		}
+		c.state = stateService
+	case stateMaster:
+		c.state = stateMasters
+	}
+}
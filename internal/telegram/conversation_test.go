@@ -0,0 +1,86 @@
+package telegram
+
+import "testing"
+
+func TestConversationSteps(t *testing.T) {
+
+	conv := &conversation{}
+	steps := []struct {
+		data  string
+		state state
+		page  int
+	}{
+		{"city:c1", stateCategory, 0},
+		{"category:k1", stateService, 0},
+		{"service:s1", stateMasters, 0},
+		{"more", stateMasters, 1},
+		{"more", stateMasters, 2},
+		{"master:m1", stateMaster, 2},
+		// back from the master returns to the page it was chosen on
+		{"back", stateMasters, 2},
+		{"back", stateMasters, 1},
+		{"back", stateMasters, 0},
+		{"back", stateService, 0},
+		// choosing the service again starts from the first page
+		{"service:s2", stateMasters, 0},
+		{"back", stateService, 0},
+		{"back", stateCategory, 0},
+		{"back", stateCity, 0},
+		{"back", stateCity, 0},
+	}
+
+	for _, step := range steps {
+		if !conv.apply(step.data) {
+			t.Fatalf("%s was rejected in state %d", step.data, conv.state)
+		}
+		if conv.state != step.state || conv.page != step.page {
+			t.Fatalf("after %s got state %d page %d, want state %d page %d", step.data, conv.state, conv.page, step.state, step.page)
+		}
+	}
+
+	if conv.cityID != "c1" || conv.categoryID != "k1" || conv.serviceID != "s2" || conv.masterID != "m1" {
+		t.Errorf("the choices are lost: %+v", conv)
+	}
+}
+
+// the buttons of an older message belong to another step, they don't move the conversation
+func TestConversationStaleButtons(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		state state
+		data  string
+	}{
+		{"city on the category step", stateCategory, "city:c2"},
+		{"category on the city step", stateCity, "category:k1"},
+		{"service on the masters step", stateMasters, "service:s2"},
+		{"master on the service step", stateService, "master:m1"},
+		{"more on the master step", stateMaster, "more"},
+		{"choice without an ID", stateCity, "city"},
+		{"unknown action", stateCity, "vote:1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conv := &conversation{state: test.state, cityID: "c1", page: 1}
+			if conv.apply(test.data) {
+				t.Errorf("%s was applied", test.data)
+			}
+			if conv.state != test.state || conv.cityID != "c1" || conv.page != 1 {
+				t.Errorf("the conversation moved: %+v", conv)
+			}
+		})
+	}
+}
+
+func TestConversationReset(t *testing.T) {
+
+	conv := &conversation{state: stateMaster, cityID: "c1", categoryID: "k1", serviceID: "s1", masterID: "m1", page: 3}
+	conv.mu.Lock()
+	conv.reset()
+	conv.mu.Unlock()
+
+	if conv.state != stateCity || conv.cityID != "" || conv.categoryID != "" || conv.serviceID != "" || conv.masterID != "" || conv.page != 0 {
+		t.Errorf("got %+v, want the first step", conv)
+	}
+}
@@ -0,0 +1,59 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// SecretTokenHeader carries the secret given to setWebhook
+const SecretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// Update holds the fields of the Telegram update that the bot uses, the other kinds of updates are ignored
+type Update struct {
+	UpdateID      int64          `json:"update_id"`
+	Message       *Message       `json:"message,omitempty"`
+	CallbackQuery *CallbackQuery `json:"callback_query,omitempty"`
+}
+
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	From      *User  `json:"from,omitempty"`
+	Chat      Chat   `json:"chat"`
+	Text      string `json:"text,omitempty"`
+}
+
+type User struct {
+	ID           int64  `json:"id"`
+	LanguageCode string `json:"language_code,omitempty"`
+}
+
+type Chat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+}
+
+type CallbackQuery struct {
+	ID      string   `json:"id"`
+	From    User     `json:"from"`
+	Message *Message `json:"message,omitempty"`
+	Data    string   `json:"data,omitempty"`
+}
+
+func ParseUpdate(body []byte) (*Update, error) {
+
+	update := &Update{}
+	if err := json.Unmarshal(body, update); err != nil {
+		return nil, err
+	}
+
+	if update.UpdateID == 0 {
+		return nil, errors.New("update_id is missing")
+	}
+	if update.Message != nil && update.Message.Chat.ID == 0 {
+		return nil, errors.New("message without a chat")
+	}
+	if update.CallbackQuery != nil && len(update.CallbackQuery.ID) == 0 {
+		return nil, errors.New("callback query without an ID")
+	}
+	return update, nil
+}
@@ -0,0 +1,40 @@
+package telegram
+
+import "testing"
+
+func TestParseUpdate(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		body  string
+		valid bool
+	}{
+		{"message", `{"update_id": 1, "message": {"message_id": 2, "from": {"id": 5, "language_code": "de"}, "chat": {"id": 5, "type": "private"}, "text": "/start"}}`, true},
+		{"callback query", `{"update_id": 1, "callback_query": {"id": "q1", "from": {"id": 5}, "message": {"message_id": 2, "chat": {"id": 5, "type": "private"}}, "data": "city:1"}}`, true},
+		{"other kind of update", `{"update_id": 1, "edited_message": {"message_id": 2}}`, true},
+		{"not JSON", `update`, false},
+		{"no update ID", `{"message": {"message_id": 2, "chat": {"id": 5, "type": "private"}}}`, false},
+		{"message without a chat", `{"update_id": 1, "message": {"message_id": 2, "text": "/start"}}`, false},
+		{"callback query without an ID", `{"update_id": 1, "callback_query": {"from": {"id": 5}, "data": "city:1"}}`, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			update, err := ParseUpdate([]byte(test.body))
+			if test.valid && err != nil {
+				t.Errorf("got %s, want the update", err)
+			}
+			if !test.valid && (err == nil || update != nil) {
+				t.Errorf("got %v, want an error", update)
+			}
+		})
+	}
+
+	update, err := ParseUpdate([]byte(tests[0].body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if update.Message.From.ID != 5 || update.Message.From.LanguageCode != "de" || update.Message.Chat.Type != "private" {
+		t.Errorf("got %+v", update.Message)
+	}
+}
@@ -15,7 +15,21 @@ func RunLinter() error {
 }
 
 func GenDoc() error {
-	return sh.Run("swag", "init", "-g", "internal/server/handler/handler.go", "--ot", "yaml", "-o", "docs")
+	return sh.Run("swag", "init", "-g", "internal/server/handler/handler.go", "--ot", "json,yaml", "-o", "docs")
+}
+
+// CheckDoc fails if the generated OpenAPI document and the routes of the server differ
+func CheckDoc() error {
+	return sh.Run("go", "run", "./cmd/checkdoc")
+}
+
+// GenProto generates the gRPC code of the API into internal/grpcserver/pb
+func GenProto() error {
+	return sh.Run("protoc", "-I", "proto", "--go_out=.", "--go_opt=module=bot", "--go-grpc_out=.", "--go-grpc_opt=module=bot", "bot/v1/bot.proto")
+}
+
+func MigrateImages() error {
+	return sh.Run("go", "run", "./cmd/migrateimages")
 }
 
 func RunTests() error {
@@ -29,5 +43,8 @@ func Build() error {
 	if err := GenDoc(); err != nil {
 		return err
 	}
+	if err := CheckDoc(); err != nil {
+		return err
+	}
 	return sh.Run("go", "build", "-o", "bot-server", "cmd/main.go")
 }
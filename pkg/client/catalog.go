@@ -0,0 +1,175 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// GetCities returns the cities sorted by name
+func (c *Client) GetCities(ctx context.Context, page Page) ([]*City, error) {
+	cities := make([]*City, 0)
+	err := c.call(ctx, c.newRequest(http.MethodGet, "/cities", page.values()), &cities)
+	return cities, err
+}
+
+func (c *Client) GetCity(ctx context.Context, cityID string) (*City, error) {
+	city := &City{}
+	err := c.call(ctx, c.newRequest(http.MethodGet, escape("cities", cityID), nil), city)
+	return city, err
+}
+
+// SaveCity creates a city and returns its ID
+func (c *Client) SaveCity(ctx context.Context, name string) (string, error) {
+	created := &id{}
+	err := c.callJSON(ctx, http.MethodPost, "/cities", map[string]string{"name": name}, created)
+	return created.ID, err
+}
+
+// UpdateCity renames the city, city.Version must be the version the update is based on
+func (c *Client) UpdateCity(ctx context.Context, city *City) error {
+	return c.callJSON(ctx, http.MethodPut, escape("cities", city.ID), city, nil)
+}
+
+// PatchCity applies a JSON merge patch, version 0 skips the version check
+func (c *Client) PatchCity(ctx context.Context, cityID string, patch interface{}, version int64) error {
+	return c.patch(ctx, escape("cities", cityID), patch, version)
+}
+
+func (c *Client) DeleteCity(ctx context.Context, cityID string) error {
+	return c.call(ctx, c.newRequest(http.MethodDelete, escape("cities", cityID), nil), nil)
+}
+
+// GetServiceCategories returns the service categories sorted by name
+func (c *Client) GetServiceCategories(ctx context.Context, page Page) ([]*ServiceCategory, error) {
+	categories := make([]*ServiceCategory, 0)
+	err := c.call(ctx, c.newRequest(http.MethodGet, "/services/categories", page.values()), &categories)
+	return categories, err
+}
+
+func (c *Client) GetServiceCategory(ctx context.Context, categoryID string) (*ServiceCategory, error) {
+	category := &ServiceCategory{}
+	err := c.call(ctx, c.newRequest(http.MethodGet, escape("services", "categories", categoryID), nil), category)
+	return category, err
+}
+
+// SaveServiceCategory creates a service category and returns its ID
+func (c *Client) SaveServiceCategory(ctx context.Context, name string) (string, error) {
+	created := &id{}
+	err := c.callJSON(ctx, http.MethodPost, "/services/categories", map[string]string{"name": name}, created)
+	return created.ID, err
+}
+
+// UpdateServiceCategory renames the category, category.Version must be the version the update is based on
+func (c *Client) UpdateServiceCategory(ctx context.Context, category *ServiceCategory) error {
+	return c.callJSON(ctx, http.MethodPut, escape("services", "categories", category.ID), category, nil)
+}
+
+// PatchServiceCategory applies a JSON merge patch, version 0 skips the version check
+func (c *Client) PatchServiceCategory(ctx context.Context, categoryID string, patch interface{}, version int64) error {
+	return c.patch(ctx, escape("services", "categories", categoryID), patch, version)
+}
+
+func (c *Client) DeleteServiceCategory(ctx context.Context, categoryID string) error {
+	return c.call(ctx, c.newRequest(http.MethodDelete, escape("services", "categories", categoryID), nil), nil)
+}
+
+// GetServices returns the services of the category, or all of them if categoryID is empty
+func (c *Client) GetServices(ctx context.Context, categoryID string, page Page) ([]*Service, error) {
+	query := page.values()
+	if len(categoryID) != 0 {
+		query.Set("category_id", categoryID)
+	}
+	services := make([]*Service, 0)
+	err := c.call(ctx, c.newRequest(http.MethodGet, "/services", query), &services)
+	return services, err
+}
+
+func (c *Client) GetService(ctx context.Context, serviceID string) (*Service, error) {
+	service := &Service{}
+	err := c.call(ctx, c.newRequest(http.MethodGet, escape("services", serviceID), nil), service)
+	return service, err
+}
+
+// SaveService creates a service and returns its ID
+func (c *Client) SaveService(ctx context.Context, service *Service) (string, error) {
+	created := &id{}
+	err := c.callJSON(ctx, http.MethodPost, "/services", service, created)
+	return created.ID, err
+}
+
+// UpdateService changes the service, service.Version must be the version the update is based on
+func (c *Client) UpdateService(ctx context.Context, service *Service) error {
+	return c.callJSON(ctx, http.MethodPut, escape("services", service.ID), service, nil)
+}
+
+// PatchService applies a JSON merge patch, version 0 skips the version check
+func (c *Client) PatchService(ctx context.Context, serviceID string, patch interface{}, version int64) error {
+	return c.patch(ctx, escape("services", serviceID), patch, version)
+}
+
+func (c *Client) DeleteService(ctx context.Context, serviceID string) error {
+	return c.call(ctx, c.newRequest(http.MethodDelete, escape("services", serviceID), nil), nil)
+}
+
+// ExportCatalog writes the catalog in the format, "csv" or "json", to w
+func (c *Client) ExportCatalog(ctx context.Context, format string, w io.Writer) error {
+
+	query := url.Values{}
+	if len(format) != 0 {
+		query.Set("format", format)
+	}
+
+	resp, err := c.send(ctx, c.newRequest(http.MethodGet, "/catalog/export", query))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// ImportCatalog reads the catalog in the format, "csv" or "json", from r.
+// With dryRun the server only reports the changes.
+func (c *Client) ImportCatalog(ctx context.Context, format string, r io.Reader, dryRun bool) (*ImportResult, error) {
+
+	query := url.Values{}
+	if len(format) != 0 {
+		query.Set("format", format)
+	}
+	if dryRun {
+		query.Set("dry_run", "true")
+	}
+
+	req := c.newRequest(http.MethodPost, "/catalog/import", query)
+	req.body = r
+	req.contentType = "application/json"
+	if format == "csv" {
+		req.contentType = "text/csv"
+	}
+
+	result := &ImportResult{}
+	err := c.call(ctx, req, result)
+	return result, err
+}
+
+// GetStats returns the dashboard numbers, days limits the registrations report, 0 means the server default
+func (c *Client) GetStats(ctx context.Context, days int) (*Stats, error) {
+
+	query := url.Values{}
+	if days > 0 {
+		query.Set("days", strconv.Itoa(days))
+	}
+
+	stats := &Stats{}
+	err := c.call(ctx, c.newRequest(http.MethodGet, "/stats", query), stats)
+	return stats, err
+}
+
+// SaveEvents records the events of the bot users, requires the bot key
+func (c *Client) SaveEvents(ctx context.Context, events []*Event) error {
+	return c.callJSON(ctx, http.MethodPost, "/events", events, nil)
+}
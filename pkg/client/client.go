@@ -0,0 +1,174 @@
+// Package client is the Go client of the versioned API, used by the bot and the control panel
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	apiPrefix            = "/api/v1"
+	apiKeyHeader         = "X-API-Key"
+	telegramUserIDHeader = "X-Telegram-User-ID"
+	mergePatchType       = "application/merge-patch+json"
+)
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	apiKey     string
+	telegramID int64
+}
+
+type Option func(c *Client)
+
+// WithHTTPClient replaces http.DefaultClient
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAPIKey sends the bot or the admin key with every request
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// New creates a client of the server at baseURL, e.g. http://localhost:8080
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// As returns a copy of the client acting for the Telegram user, required by the Self routes
+// and used to link new masters and events to the user
+func (c *Client) As(telegramID int64) *Client {
+	user := *c
+	user.telegramID = telegramID
+	return &user
+}
+
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	body        io.Reader
+	contentType string
+	ifMatch     int64
+}
+
+func (c *Client) newRequest(method, path string, query url.Values) *request {
+	return &request{method: method, path: path, query: query}
+}
+
+func (r *request) json(body interface{}) (*request, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	r.body = bytes.NewReader(data)
+	r.contentType = "application/json"
+	return r, nil
+}
+
+// send returns the response of a successful request, the caller closes the body
+func (c *Client) send(ctx context.Context, r *request) (*http.Response, error) {
+
+	target := c.baseURL + apiPrefix + r.path
+	if len(r.query) != 0 {
+		target += "?" + r.query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.method, target, r.body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if len(r.contentType) != 0 {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+	if len(c.apiKey) != 0 {
+		req.Header.Set(apiKeyHeader, c.apiKey)
+	}
+	if c.telegramID != 0 {
+		req.Header.Set(telegramUserIDHeader, strconv.FormatInt(c.telegramID, 10))
+	}
+	if r.ifMatch != 0 {
+		req.Header.Set("If-Match", fmt.Sprintf(`"%d"`, r.ifMatch))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		return nil, newError(resp)
+	}
+	return resp, nil
+}
+
+// call sends the request and decodes the JSON response into out, if given
+func (c *Client) call(ctx context.Context, r *request, out interface{}) error {
+
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		_, err := io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// callJSON sends body as JSON and decodes the response into out, if given
+func (c *Client) callJSON(ctx context.Context, method, path string, body, out interface{}) error {
+	r, err := c.newRequest(method, path, nil).json(body)
+	if err != nil {
+		return err
+	}
+	return c.call(ctx, r, out)
+}
+
+// patch sends a JSON merge patch, version 0 skips the version check unless the patch has the version field
+func (c *Client) patch(ctx context.Context, path string, patch interface{}, version int64) error {
+	r, err := c.newRequest(http.MethodPatch, path, nil).json(patch)
+	if err != nil {
+		return err
+	}
+	r.contentType = mergePatchType
+	r.ifMatch = version
+	return c.call(ctx, r, nil)
+}
+
+type id struct {
+	ID string `json:"id"`
+}
+
+func escape(segments ...string) string {
+	escaped := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		escaped = append(escaped, url.PathEscape(segment))
+	}
+	return "/" + strings.Join(escaped, "/")
+}
@@ -0,0 +1,268 @@
+package client
+
+import (
+	"bot/internal/config"
+	"bot/internal/dbadapter"
+	"bot/internal/dbtest"
+	"bot/internal/fsadapter"
+	"bot/internal/logger"
+	"bot/internal/server"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAll(t *testing.T) {
+
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		requests++
+		if req.URL.Path != apiPrefix+"/cities" {
+			http.NotFound(rw, req)
+			return
+		}
+
+		page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+		limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+		cities := make([]*City, 0)
+		for i := page * limit; i < (page+1)*limit && i < 5; i++ {
+			cities = append(cities, &City{ID: strconv.Itoa(i), Name: fmt.Sprintf("city %d", i)})
+		}
+		json.NewEncoder(rw).Encode(cities)
+	}))
+	defer srv.Close()
+
+	cities, err := All(context.Background(), 2, New(srv.URL).GetCities)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cities) != 5 {
+		t.Fatalf("got %d cities, want 5", len(cities))
+	}
+	for i, city := range cities {
+		if city.ID != strconv.Itoa(i) {
+			t.Errorf("city %d has ID %s", i, city.ID)
+		}
+	}
+	// the third page is not full and ends the listing
+	if requests != 3 {
+		t.Errorf("got %d requests, want 3", requests)
+	}
+}
+
+// the errors are produced by the real router, the requests fail before the handlers reach the database
+func TestErrors(t *testing.T) {
+
+	cfg := &config.Config{
+		AdminKeys:  []string{"admin"},
+		RateLimits: []config.RateLimit{{Method: http.MethodPost, Path: "/masters", Rate: 0.001, Burst: 1}},
+	}
+	router, err := server.NewRouter(logger.NewLogger(), cfg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	ctx := context.Background()
+	anonymous := New(srv.URL)
+	admin := New(srv.URL, WithAPIKey("admin"))
+
+	_, err = anonymous.GetMastersAdmin(ctx, AdminMastersQuery{})
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("GetMastersAdmin without a key: got %v, want %v", err, ErrUnauthorized)
+	}
+
+	err = admin.UpdateCity(ctx, &City{ID: "city", Name: "Berlin"})
+	if !errors.Is(err, ErrPreconditionRequired) {
+		t.Errorf("UpdateCity without a version: got %v, want %v", err, ErrPreconditionRequired)
+	}
+	if errors.Is(err, ErrPreconditionFailed) {
+		t.Error("the errors of different statuses match")
+	}
+
+	_, err = anonymous.SaveMaster(ctx, &Master{})
+	if !errors.Is(err, ErrBadRequest) {
+		t.Errorf("SaveMaster with an empty form: got %v, want %v", err, ErrBadRequest)
+	}
+
+	_, err = anonymous.SaveMaster(ctx, &Master{})
+	if !errors.Is(err, ErrTooManyRequests) {
+		t.Fatalf("SaveMaster over the limit: got %v, want %v", err, ErrTooManyRequests)
+	}
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter <= 0 {
+		t.Errorf("got %#v, want the Retry-After delay", err)
+	}
+}
+
+// the SDK against the real router and the test database: a master registered by the bot,
+// changed by its Telegram user and reviewed by the control panel
+func TestRoundTrip(t *testing.T) {
+
+	cfg := dbtest.Config(t)
+	cfg.AdminKeys = []string{"admin"}
+	cfg.BotKeys = []string{"bot"}
+	cfg.StorageBackend = "fs"
+	cfg.StorageRoot = t.TempDir()
+	cfg.StorageSecret = "secret"
+	cfg.ImagePrefix = "http://localhost/images"
+	cfg.MaxImageSize = 1 << 20
+	cfg.ImageTypes = []string{"image/png"}
+
+	log := logger.NewLogger()
+	adapter, err := dbadapter.NewDbAdapter(log, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := adapter.AutoMigrate(); err != nil {
+		t.Fatal(err)
+	}
+	storage, err := fsadapter.NewFSAdapter(log, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, err := server.NewRouter(log, cfg, adapter, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the cleanups go through the server, it is closed after them
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	telegramID := time.Now().UnixNano() % 1e9
+	admin := New(srv.URL, WithAPIKey("admin"))
+	user := New(srv.URL, WithAPIKey("bot")).As(telegramID)
+
+	cityID, err := admin.SaveCity(ctx, "city "+suffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.DeleteCity(ctx, cityID) })
+	categoryID, err := admin.SaveServiceCategory(ctx, "category "+suffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.DeleteServiceCategory(ctx, categoryID) })
+	serviceID, err := admin.SaveService(ctx, &Service{Name: "service " + suffix, CatID: categoryID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.DeleteService(ctx, serviceID) })
+
+	masterID, err := user.SaveMaster(ctx, &Master{
+		Name:      "master " + suffix,
+		Contact:   "@master",
+		CityID:    cityID,
+		ServCatID: categoryID,
+		ServIDs:   []string{serviceID},
+		Status:    Pending,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.DeleteMaster(ctx, masterID) })
+	if err := admin.ApproveMaster(ctx, masterID); err != nil {
+		t.Fatal(err)
+	}
+
+	master, err := user.GetMaster(ctx, Self)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if master.ID != masterID || master.Name != "master "+suffix || master.CityID != cityID || master.TelegramID != telegramID {
+		t.Fatalf("got %+v, want the saved master", master)
+	}
+
+	// the image of the approved master waits for a revision
+	picture := &bytes.Buffer{}
+	if err := png.Encode(picture, image.NewGray(image.Rect(0, 0, 2, 3))); err != nil {
+		t.Fatal(err)
+	}
+	imageName, err := user.SaveMasterImage(ctx, Self, "picture.png", picture, "the salon")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pendingRevision := func() *MasterRevision {
+		t.Helper()
+		revisions, err := All(ctx, 50, func(ctx context.Context, page Page) ([]*MasterRevision, error) {
+			return admin.GetMasterRevisions(ctx, Pending, page)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found *MasterRevision
+		for _, revision := range revisions {
+			if revision.MasterID != masterID {
+				continue
+			}
+			if found != nil {
+				t.Fatal("the master has more than one pending revision")
+			}
+			found = revision
+		}
+		if found == nil {
+			t.Fatal("the master has no pending revision")
+		}
+		if len(found.Images) != 1 || found.Images[0].Name != imageName || !found.Images[0].Pending {
+			t.Errorf("got the images %+v, want the pending %s", found.Images, imageName)
+		}
+		return found
+	}
+
+	if revision := pendingRevision(); !revision.ImagesOnly {
+		t.Errorf("got %+v, want a revision of the images only", revision)
+	}
+
+	master.Name = "renamed " + suffix
+	revisionID, pending, err := user.UpdateSelfMaster(ctx, &master.Master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pending {
+		t.Fatal("the change of the approved master was published without a review")
+	}
+
+	// the profile change takes the place of the image revision, the image stays pending with it
+	revision := pendingRevision()
+	if revision.ID != revisionID || revision.ImagesOnly || revision.Name != "renamed "+suffix {
+		t.Errorf("got the revision %+v, want %s renaming the master", revision, revisionID)
+	}
+	if err := admin.ApproveMasterRevision(ctx, revisionID); err != nil {
+		t.Fatal(err)
+	}
+
+	master, err = admin.GetMaster(ctx, masterID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if master.Name != "renamed "+suffix {
+		t.Errorf("got the name %q after the approval, want the revision's", master.Name)
+	}
+
+	images, err := admin.GetMasterImages(ctx, masterID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 1 {
+		t.Fatalf("got %d images, want 1", len(images))
+	}
+	if images[0].Name != imageName || images[0].Pending || images[0].Caption != "the salon" {
+		t.Errorf("got %+v, want the published %s", images[0], imageName)
+	}
+	if images[0].Width != 2 || images[0].Height != 3 || images[0].ContentType != "image/png" {
+		t.Errorf("got %dx%d %s, want the 2x3 image/png", images[0].Width, images[0].Height, images[0].ContentType)
+	}
+}
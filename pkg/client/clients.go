@@ -0,0 +1,40 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// GetClient returns the profile of the bot user
+func (c *Client) GetClient(ctx context.Context, telegramID int64) (*TelegramClient, error) {
+	client := &TelegramClient{}
+	err := c.call(ctx, c.newRequest(http.MethodGet, clientPath(telegramID), nil), client)
+	return client, err
+}
+
+// SaveClient creates or updates the profile of the bot user and returns the saved one
+func (c *Client) SaveClient(ctx context.Context, client *TelegramClient) (*TelegramClient, error) {
+	saved := &TelegramClient{}
+	err := c.callJSON(ctx, http.MethodPut, clientPath(client.TelegramID), client, saved)
+	return saved, err
+}
+
+// GetFavorites returns the masters saved by the bot user
+func (c *Client) GetFavorites(ctx context.Context, telegramID int64, page Page) ([]*MasterShort, error) {
+	masters := make([]*MasterShort, 0)
+	err := c.call(ctx, c.newRequest(http.MethodGet, clientPath(telegramID)+"/favorites", page.values()), &masters)
+	return masters, err
+}
+
+func (c *Client) SaveFavorite(ctx context.Context, telegramID int64, masterID string) error {
+	return c.call(ctx, c.newRequest(http.MethodPut, clientPath(telegramID)+escape("favorites", masterID), nil), nil)
+}
+
+func (c *Client) DeleteFavorite(ctx context.Context, telegramID int64, masterID string) error {
+	return c.call(ctx, c.newRequest(http.MethodDelete, clientPath(telegramID)+escape("favorites", masterID), nil), nil)
+}
+
+func clientPath(telegramID int64) string {
+	return escape("clients", strconv.FormatInt(telegramID, 10))
+}
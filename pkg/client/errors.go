@@ -0,0 +1,52 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error is a response with an error status, compare it with the Err values using errors.Is
+type Error struct {
+	StatusCode int
+	Message    string
+	// RetryAfter is set for ErrTooManyRequests
+	RetryAfter time.Duration
+}
+
+var (
+	ErrBadRequest           = &Error{StatusCode: http.StatusBadRequest}
+	ErrUnauthorized         = &Error{StatusCode: http.StatusUnauthorized}
+	ErrNotFound             = &Error{StatusCode: http.StatusNotFound}
+	ErrConflict             = &Error{StatusCode: http.StatusConflict}
+	ErrPreconditionFailed   = &Error{StatusCode: http.StatusPreconditionFailed}
+	ErrPreconditionRequired = &Error{StatusCode: http.StatusPreconditionRequired}
+	ErrTooManyRequests      = &Error{StatusCode: http.StatusTooManyRequests}
+)
+
+func (e *Error) Error() string {
+	if len(e.Message) == 0 {
+		return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Is matches the errors with the same status code
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.StatusCode == e.StatusCode
+}
+
+func newError(resp *http.Response) *Error {
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	err := &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(message))}
+
+	if seconds, parseErr := strconv.Atoi(resp.Header.Get("Retry-After")); parseErr == nil {
+		err.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return err
+}
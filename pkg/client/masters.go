@@ -0,0 +1,243 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+)
+
+const dateLayout = "2006-01-02"
+
+// GetMastersBot returns the approved masters shown by the bot
+func (c *Client) GetMastersBot(ctx context.Context, q MastersQuery) ([]*MasterShort, error) {
+
+	query := q.Page.values()
+	if len(q.CityID) != 0 {
+		query.Set("city_id", q.CityID)
+	}
+	if len(q.ServiceID) != 0 {
+		query.Set("service_id", q.ServiceID)
+	}
+	if len(q.Order) != 0 {
+		query.Set("order", q.Order)
+	}
+
+	masters := make([]*MasterShort, 0)
+	err := c.call(ctx, c.newRequest(http.MethodGet, "/masters/bot", query), &masters)
+	return masters, err
+}
+
+// GetMastersAdmin returns the masters of the control panel, requires the admin key
+func (c *Client) GetMastersAdmin(ctx context.Context, q AdminMastersQuery) ([]*MasterShort, error) {
+
+	query := q.Page.values()
+	if q.Status != 0 {
+		query.Set("status", strconv.FormatUint(uint64(q.Status), 10))
+	}
+	if len(q.CityID) != 0 {
+		query.Set("city_id", q.CityID)
+	}
+	if len(q.CategoryID) != 0 {
+		query.Set("category_id", q.CategoryID)
+	}
+	if !q.From.IsZero() {
+		query.Set("from", q.From.Format(dateLayout))
+	}
+	if !q.To.IsZero() {
+		query.Set("to", q.To.Format(dateLayout))
+	}
+	if len(q.Search) != 0 {
+		query.Set("q", q.Search)
+	}
+	if len(q.Sort) != 0 {
+		query.Set("sort", q.Sort)
+	}
+
+	masters := make([]*MasterShort, 0)
+	err := c.call(ctx, c.newRequest(http.MethodGet, "/masters/admin", query), &masters)
+	return masters, err
+}
+
+// GetMaster returns the master, Self selects the master of the Telegram user
+func (c *Client) GetMaster(ctx context.Context, masterID string) (*MasterLong, error) {
+	master := &MasterLong{}
+	err := c.call(ctx, c.newRequest(http.MethodGet, escape("masters", masterID), nil), master)
+	return master, err
+}
+
+// SaveMaster registers a master waiting for approval and returns its ID
+func (c *Client) SaveMaster(ctx context.Context, master *Master) (string, error) {
+	created := &id{}
+	err := c.callJSON(ctx, http.MethodPost, "/masters", master, created)
+	return created.ID, err
+}
+
+// UpdateMaster changes the master, master.Version must be the version the update is based on
+func (c *Client) UpdateMaster(ctx context.Context, master *MasterLong) (string, error) {
+	updated := &id{}
+	err := c.callJSON(ctx, http.MethodPut, escape("masters", master.ID), master, updated)
+	return updated.ID, err
+}
+
+// UpdateSelfMaster changes the master of the Telegram user. The changes of an approved
+// master wait for approval as a revision, then pending is true and the ID is the revision's.
+func (c *Client) UpdateSelfMaster(ctx context.Context, master *Master) (string, bool, error) {
+
+	req, err := c.newRequest(http.MethodPut, escape("masters", Self), nil).json(master)
+	if err != nil {
+		return "", false, err
+	}
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+
+	updated := &id{}
+	if err := json.NewDecoder(resp.Body).Decode(updated); err != nil {
+		return "", false, err
+	}
+	return updated.ID, resp.StatusCode == http.StatusAccepted, nil
+}
+
+// PatchMaster applies a JSON merge patch, version 0 skips the version check
+func (c *Client) PatchMaster(ctx context.Context, masterID string, patch interface{}, version int64) error {
+	return c.patch(ctx, escape("masters", masterID), patch, version)
+}
+
+func (c *Client) DeleteMaster(ctx context.Context, masterID string) error {
+	return c.call(ctx, c.newRequest(http.MethodDelete, escape("masters", masterID), nil), nil)
+}
+
+func (c *Client) ApproveMaster(ctx context.Context, masterID string) error {
+	return c.call(ctx, c.newRequest(http.MethodPost, escape("masters", masterID)+":approve", nil), nil)
+}
+
+// GetMasterRevisions returns the revisions of all the masters with the status, 0 matches every status
+func (c *Client) GetMasterRevisions(ctx context.Context, status uint, page Page) ([]*MasterRevision, error) {
+	return c.getRevisions(ctx, "/masters/revisions", status, page)
+}
+
+// GetSelfRevisions returns the revisions of the master of the Telegram user
+func (c *Client) GetSelfRevisions(ctx context.Context, status uint, page Page) ([]*MasterRevision, error) {
+	return c.getRevisions(ctx, "/masters/self/revisions", status, page)
+}
+
+func (c *Client) getRevisions(ctx context.Context, path string, status uint, page Page) ([]*MasterRevision, error) {
+	query := page.values()
+	if status != 0 {
+		query.Set("status", strconv.FormatUint(uint64(status), 10))
+	}
+	revisions := make([]*MasterRevision, 0)
+	err := c.call(ctx, c.newRequest(http.MethodGet, path, query), &revisions)
+	return revisions, err
+}
+
+// ApproveMasterRevision publishes the revision, ErrPreconditionFailed if the master changed since it was submitted
+func (c *Client) ApproveMasterRevision(ctx context.Context, revisionID string) error {
+	return c.call(ctx, c.newRequest(http.MethodPost, escape("masters", "revisions", revisionID)+":approve", nil), nil)
+}
+
+func (c *Client) RejectMasterRevision(ctx context.Context, revisionID string) error {
+	return c.call(ctx, c.newRequest(http.MethodPost, escape("masters", "revisions", revisionID)+":reject", nil), nil)
+}
+
+// GetMasterImages returns the images of the master in their order
+func (c *Client) GetMasterImages(ctx context.Context, masterID string) ([]*Image, error) {
+	images := make([]*Image, 0)
+	err := c.call(ctx, c.newRequest(http.MethodGet, escape("masters", masterID, "images"), nil), &images)
+	return images, err
+}
+
+// SaveMasterImage uploads the image through the server and returns its URL
+func (c *Client) SaveMasterImage(ctx context.Context, masterID, fileName string, image io.Reader, caption string) (string, error) {
+
+	fields := map[string]string{}
+	if len(caption) != 0 {
+		fields["caption"] = caption
+	}
+	req, err := c.newRequest(http.MethodPost, escape("masters", masterID, "images"), nil).multipart(fileName, image, fields)
+	if err != nil {
+		return "", err
+	}
+
+	saved := struct {
+		URL string `json:"url"`
+	}{}
+	err = c.call(ctx, req, &saved)
+	return saved.URL, err
+}
+
+// UpdateMasterImage replaces the content of the image
+func (c *Client) UpdateMasterImage(ctx context.Context, masterID, imageName, fileName string, image io.Reader) error {
+	req, err := c.newRequest(http.MethodPut, escape("masters", masterID, "images", imageName), nil).multipart(fileName, image, nil)
+	if err != nil {
+		return err
+	}
+	return c.call(ctx, req, nil)
+}
+
+// PresignMasterImageUpload returns the URL to upload the image directly to the storage,
+// the upload is finished with ConfirmMasterImageUpload
+func (c *Client) PresignMasterImageUpload(ctx context.Context, masterID string, upload UploadRequest) (*UploadURL, error) {
+	uploadURL := &UploadURL{}
+	err := c.callJSON(ctx, http.MethodPost, escape("masters", masterID, "images", "uploads"), upload, uploadURL)
+	return uploadURL, err
+}
+
+// ConfirmMasterImageUpload adds the uploaded image to the master, ErrConflict if it was confirmed already
+func (c *Client) ConfirmMasterImageUpload(ctx context.Context, masterID, imageName, caption string) (*Image, error) {
+	image := &Image{}
+	err := c.callJSON(ctx, http.MethodPost, escape("masters", masterID, "images", imageName, "confirm"), map[string]string{"caption": caption}, image)
+	return image, err
+}
+
+// SetMasterCover makes the image the first one of the master
+func (c *Client) SetMasterCover(ctx context.Context, masterID, imageName string) error {
+	return c.call(ctx, c.newRequest(http.MethodPost, escape("masters", masterID, "images", imageName, "cover"), nil), nil)
+}
+
+// ReorderMasterImages sets the order of the images, names must list all of them
+func (c *Client) ReorderMasterImages(ctx context.Context, masterID string, names []string) error {
+	return c.callJSON(ctx, http.MethodPut, escape("masters", masterID, "images", "order"), map[string][]string{"names": names}, nil)
+}
+
+func (c *Client) UpdateMasterImageCaption(ctx context.Context, masterID, imageName, caption string) error {
+	return c.callJSON(ctx, http.MethodPut, escape("masters", masterID, "images", imageName, "caption"), map[string]string{"caption": caption}, nil)
+}
+
+func (c *Client) DeleteMasterImage(ctx context.Context, masterID, imageName string) error {
+	return c.call(ctx, c.newRequest(http.MethodDelete, escape("masters", masterID, "images", imageName), nil), nil)
+}
+
+// multipart sets the body to a form with the file and the fields
+func (r *request) multipart(fileName string, file io.Reader, fields map[string]string) (*request, error) {
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	part, err := writer.CreateFormFile("file", fileName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return nil, err
+	}
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	r.body = body
+	r.contentType = writer.FormDataContentType()
+	return r, nil
+}
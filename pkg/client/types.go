@@ -0,0 +1,106 @@
+package client
+
+import (
+	"bot/internal/entities"
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// The entities of the server, the aliases make them usable outside of the module
+
+type (
+	City            = entities.City
+	ServiceCategory = entities.ServiceCategory
+	Service         = entities.Service
+	Image           = entities.Image
+	Master          = entities.Master
+	MasterLong      = entities.MasterLong
+	MasterShort     = entities.MasterShort
+	MasterRevision  = entities.MasterRevision
+	TelegramClient  = entities.Client
+	Catalog         = entities.Catalog
+	ImportResult    = entities.ImportResult
+	Stats           = entities.Stats
+	Event           = entities.Event
+)
+
+const (
+	Pending  = entities.PENDING
+	Approved = entities.APPROVED
+	Declined = entities.DECLINED
+)
+
+// Self is the master ID of the Telegram user the client acts for, see Client.As
+const Self = "self"
+
+// Page selects a page of a list, the zero Page returns the whole list
+type Page struct {
+	// Page is zero based
+	Page  int
+	Limit int
+}
+
+func (p Page) values() url.Values {
+	query := url.Values{}
+	if p.Limit > 0 {
+		query.Set("page", strconv.Itoa(p.Page))
+		query.Set("limit", strconv.Itoa(p.Limit))
+	}
+	return query
+}
+
+// All requests the pages of limit items one after another until a page is not full
+func All[T any](ctx context.Context, limit int, list func(ctx context.Context, page Page) ([]T, error)) ([]T, error) {
+	all := make([]T, 0)
+	for page := 0; ; page++ {
+		items, err := list(ctx, Page{Page: page, Limit: limit})
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		if len(items) < limit || limit <= 0 {
+			return all, nil
+		}
+	}
+}
+
+// MastersQuery filters the masters listed by the bot
+type MastersQuery struct {
+	Page
+	CityID    string
+	ServiceID string
+	// Order is empty or "popular"
+	Order string
+}
+
+// AdminMastersQuery filters the masters of the control panel
+type AdminMastersQuery struct {
+	Page
+	Status     uint
+	CityID     string
+	CategoryID string
+	// From and To limit the registration days, both inclusive
+	From time.Time
+	To   time.Time
+	// Search matches the name and the contact
+	Search string
+	// Sort is one of "date", "-date", "name", "-name"
+	Sort string
+}
+
+// UploadRequest describes the image to upload directly to the storage
+type UploadRequest struct {
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+}
+
+// UploadURL is where and how to upload the image before confirming it
+type UploadURL struct {
+	Name      string            `json:"name"`
+	URL       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+}